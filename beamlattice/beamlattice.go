@@ -4,9 +4,8 @@
 package beamlattice
 
 import (
-	"errors"
-
 	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/errors"
 	"github.com/hpinc/go3mf/spec"
 )
 
@@ -20,11 +19,11 @@ var DefaultExtension = go3mf.Extension{
 }
 
 var (
-	ErrLatticeObjType       = errors.New("MUST only be added to a mesh object of type model or solidsupport")
-	ErrLatticeClippedNoMesh = errors.New("if clipping mode is not equal to none, a clippingmesh resource MUST be specified")
-	ErrLatticeInvalidMesh   = errors.New("the clippingmesh and representationmesh MUST be a mesh object of type model and MUST NOT contain a beamlattice")
-	ErrLatticeSameVertex    = errors.New("a beam MUST consist of two distinct vertex indices")
-	ErrLatticeBeamR2        = errors.New("r2 MUST not be defined, if r1 is not defined")
+	ErrLatticeObjType       = errors.NewRule("beamlattice-2-object-type", errors.SeverityError, "MUST only be added to a mesh object of type model or solidsupport")
+	ErrLatticeClippedNoMesh = errors.NewRule("beamlattice-2.1-clippingmesh", errors.SeverityError, "if clipping mode is not equal to none, a clippingmesh resource MUST be specified")
+	ErrLatticeInvalidMesh   = errors.NewRule("beamlattice-2.1-mesh", errors.SeverityError, "the clippingmesh and representationmesh MUST be a mesh object of type model and MUST NOT contain a beamlattice")
+	ErrLatticeSameVertex    = errors.NewRule("beamlattice-2.2-distinct-vertices", errors.SeverityError, "a beam MUST consist of two distinct vertex indices")
+	ErrLatticeBeamR2        = errors.NewRule("beamlattice-2.2-radius", errors.SeverityError, "r2 MUST not be defined, if r1 is not defined")
 )
 
 func init() {
@@ -119,17 +119,17 @@ func TestDecode(t *testing.T) {
 
 func TestDecode_warns(t *testing.T) {
 	want := []string{
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("radius", true)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("minlength", true)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("cap", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("clippingmode", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("clippingmesh", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("representationmesh", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice/beams/beam[0]: %v", errors.NewParseAttrError("r1", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice/beams/beam[0]: %v", errors.NewParseAttrError("r2", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice/beams/beam[2]: %v", errors.NewParseAttrError("v2", true)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice/beams/beam[3]: %v", errors.NewParseAttrError("v1", true)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/beamlattice/beamsets/beamset[0]/ref[2]: %v", errors.NewParseAttrError("index", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 17 Column: 6 XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("radius", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 17 Column: 6 XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("minlength", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 17 Column: 6 XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("cap", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 17 Column: 6 XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("clippingmode", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 17 Column: 6 XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("clippingmesh", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 17 Column: 6 XPath: /model/resources/object[0]/mesh/beamlattice: %v", errors.NewParseAttrError("representationmesh", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 19 Column: 8 XPath: /model/resources/object[0]/mesh/beamlattice/beams/beam[0]: %v", errors.NewParseAttrError("r1", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 19 Column: 8 XPath: /model/resources/object[0]/mesh/beamlattice/beams/beam[0]: %v", errors.NewParseAttrError("r2", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 21 Column: 8 XPath: /model/resources/object[0]/mesh/beamlattice/beams/beam[2]: %v", errors.NewParseAttrError("v2", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 22 Column: 8 XPath: /model/resources/object[0]/mesh/beamlattice/beams/beam[3]: %v", errors.NewParseAttrError("v1", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 37 Column: 9 XPath: /model/resources/object[0]/mesh/beamlattice/beamsets/beamset[0]/ref[2]: %v", errors.NewParseAttrError("index", true)),
 	}
 	got := new(go3mf.Model)
 	got.Path = "/3D/3dmodel.model"
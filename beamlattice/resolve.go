@@ -0,0 +1,28 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package beamlattice
+
+import "github.com/hpinc/go3mf"
+
+func (Spec) Resolve(parent interface{}, name string, index int) (interface{}, bool) {
+	switch p := parent.(type) {
+	case *go3mf.Mesh:
+		if name == attrBeamLattice && index == -1 {
+			bl := GetBeamLattice(p)
+			return bl, bl != nil
+		}
+	case *BeamLattice:
+		switch name {
+		case attrBeam:
+			if index >= 0 && index < len(p.Beams.Beam) {
+				return &p.Beams.Beam[index], true
+			}
+		case attrBeamSet:
+			if index >= 0 && index < len(p.BeamSets.BeamSet) {
+				return &p.BeamSets.BeamSet[index], true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,41 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package beamlattice
+
+import (
+	"testing"
+
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/spec"
+)
+
+func TestSpec_Resolve(t *testing.T) {
+	bl := &BeamLattice{Beams: Beams{Beam: make([]Beam, 2)}, BeamSets: BeamSets{BeamSet: make([]BeamSet, 2)}}
+	m := &go3mf.Model{
+		Extensions: []go3mf.Extension{DefaultExtension},
+		Resources: go3mf.Resources{Objects: []*go3mf.Object{
+			{ID: 1, Mesh: &go3mf.Mesh{Any: spec.Any{bl}}},
+			{ID: 2, Mesh: new(go3mf.Mesh)},
+		}},
+	}
+	tests := []struct {
+		xpath  string
+		want   interface{}
+		wantOk bool
+	}{
+		{"/model/resources/object[0]/mesh/beamlattice", bl, true},
+		{"/model/resources/object[0]/mesh/beamlattice/beam[1]", &bl.Beams.Beam[1], true},
+		{"/model/resources/object[0]/mesh/beamlattice/beamset[1]", &bl.BeamSets.BeamSet[1], true},
+		{"/model/resources/object[0]/mesh/beamlattice/beam[2]", nil, false},
+		{"/model/resources/object[1]/mesh/beamlattice", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.xpath, func(t *testing.T) {
+			got, ok := m.Resolve("", tt.xpath)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("Spec.Resolve() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
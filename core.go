@@ -4,11 +4,13 @@
 package go3mf
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"image/color"
 	"io"
-	"sort"
-	"sync"
+	"io/ioutil"
 
 	"github.com/hpinc/go3mf/spec"
 )
@@ -105,10 +107,48 @@ type Metadata struct {
 }
 
 // Attachment defines the Model Attachment.
+//
+// The content is read from Data, OpenFunc or, for decoded attachments,
+// the package file, which can be read more than once, so a model can be encoded
+// several times, even concurrently. Stream is consumed by the first call to Open,
+// which moves its content to Data, so that call must not run concurrently.
+//
+// Attachments decoded from a package are opened lazily,
+// so their content is only available while the package is open,
+// unless Decoder.BufferAttachments is set, in which case it is stored in Data.
+// Decoded attachments never set Stream, use Open to read them.
 type Attachment struct {
 	Stream      io.Reader
+	Data        []byte
+	OpenFunc    func() (io.ReadCloser, error)
 	Path        string
 	ContentType string
+	file        packageFile
+}
+
+// Open returns a reader with the attachment content.
+// The first defined of Stream, Data, OpenFunc and the package file
+// the attachment was decoded from takes precedence.
+//
+// Stream is buffered into Data and then set to nil,
+// so the following calls return the same content.
+func (a *Attachment) Open() (io.ReadCloser, error) {
+	switch {
+	case a.Stream != nil:
+		data, err := ioutil.ReadAll(a.Stream)
+		if err != nil {
+			return nil, err
+		}
+		a.Data, a.Stream = data, nil
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	case a.Data != nil:
+		return ioutil.NopCloser(bytes.NewReader(a.Data)), nil
+	case a.OpenFunc != nil:
+		return a.OpenFunc()
+	case a.file != nil:
+		return a.file.Open()
+	}
+	return nil, errors.New("attachment does not have content")
 }
 
 // Relationship defines a dependency between
@@ -136,31 +176,28 @@ type Resources struct {
 }
 
 // UnusedID returns the lowest unused ID.
+//
+// It takes linear time, use a ResourceIndex to allocate several IDs.
 func (rs *Resources) UnusedID() uint32 {
-	if len(rs.Assets) == 0 && len(rs.Objects) == 0 {
-		return 1
-	}
-	ids := make([]int, len(rs.Assets)+len(rs.Objects)+1)
-	ids[0] = 0
-	for i, r := range rs.Assets {
-		id := r.Identify()
-		ids[i+1] = int(id)
-	}
-	for i, o := range rs.Objects {
-		ids[len(rs.Assets)+i+1] = int(o.ID)
+	// The lowest unused ID is at most the number of resources plus one.
+	n := uint32(len(rs.Assets) + len(rs.Objects))
+	used := make([]bool, n+1)
+	for _, r := range rs.Assets {
+		if id := r.Identify(); id <= n {
+			used[id] = true
+		}
 	}
-	sort.Ints(ids)
-	lowest := 0
-	for i, id := range ids {
-		if id != i {
-			lowest = i
-			break
+	for _, o := range rs.Objects {
+		if o.ID <= n {
+			used[o.ID] = true
 		}
 	}
-	if lowest == 0 {
-		lowest = ids[len(ids)-1] + 1
+	for id := uint32(1); id <= n; id++ {
+		if !used[id] {
+			return id
+		}
 	}
-	return uint32(lowest)
+	return n + 1
 }
 
 // FindObject returns the resource with the target ID.
@@ -208,6 +245,7 @@ type ChildModel struct {
 // but they are usefull to reference custom attachments.
 // Childs keys cannot be an empty string.
 // RootRelationships are the OPC root relationships.
+// CoreProperties are the OPC core properties, which are not written if nil.
 type Model struct {
 	Path              string
 	Language          string
@@ -221,8 +259,12 @@ type Model struct {
 	Childs            map[string]*ChildModel // path -> child
 	RootRelationships []Relationship
 	Relationships     []Relationship
+	CoreProperties    *CoreProperties
 	Any               spec.Any
 	AnyAttr           spec.AnyAttr
+	positions         *positionIndex                // nil unless decoded with Decoder.TrackPositions
+	validation        *validation                   // only set while validating
+	indexes           map[*Resources]*ResourceIndex // only set during model-wide passes
 }
 
 // PathOrDefault returns Path if not empty, else DefaultModelPath.
@@ -235,29 +277,38 @@ func (m *Model) PathOrDefault() string {
 
 // BoundingBox returns the bounding box of the model.
 func (m *Model) BoundingBox() Box {
+	box, _ := m.BoundingBoxContext(context.Background())
+	return box
+}
+
+// BoundingBoxContext returns the bounding box of the model,
+// computing the one of each build item concurrently,
+// using at most the goroutines set in ctx with WithParallelism.
+// It stops early if ctx is done, returning ctx.Err().
+func (m *Model) BoundingBoxContext(ctx context.Context) (Box, error) {
 	if len(m.Build.Items) == 0 {
-		return Box{}
+		return Box{}, ctx.Err()
 	}
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	box := newLimitBox()
-	wg.Add(len(m.Build.Items))
-	for i := range m.Build.Items {
-		go func(i int) {
-			defer wg.Done()
-			item := m.Build.Items[i]
-			if o, ok := m.FindObject(item.ObjectPath(), item.ObjectID); ok {
-				ibox := o.boundingBox(m, "")
-				if ibox != emptyBox {
-					mu.Lock()
-					box = box.extend(item.Transform.MulBox(ibox))
-					mu.Unlock()
-				}
+	m = m.withIndexes()
+	boxes := make([]Box, len(m.Build.Items))
+	err := forEach(ctx, 0, len(m.Build.Items), func(i int) {
+		item := m.Build.Items[i]
+		if o, ok := m.FindObject(item.ObjectPath(), item.ObjectID); ok {
+			if ibox := o.boundingBox(m, item.ObjectPath(), nil); ibox != emptyBox {
+				boxes[i] = item.Transform.MulBox(ibox)
 			}
-		}(i)
+		}
+	})
+	if err != nil {
+		return Box{}, err
 	}
-	wg.Wait()
-	return box
+	box := newLimitBox()
+	for _, ibox := range boxes {
+		if ibox != emptyBox {
+			box = box.extend(ibox)
+		}
+	}
+	return box, nil
 }
 
 // FindResources returns the resource associated with path.
@@ -274,6 +325,9 @@ func (m *Model) FindResources(path string) (*Resources, bool) {
 // FindAsset returns the resource with the target path and ID.
 func (m *Model) FindAsset(path string, id uint32) (Asset, bool) {
 	if rs, ok := m.FindResources(path); ok {
+		if ix, ok := m.indexes[rs]; ok {
+			return ix.FindAsset(id)
+		}
 		return rs.FindAsset(id)
 	}
 	return nil, false
@@ -282,6 +336,9 @@ func (m *Model) FindAsset(path string, id uint32) (Asset, bool) {
 // FindObject returns the object with the target path and ID.
 func (m *Model) FindObject(path string, id uint32) (*Object, bool) {
 	if rs, ok := m.FindResources(path); ok {
+		if ix, ok := m.indexes[rs]; ok {
+			return ix.FindObject(id)
+		}
 		return rs.FindObject(id)
 	}
 	return nil, false
@@ -413,17 +470,24 @@ type Object struct {
 	AnyAttr    spec.AnyAttr
 }
 
-func (o *Object) boundingBox(m *Model, path string) Box {
+func (o *Object) boundingBox(m *Model, path string, visiting []*Object) Box {
+	for _, v := range visiting {
+		if v == o {
+			return Box{} // recursive reference
+		}
+	}
 	if o.Mesh != nil {
 		return o.Mesh.BoundingBox()
 	}
 	if o.Components == nil || len(o.Components.Component) == 0 {
 		return Box{}
 	}
+	visiting = append(visiting, o)
 	box := newLimitBox()
 	for _, c := range o.Components.Component {
-		if obj, ok := m.FindObject(c.ObjectPath(path), c.ObjectID); ok {
-			cbox := obj.boundingBox(m, path)
+		cpath := c.ObjectPath(path)
+		if obj, ok := m.FindObject(cpath, c.ObjectID); ok {
+			cbox := obj.boundingBox(m, cpath, visiting)
 			if cbox != emptyBox {
 				box = box.extend(c.Transform.MulBox(cbox))
 			}
@@ -630,4 +694,5 @@ const (
 	attrMetadata      = "metadata"
 	attrMetadataGroup = "metadatagroup"
 	attrPath          = "path"
+	attrRelationship  = "relationship"
 )
@@ -4,6 +4,10 @@
 package go3mf
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
 	"reflect"
 	"testing"
 
@@ -196,6 +200,9 @@ func TestResources_UnusedID(t *testing.T) {
 		{"sparce", &Resources{Assets: []Asset{&BaseMaterials{ID: 12}}, Objects: []*Object{
 			{ID: 6}, {ID: 4}, {ID: 8}, {ID: 10}, {ID: 2}}}, 1,
 		},
+		{"duplicated", &Resources{Objects: []*Object{{ID: 1}, {ID: 1}, {ID: 2}}}, 3},
+		{"zero", &Resources{Objects: []*Object{{ID: 0}, {ID: 1}}}, 2},
+		{"full", &Resources{Objects: []*Object{{ID: 3}, {ID: 1}, {ID: 2}}}, 4},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -476,6 +483,14 @@ func TestModel_BoundingBox(t *testing.T) {
 				}}},
 			}},
 		}, Box{Min: Point3D{10, 20, 30}, Max: Point3D{110, 120, 130}}},
+		{"recursive", &Model{
+			Build: Build{Items: []*Item{{ObjectID: 2}}},
+			Resources: Resources{Objects: []*Object{
+				{ID: 1, Mesh: &Mesh{Vertices: Vertices{Vertex: []Point3D{{10, 20, 30}}}}},
+				{ID: 2, Components: &Components{Component: []*Component{{ObjectID: 1}, {ObjectID: 3}}}},
+				{ID: 3, Components: &Components{Component: []*Component{{ObjectID: 2}}}},
+			}},
+		}, Box{Min: Point3D{10, 20, 30}, Max: Point3D{10, 20, 30}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -485,3 +500,63 @@ func TestModel_BoundingBox(t *testing.T) {
 		})
 	}
 }
+
+func TestModel_BoundingBoxContext(t *testing.T) {
+	m := &Model{
+		Build: Build{Items: []*Item{{ObjectID: 1}, {ObjectID: 1, Transform: Identity().Translate(100, 0, 0)}}},
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: &Mesh{Vertices: Vertices{Vertex: []Point3D{{10, 20, 30}}}}},
+		}},
+	}
+	want := Box{Min: Point3D{10, 20, 30}, Max: Point3D{110, 20, 30}}
+	for _, ctx := range []context.Context{context.Background(), WithParallelism(context.Background(), 1)} {
+		if got, err := m.BoundingBoxContext(ctx); err != nil || got != want {
+			t.Errorf("Model.BoundingBoxContext() = %v, %v, want %v", got, err, want)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.BoundingBoxContext(ctx); err != context.Canceled {
+		t.Errorf("Model.BoundingBoxContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAttachment_Open(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       *Attachment
+		want    string
+		wantErr bool
+	}{
+		{"empty", new(Attachment), "", true},
+		{"stream", &Attachment{Stream: bytes.NewBufferString("stream")}, "stream", false},
+		{"data", &Attachment{Data: []byte("data")}, "data", false},
+		{"openFunc", &Attachment{OpenFunc: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewBufferString("open")), nil
+		}}, "open", false},
+		{"file", &Attachment{file: &fakePackageFile{data: []byte("file")}}, "file", false},
+		{"dataFirst", &Attachment{Data: []byte("data"), file: &fakePackageFile{data: []byte("file")}}, "data", false},
+		{"both", &Attachment{Stream: bytes.NewBufferString("stream"), file: &fakePackageFile{data: []byte("file")}}, "stream", false},
+		{"streamFirst", &Attachment{Stream: bytes.NewBufferString("stream"), Data: []byte("data")}, "stream", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The content must not change when the attachment is opened again.
+			for i := 0; i < 2; i++ {
+				r, err := tt.a.Open()
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Attachment.Open() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if tt.wantErr {
+					return
+				}
+				got, _ := ioutil.ReadAll(r)
+				r.Close()
+				if string(got) != tt.want {
+					t.Errorf("Attachment.Open() = %s, want %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
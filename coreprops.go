@@ -0,0 +1,121 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const (
+	// RelTypeCoreProperties is the package relationship type of the core properties part.
+	RelTypeCoreProperties = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	// ContentTypeCoreProperties is the core properties content type.
+	ContentTypeCoreProperties = "application/vnd.openxmlformats-package.core-properties+xml"
+	// DefaultCorePropertiesPath is the recommended core properties part name.
+	DefaultCorePropertiesPath = "/docProps/core.xml"
+)
+
+const (
+	nsCoreProperties = "http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+	nsDC             = "http://purl.org/dc/elements/1.1/"
+	nsDCTerms        = "http://purl.org/dc/terms/"
+	nsXSI            = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// w3cdtfLayouts are the date formats allowed by the W3CDTF profile of ISO 8601.
+var w3cdtfLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02", "2006-01", "2006"}
+
+// CoreProperties are the OPC core properties of a package,
+// which are indexed by file explorers and asset management systems.
+// The zero value of a field means that the property is not set.
+type CoreProperties struct {
+	Category       string
+	ContentStatus  string
+	Created        time.Time
+	Creator        string
+	Description    string
+	Identifier     string
+	Keywords       string
+	Language       string
+	LastModifiedBy string
+	LastPrinted    time.Time
+	Modified       time.Time
+	Revision       string
+	Subject        string
+	Title          string
+	Version        string
+}
+
+func parseW3CDTF(name, s string) (t time.Time, err error) {
+	if s == "" {
+		return
+	}
+	for _, layout := range w3cdtfLayouts {
+		if t, err = time.Parse(layout, s); err == nil {
+			return
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: invalid %s date %q", DefaultCorePropertiesPath, name, s)
+}
+
+func (e *Encoder) writeCoreProperties(props *CoreProperties) error {
+	w, err := e.create(DefaultCorePropertiesPath, ContentTypeCoreProperties)
+	if err != nil {
+		return err
+	}
+	e.w.AddRelationship(Relationship{Type: RelTypeCoreProperties, Path: DefaultCorePropertiesPath})
+	if _, err = w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	x := e.newXMLEncoder(w)
+	start := xml.StartElement{Name: xml.Name{Local: "cp:coreProperties"}, Attr: []xml.Attr{
+		{Name: xml.Name{Space: "xmlns", Local: "cp"}, Value: nsCoreProperties},
+		{Name: xml.Name{Space: "xmlns", Local: "dc"}, Value: nsDC},
+		{Name: xml.Name{Space: "xmlns", Local: "dcterms"}, Value: nsDCTerms},
+		{Name: xml.Name{Space: "xmlns", Local: "xsi"}, Value: nsXSI},
+	}}
+	x.EncodeToken(start)
+	writeCoreProperty(x, "cp:category", props.Category)
+	writeCoreProperty(x, "cp:contentStatus", props.ContentStatus)
+	writeCoreDate(x, "dcterms:created", props.Created, true)
+	writeCoreProperty(x, "dc:creator", props.Creator)
+	writeCoreProperty(x, "dc:description", props.Description)
+	writeCoreProperty(x, "dc:identifier", props.Identifier)
+	writeCoreProperty(x, "cp:keywords", props.Keywords)
+	writeCoreProperty(x, "dc:language", props.Language)
+	writeCoreProperty(x, "cp:lastModifiedBy", props.LastModifiedBy)
+	writeCoreDate(x, "cp:lastPrinted", props.LastPrinted, false)
+	writeCoreDate(x, "dcterms:modified", props.Modified, true)
+	writeCoreProperty(x, "cp:revision", props.Revision)
+	writeCoreProperty(x, "dc:subject", props.Subject)
+	writeCoreProperty(x, "dc:title", props.Title)
+	writeCoreProperty(x, "cp:version", props.Version)
+	x.EncodeToken(start.End())
+	return x.Flush()
+}
+
+func writeCoreProperty(x *xmlEncoder, name, value string) {
+	if value == "" {
+		return
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	x.EncodeToken(start)
+	x.EncodeToken(xml.CharData(value))
+	x.EncodeToken(start.End())
+}
+
+func writeCoreDate(x *xmlEncoder, name string, t time.Time, w3cdtf bool) {
+	if t.IsZero() {
+		return
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if w3cdtf {
+		start.Attr = []xml.Attr{{Name: xml.Name{Local: "xsi:type"}, Value: "dcterms:W3CDTF"}}
+	}
+	x.EncodeToken(start)
+	x.EncodeToken(xml.CharData(t.Format(time.RFC3339Nano)))
+	x.EncodeToken(start.End())
+}
@@ -0,0 +1,91 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestEncoder_Encode_CoreProperties(t *testing.T) {
+	want := &Model{
+		Path: DefaultModelPath,
+		CoreProperties: &CoreProperties{
+			Category:       "parts",
+			ContentStatus:  "final",
+			Created:        time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+			Creator:        "Jane <jane@example.com>",
+			Description:    "a cube",
+			Identifier:     "id",
+			Keywords:       "cube, test",
+			Language:       "en-US",
+			LastModifiedBy: "John",
+			LastPrinted:    time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("", 3600)),
+			Modified:       time.Date(2021, 2, 3, 4, 5, 6, 500, time.UTC),
+			Revision:       "3",
+			Subject:        "testing",
+			Title:          "cube",
+			Version:        "1.0",
+		},
+	}
+	var buff bytes.Buffer
+	if err := NewEncoder(&buff).Encode(want); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	got := new(Model)
+	if err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Decoder.Decode() = %v", diff)
+	}
+	r, _ := zip.NewReader(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	for _, f := range r.File {
+		if f.Name != DefaultCorePropertiesPath[1:] {
+			continue
+		}
+		rc, _ := f.Open()
+		content, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if !strings.Contains(string(content), `<dcterms:created xsi:type="dcterms:W3CDTF">2021-01-02T03:04:05Z</dcterms:created>`) {
+			t.Errorf("Encoder.Encode() core properties = %s", content)
+		}
+		return
+	}
+	t.Error("Encoder.Encode() core properties part not found")
+}
+
+func Test_parseW3CDTF(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"2021", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2021-05", time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2021-05-04", time.Date(2021, 5, 4, 0, 0, 0, 0, time.UTC), false},
+		{"2021-05-04T10:20Z", time.Date(2021, 5, 4, 10, 20, 0, 0, time.UTC), false},
+		{"2021-05-04T10:20:30Z", time.Date(2021, 5, 4, 10, 20, 30, 0, time.UTC), false},
+		{"2021-05-04T10:20:30.5+01:00", time.Date(2021, 5, 4, 9, 20, 30, 5e8, time.UTC), false},
+		{"04/05/2021", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseW3CDTF("created", tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseW3CDTF() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseW3CDTF() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
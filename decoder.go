@@ -16,9 +16,11 @@ import (
 
 type modelDecoder struct {
 	baseDecoder
-	model  *Model
-	isRoot bool
-	path   string
+	model     *Model
+	isRoot    bool
+	path      string
+	handler   *decodeHandler
+	metadatas int
 }
 
 func (d *modelDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
@@ -26,17 +28,26 @@ func (d *modelDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 		switch name.Local {
 		case attrResources:
 			resources, _ := d.model.FindResources(d.path)
-			child = &resourceDecoder{resources: resources, model: d.model}
+			if d.handler != nil {
+				// Keep the pending resources apart, so the handler
+				// can freely modify the model resources.
+				child = &resourceDecoder{resources: new(Resources), target: resources, model: d.model, handler: d.handler}
+			} else {
+				child = &resourceDecoder{resources: resources, model: d.model}
+			}
 			i = -1
 		case attrBuild:
 			if d.isRoot {
-				child = &buildDecoder{build: &d.model.Build, model: d.model}
+				child = &buildDecoder{build: &d.model.Build, model: d.model, handler: d.handler}
 				i = -1
 			}
 		case attrMetadata:
 			if d.isRoot {
-				child = &metadataDecoder{metadatas: &d.model.Metadata, model: d.model}
-				i = len(d.model.Metadata)
+				child = &metadataDecoder{metadatas: &d.model.Metadata, model: d.model, handler: d.handler}
+				i = len(d.model.Metadata) + d.metadatas
+				if d.handler != nil {
+					d.metadatas++
+				}
 			}
 		}
 	} else {
@@ -140,6 +151,7 @@ type metadataDecoder struct {
 	model     *Model
 	metadatas *[]Metadata
 	metadata  Metadata
+	handler   *decodeHandler
 }
 
 func (d *metadataDecoder) namespace(local string) (string, bool) {
@@ -182,19 +194,28 @@ func (d *metadataDecoder) CharData(txt []byte) {
 }
 
 func (d *metadataDecoder) End() {
+	if d.handler != nil {
+		d.handler.metadata(d.metadata)
+		return
+	}
 	*d.metadatas = append(*d.metadatas, d.metadata)
 }
 
 type buildDecoder struct {
 	baseDecoder
-	model *Model
-	build *Build
+	model   *Model
+	build   *Build
+	handler *decodeHandler
+	items   int
 }
 
 func (d *buildDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	if name.Space == Namespace && name.Local == attrItem {
-		child = &buildItemDecoder{build: d.build, model: d.model}
-		i = len(d.build.Items)
+		child = &buildItemDecoder{build: d.build, model: d.model, handler: d.handler}
+		i = len(d.build.Items) + d.items
+		if d.handler != nil {
+			d.items++
+		}
 	}
 	return
 }
@@ -214,12 +235,17 @@ func (d *buildDecoder) Start(attrs []spec.XMLAttr) error {
 
 type buildItemDecoder struct {
 	baseDecoder
-	model *Model
-	build *Build
-	item  Item
+	model   *Model
+	build   *Build
+	item    Item
+	handler *decodeHandler
 }
 
 func (d *buildItemDecoder) End() {
+	if d.handler != nil {
+		d.handler.item(&d.item)
+		return
+	}
 	d.build.Items = append(d.build.Items, &d.item)
 }
 
@@ -272,6 +298,37 @@ type resourceDecoder struct {
 	baseDecoder
 	model     *Model
 	resources *Resources
+	target    *Resources
+	handler   *decodeHandler
+	assets    int
+	objects   int
+}
+
+// End passes the pending resources to the handler, if any.
+func (d *resourceDecoder) End() {
+	d.flush()
+	if d.target != nil {
+		d.target.AnyAttr = append(d.target.AnyAttr, d.resources.AnyAttr...)
+	}
+}
+
+// flush passes the decoded resources to the handler.
+// As the previous child has already ended when a new one starts,
+// every resource in the list is complete.
+func (d *resourceDecoder) flush() {
+	if d.handler == nil {
+		return
+	}
+	assets, objects := d.resources.Assets, d.resources.Objects
+	d.resources.Assets, d.resources.Objects = nil, nil
+	d.assets += len(assets)
+	d.objects += len(objects)
+	for _, a := range assets {
+		d.handler.asset(a)
+	}
+	for _, o := range objects {
+		d.handler.object(o)
+	}
 }
 
 func (d *resourceDecoder) Start(attrs []spec.XMLAttr) error {
@@ -288,25 +345,26 @@ func (d *resourceDecoder) Start(attrs []spec.XMLAttr) error {
 }
 
 func (d *resourceDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
+	d.flush()
 	if name.Space == Namespace {
 		switch name.Local {
 		case attrObject:
-			child = &objectDecoder{resources: d.resources, model: d.model}
-			i = len(d.resources.Objects)
+			child = &objectDecoder{resources: d.resources, model: d.model, handler: d.handler}
+			i = len(d.resources.Objects) + d.objects
 		case attrBaseMaterials:
 			child = &baseMaterialsDecoder{resources: d.resources}
-			i = len(d.resources.Assets)
+			i = len(d.resources.Assets) + d.assets
 		}
 	} else if ext, ok := spec.Load(name.Space); ok {
 		dec := ext.NewElementDecoder(name)
-		i = len(d.resources.Assets)
+		i = len(d.resources.Assets) + d.assets
 		child = dec
 		if dec != nil {
 			d.resources.Assets = append(d.resources.Assets, dec.Element().(Asset))
 		}
 	} else {
 		child = &unknownAssetDecoder{UnknownTokensDecoder: *spec.NewUnknownDecoder(name), resources: d.resources}
-		i = len(d.resources.Assets)
+		i = len(d.resources.Assets) + d.assets
 	}
 	return
 }
@@ -392,6 +450,7 @@ func (d *baseMaterialDecoder) Start(attrs []spec.XMLAttr) error {
 type meshDecoder struct {
 	baseDecoder
 	resource *Object
+	handler  *decodeHandler
 }
 
 func (d *meshDecoder) Start(attrs []spec.XMLAttr) error {
@@ -411,10 +470,10 @@ func (d *meshDecoder) Start(attrs []spec.XMLAttr) error {
 func (d *meshDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	if name.Space == Namespace {
 		if name.Local == attrVertices {
-			child = &verticesDecoder{mesh: d.resource.Mesh}
+			child = &verticesDecoder{mesh: d.resource.Mesh, resource: d.resource, handler: d.handler}
 			i = -1
 		} else if name.Local == attrTriangles {
-			child = &trianglesDecoder{resource: d.resource}
+			child = &trianglesDecoder{resource: d.resource, handler: d.handler}
 			i = -1
 		}
 	} else {
@@ -431,11 +490,22 @@ func (d *meshDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 type verticesDecoder struct {
 	baseDecoder
 	mesh          *Mesh
+	resource      *Object
+	handler       *decodeHandler
 	vertexDecoder vertexDecoder
 }
 
+func (d *verticesDecoder) End() {
+	if d.handler != nil {
+		d.handler.vertices(d.resource)
+		d.mesh.Vertices.Vertex = nil
+	}
+}
+
 func (d *verticesDecoder) Start(attrs []spec.XMLAttr) error {
 	d.vertexDecoder.mesh = d.mesh
+	d.vertexDecoder.resource = d.resource
+	d.vertexDecoder.handler = d.handler
 	var errs error
 	for _, a := range attrs {
 		var attr spec.AttrGroup
@@ -451,14 +521,17 @@ func (d *verticesDecoder) Start(attrs []spec.XMLAttr) error {
 func (d *verticesDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	if name.Space == Namespace && name.Local == attrVertex {
 		child = &d.vertexDecoder
-		i = len(d.mesh.Vertices.Vertex)
+		i = len(d.mesh.Vertices.Vertex) + d.vertexDecoder.flushed
 	}
 	return
 }
 
 type vertexDecoder struct {
 	baseDecoder
-	mesh *Mesh
+	mesh     *Mesh
+	resource *Object
+	handler  *decodeHandler
+	flushed  int
 }
 
 func (d *vertexDecoder) Start(attrs []spec.XMLAttr) error {
@@ -484,17 +557,30 @@ func (d *vertexDecoder) Start(attrs []spec.XMLAttr) error {
 		}
 	}
 	d.mesh.Vertices.Vertex = append(d.mesh.Vertices.Vertex, Point3D{x, y, z})
+	if d.handler != nil && len(d.mesh.Vertices.Vertex) == handlerChunkSize {
+		d.flushed += d.handler.vertices(d.resource)
+	}
 	return errs
 }
 
 type trianglesDecoder struct {
 	baseDecoder
 	resource        *Object
+	handler         *decodeHandler
 	triangleDecoder triangleDecoder
 }
 
+func (d *trianglesDecoder) End() {
+	if d.handler != nil {
+		d.handler.triangles(d.resource)
+		d.resource.Mesh.Triangles.Triangle = nil
+	}
+}
+
 func (d *trianglesDecoder) Start(attrs []spec.XMLAttr) error {
 	d.triangleDecoder.mesh = d.resource.Mesh
+	d.triangleDecoder.resource = d.resource
+	d.triangleDecoder.handler = d.handler
 	d.triangleDecoder.defaultPropertyID = d.resource.PID
 	d.triangleDecoder.defaultPropertyIndex = d.resource.PIndex
 
@@ -516,7 +602,7 @@ func (d *trianglesDecoder) Start(attrs []spec.XMLAttr) error {
 func (d *trianglesDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	if name.Space == Namespace && name.Local == attrTriangle {
 		child = &d.triangleDecoder
-		i = len(d.resource.Mesh.Triangles.Triangle)
+		i = len(d.resource.Mesh.Triangles.Triangle) + d.triangleDecoder.flushed
 	}
 	return
 }
@@ -524,6 +610,9 @@ func (d *trianglesDecoder) Child(name xml.Name) (i int, child spec.ElementDecode
 type triangleDecoder struct {
 	baseDecoder
 	mesh                                    *Mesh
+	resource                                *Object
+	handler                                 *decodeHandler
+	flushed                                 int
 	defaultPropertyIndex, defaultPropertyID uint32
 }
 
@@ -583,6 +672,9 @@ func (d *triangleDecoder) Start(attrs []spec.XMLAttr) error {
 	t.PID = pid
 	t.P1, t.P2, t.P3 = p1, p2, p3
 	d.mesh.Triangles.Triangle = append(d.mesh.Triangles.Triangle, t)
+	if d.handler != nil && len(d.mesh.Triangles.Triangle) == handlerChunkSize {
+		d.flushed += d.handler.triangles(d.resource)
+	}
 	return errs
 }
 
@@ -598,6 +690,7 @@ type objectDecoder struct {
 	model     *Model
 	resources *Resources
 	resource  Object
+	handler   *decodeHandler
 }
 
 func (d *objectDecoder) End() {
@@ -624,7 +717,7 @@ func (d *objectDecoder) Start(attrs []spec.XMLAttr) error {
 func (d *objectDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	if name.Space == Namespace {
 		if name.Local == attrMesh {
-			child = &meshDecoder{resource: &d.resource}
+			child = &meshDecoder{resource: &d.resource, handler: d.handler}
 			i = -1
 		} else if name.Local == attrComponents {
 			child = &componentsDecoder{resource: &d.resource}
@@ -750,15 +843,16 @@ func (d *baseDecoder) End()                       {}
 
 type topLevelDecoder struct {
 	baseDecoder
-	model  *Model
-	isRoot bool
-	path   string
+	model   *Model
+	isRoot  bool
+	path    string
+	handler *decodeHandler
 }
 
 func (d *topLevelDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	modelName := xml.Name{Space: Namespace, Local: attrModel}
 	if name == modelName {
-		child = &modelDecoder{model: d.model, isRoot: d.isRoot, path: d.path}
+		child = &modelDecoder{model: d.model, isRoot: d.isRoot, path: d.path, handler: d.handler}
 		i = -1
 	}
 	return
@@ -790,3 +884,61 @@ func (d *unknownAssetDecoder) End() {
 	d.resource.UnknownTokens = d.UnknownTokensDecoder.Tokens()
 	d.resources.Assets = append(d.resources.Assets, &d.resource)
 }
+
+// handlerChunkSize is the number of vertices and triangles
+// passed to the ModelHandler in each call.
+var handlerChunkSize = 4096
+
+// decodeHandler passes the decoded content of a model part to a ModelHandler,
+// keeping the first error returned by its callbacks.
+type decodeHandler struct {
+	h    *ModelHandler
+	path string
+	err  error
+}
+
+func (d *decodeHandler) metadata(md Metadata) {
+	if d.err == nil && d.h.Metadata != nil {
+		d.err = d.h.Metadata(md)
+	}
+}
+
+func (d *decodeHandler) asset(a Asset) {
+	if d.err == nil && d.h.Asset != nil {
+		d.err = d.h.Asset(d.path, a)
+	}
+}
+
+func (d *decodeHandler) object(o *Object) {
+	if d.err == nil && d.h.Object != nil {
+		d.err = d.h.Object(d.path, o)
+	}
+}
+
+func (d *decodeHandler) item(item *Item) {
+	if d.err == nil && d.h.Item != nil {
+		d.err = d.h.Item(item)
+	}
+}
+
+// vertices passes the pending vertices of o to the handler
+// and returns how many they were.
+func (d *decodeHandler) vertices(o *Object) int {
+	v := o.Mesh.Vertices.Vertex
+	if len(v) > 0 && d.err == nil && d.h.Vertices != nil {
+		d.err = d.h.Vertices(d.path, o, v)
+	}
+	o.Mesh.Vertices.Vertex = v[:0]
+	return len(v)
+}
+
+// triangles passes the pending triangles of o to the handler
+// and returns how many they were.
+func (d *decodeHandler) triangles(o *Object) int {
+	t := o.Mesh.Triangles.Triangle
+	if len(t) > 0 && d.err == nil && d.h.Triangles != nil {
+		d.err = d.h.Triangles(d.path, o, t)
+	}
+	o.Mesh.Triangles.Triangle = t[:0]
+	return len(t)
+}
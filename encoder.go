@@ -6,6 +6,7 @@ package go3mf
 import (
 	"bufio"
 	"bytes"
+	"compress/flate"
 	"encoding/xml"
 	"io"
 	"os"
@@ -21,6 +22,7 @@ const defaultFloatPrecision = 4
 
 type xmlEncoder struct {
 	floatPresicion int
+	sortAttrs      bool
 	relationships  []Relationship
 	p              xml3mf.Printer
 }
@@ -50,6 +52,9 @@ func (enc *xmlEncoder) EncodeToken(t xml.Token) {
 	p := &enc.p
 	switch t := t.(type) {
 	case xml.StartElement:
+		if enc.sortAttrs {
+			t.Attr = canonicalAttrs(t.Attr)
+		}
 		p.WriteStart(&t)
 	case xml.EndElement:
 		p.WriteEnd(t.Name)
@@ -58,6 +63,41 @@ func (enc *xmlEncoder) EncodeToken(t xml.Token) {
 	}
 }
 
+// canonicalAttrs returns attrs sorted so the name space declarations go first,
+// then the attributes without name space, which keep their relative order,
+// and then the rest of the attributes sorted by name.
+// attrs is not modified.
+func canonicalAttrs(attrs []xml.Attr) []xml.Attr {
+	if sort.SliceIsSorted(attrs, func(i, j int) bool { return attrLess(attrs[i], attrs[j]) }) {
+		return attrs
+	}
+	s := make([]xml.Attr, len(attrs))
+	copy(s, attrs)
+	sort.SliceStable(s, func(i, j int) bool { return attrLess(s[i], s[j]) })
+	return s
+}
+
+func attrLess(a, b xml.Attr) bool {
+	ga, gb := attrGroup(a), attrGroup(b)
+	if ga != gb || ga == 1 {
+		return ga < gb
+	}
+	if a.Name.Space != b.Name.Space {
+		return a.Name.Space < b.Name.Space
+	}
+	return a.Name.Local < b.Name.Local
+}
+
+func attrGroup(a xml.Attr) int {
+	switch {
+	case a.Name.Space == attrXmlns || (a.Name.Space == "" && a.Name.Local == attrXmlns):
+		return 0
+	case a.Name.Space == "":
+		return 1
+	}
+	return 2
+}
+
 // Flush flushes any buffered XML to the underlying writer.
 func (enc *xmlEncoder) Flush() error {
 	return enc.p.Flush()
@@ -80,9 +120,10 @@ type packagePart interface {
 }
 
 type packageWriter interface {
-	Create(name, contentType string) (packagePart, error)
+	Create(name, contentType string, c Compression) (packagePart, error)
 	AddRelationship(Relationship)
 	Close() error
+	relationshipsParts() ([]relationshipsPart, error)
 }
 
 // MarshalModel returns the XML encoding of m.
@@ -117,12 +158,93 @@ func CreateWriter(name string) (*WriteCloser, error) {
 	}, nil
 }
 
+// Compression defines how a package part is compressed.
+type Compression int
+
+const (
+	// CompressionNormal is optimized for a reasonable compromise between size and performance.
+	CompressionNormal Compression = iota
+	// CompressionNone stores the part without compression.
+	CompressionNone
+	// CompressionMaximum is optimized for size.
+	CompressionMaximum
+	// CompressionFast is optimized for performance.
+	CompressionFast
+)
+
+func (c Compression) level() int {
+	switch c {
+	case CompressionNone:
+		return flate.NoCompression
+	case CompressionMaximum:
+		return flate.BestCompression
+	case CompressionFast:
+		return flate.BestSpeed
+	}
+	return flate.DefaultCompression
+}
+
+// flags returns the deflate option bits of the zip general purpose flags.
+func (c Compression) flags() uint16 {
+	switch c {
+	case CompressionMaximum:
+		return 0x2
+	case CompressionFast:
+		return 0x4
+	}
+	return 0
+}
+
+// A PartEncrypter encrypts the parts of a package while encoding.
+// The root model part and the relationships parts are never encrypted.
+type PartEncrypter interface {
+	// Encrypt returns a writer that encrypts the content of the part name into w,
+	// or nil if the part must not be encrypted.
+	// The writer is closed once the whole part has been written.
+	Encrypt(name, contentType string, w io.Writer) (io.WriteCloser, error)
+	// Close is called once all the parts have been written,
+	// so the information required to decrypt them can be added to the package
+	// by creating new parts and package relationships.
+	Close(create func(name, contentType string) (io.Writer, error), addRelationship func(Relationship)) error
+}
+
+type encryptedPart struct {
+	packagePart
+	w io.Writer
+}
+
+func (p *encryptedPart) Write(b []byte) (int, error) {
+	return p.w.Write(b)
+}
+
 // An Encoder writes Model data to an output stream.
 //
 // See the documentation for strconv.FormatFloat for details about the FloatPrecision behaviour.
+//
+// The compression of a part is the one defined in PartCompression for its name,
+// if any, else the one defined in ContentTypeCompression for its content type,
+// if any, else Compression.
+//
+// If Encrypter is not nil, the child models and the attachments are written through it.
+//
+// If Signer is not nil, the package is signed with an OPC digital signature
+// covering the model and attachment parts.
+//
+// If Deterministic is true, encoding the same model always produces the same bytes:
+// the zip entries have a fixed modification time, the relationships without ID
+// get an ID derived from their type and target, and the attributes
+// of each element are written in a canonical order.
 type Encoder struct {
-	FloatPrecision int
-	w              packageWriter
+	FloatPrecision         int
+	Compression            Compression
+	ContentTypeCompression map[string]Compression
+	PartCompression        map[string]Compression
+	Deterministic          bool
+	Encrypter              PartEncrypter
+	Signer                 *Signer
+	w                      packageWriter
+	encrypting             io.Closer
+	digests                []partDigest
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -133,58 +255,111 @@ func NewEncoder(w io.Writer) *Encoder {
 	}
 }
 
+// SetCompressor sets the function used to deflate the parts,
+// which defaults to compress/flate. level is a compress/flate
+// compression level and is derived from the part Compression.
+//
+// It is intended to plug faster deflate implementations.
+func (e *Encoder) SetCompressor(comp func(w io.Writer, level int) (io.WriteCloser, error)) {
+	if w, ok := e.w.(*opcWriter); ok {
+		w.compressor = comp
+	}
+}
+
 // Encode writes the XML encoding of m to the stream.
 func (e *Encoder) Encode(m *Model) error {
-	if err := e.writeAttachements(m.Attachments); err != nil {
+	w, enc, err := e.createRootModel(m)
+	if err != nil {
 		return err
 	}
+	if err = e.writeModel(enc, m); err != nil {
+		return err
+	}
+	return e.closeRootModel(w, enc, m)
+}
+
+// createRootModel writes the attachments and returns
+// the root model part, ready to write the model element.
+func (e *Encoder) createRootModel(m *Model) (packagePart, *xmlEncoder, error) {
+	if w, ok := e.w.(*opcWriter); ok {
+		w.deterministic = e.Deterministic
+	}
+	e.digests = nil
+	if m.CoreProperties != nil {
+		if err := e.writeCoreProperties(m.CoreProperties); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := e.writeAttachements(m.Attachments); err != nil {
+		return nil, nil, err
+	}
 	rootName := m.PathOrDefault()
 	for _, r := range m.RootRelationships {
 		e.w.AddRelationship(r)
 	}
 	e.w.AddRelationship(Relationship{Type: RelType3DModel, Path: rootName})
 
-	w, err := e.w.Create(rootName, ContentType3DModel)
+	w, err := e.create(rootName, ContentType3DModel)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	if _, err := w.Write([]byte(xml.Header)); err != nil {
-		return err
+		return nil, nil, err
 	}
-	enc := newXMLEncoder(w, e.FloatPrecision)
+	enc := e.newXMLEncoder(w)
 	enc.relationships = make([]Relationship, len(m.Relationships))
 	copy(enc.relationships, m.Relationships)
-	for path := range m.Childs {
+	for _, path := range m.sortedChilds() {
 		enc.AddRelationship(spec.Relationship{Type: RelType3DModel, Path: path})
 	}
-	if err = e.writeModel(enc, m); err != nil {
-		return err
-	}
+	return w, enc, nil
+}
+
+// closeRootModel writes the relationships of the root model part
+// and the child models, and closes the package.
+func (e *Encoder) closeRootModel(w packagePart, enc *xmlEncoder, m *Model) error {
 	for _, r := range enc.relationships {
 		w.AddRelationship(r)
 	}
-	if err = e.writeChildModels(m); err != nil {
+	if err := e.writeChildModels(m); err != nil {
 		return err
 	}
-
+	if err := e.closeEncrypted(); err != nil {
+		return err
+	}
+	if e.Encrypter != nil {
+		err := e.Encrypter.Close(func(name, contentType string) (io.Writer, error) {
+			return e.create(name, contentType)
+		}, e.w.AddRelationship)
+		if err != nil {
+			return err
+		}
+	}
+	if e.Signer != nil {
+		if err := e.writeSignature(); err != nil {
+			return err
+		}
+	}
 	return e.w.Close()
 }
 
 func (e *Encoder) writeChildModels(m *Model) error {
-	for path, child := range m.Childs {
+	for _, path := range m.sortedChilds() {
 		var (
-			w   packagePart
-			err error
+			w     packagePart
+			err   error
+			child = m.Childs[path]
 		)
 		path = resolveRelationship(m.PathOrDefault(), path)
-		if w, err = e.w.Create(path, ContentType3DModel); err != nil {
+		if w, err = e.createEncrypted(path, ContentType3DModel); err != nil {
 			return err
 		}
 		if _, err = w.Write([]byte(xml.Header)); err != nil {
 			return err
 		}
-		enc := newXMLEncoder(w, e.FloatPrecision)
-		enc.relationships = child.Relationships
+		enc := e.newXMLEncoder(w)
+		enc.relationships = make([]Relationship, len(child.Relationships))
+		copy(enc.relationships, child.Relationships)
 		if err = e.writeChildModel(enc, m, child); err != nil {
 			return err
 		}
@@ -195,19 +370,75 @@ func (e *Encoder) writeChildModels(m *Model) error {
 	return nil
 }
 
-func (e *Encoder) writeAttachements(att []Attachment) error {
-	for _, a := range att {
-		w, err := e.w.Create(a.Path, a.ContentType)
-		if err == nil {
-			_, err = io.Copy(w, a.Stream)
+func (e *Encoder) newXMLEncoder(w io.Writer) *xmlEncoder {
+	enc := newXMLEncoder(w, e.FloatPrecision)
+	enc.sortAttrs = e.Deterministic
+	return enc
+}
+
+// createEncrypted creates a part whose content is encrypted by e.Encrypter, if needed.
+func (e *Encoder) createEncrypted(name, contentType string) (packagePart, error) {
+	w, err := e.create(name, contentType)
+	if err != nil || e.Encrypter == nil {
+		return w, err
+	}
+	ew, err := e.Encrypter.Encrypt(name, contentType, w)
+	if err != nil || ew == nil {
+		return w, err
+	}
+	e.encrypting = ew
+	return &encryptedPart{packagePart: w, w: ew}, nil
+}
+
+// closeEncrypted flushes the encrypted content of the last part, if any.
+func (e *Encoder) closeEncrypted() error {
+	if e.encrypting == nil {
+		return nil
+	}
+	err := e.encrypting.Close()
+	e.encrypting = nil
+	return err
+}
+
+func (e *Encoder) create(name, contentType string) (packagePart, error) {
+	if err := e.closeEncrypted(); err != nil {
+		return nil, err
+	}
+	c, ok := e.PartCompression[name]
+	if !ok {
+		if c, ok = e.ContentTypeCompression[contentType]; !ok {
+			c = e.Compression
 		}
-		if err != nil {
+	}
+	w, err := e.w.Create(name, contentType, c)
+	if err != nil || e.Signer == nil {
+		return w, err
+	}
+	return e.sign(name, contentType, w), nil
+}
+
+func (e *Encoder) writeAttachements(att []Attachment) error {
+	for i := range att {
+		if err := e.writeAttachment(&att[i]); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (e *Encoder) writeAttachment(a *Attachment) error {
+	r, err := a.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := e.createEncrypted(a.Path, a.ContentType)
+	if err == nil {
+		_, err = io.Copy(w, r)
+	}
+	return err
+}
+
 func (e *Encoder) modelToken(x spec.Encoder, m *Model, isRoot bool) (xml.StartElement, error) {
 	attrs := []xml.Attr{
 		{Name: xml.Name{Local: attrXmlns}, Value: Namespace},
@@ -364,6 +595,22 @@ func (e *Encoder) writeMetadata(x spec.Encoder, metadata []Metadata) {
 }
 
 func (e *Encoder) writeObject(x spec.Encoder, r *Object) {
+	xo := e.objectToken(x, r)
+	x.EncodeToken(xo)
+
+	if len(r.Metadata.Metadata) != 0 {
+		e.writeMetadataGroup(x, r.Metadata)
+	}
+
+	if r.Mesh != nil {
+		e.writeMesh(x, r, r.Mesh)
+	} else if r.Components != nil {
+		e.writeComponents(x, r.Components)
+	}
+	x.EncodeToken(xo.End())
+}
+
+func (e *Encoder) objectToken(x spec.Encoder, r *Object) xml.StartElement {
 	xo := xml.StartElement{Name: xml.Name{Local: attrObject}, Attr: []xml.Attr{
 		{Name: xml.Name{Local: attrID}, Value: strconv.FormatUint(uint64(r.ID), 10)},
 	}}
@@ -393,18 +640,7 @@ func (e *Encoder) writeObject(x spec.Encoder, r *Object) {
 		}
 	}
 	r.AnyAttr.Marshal3MF(x, &xo)
-	x.EncodeToken(xo)
-
-	if len(r.Metadata.Metadata) != 0 {
-		e.writeMetadataGroup(x, r.Metadata)
-	}
-
-	if r.Mesh != nil {
-		e.writeMesh(x, r, r.Mesh)
-	} else if r.Components != nil {
-		e.writeComponents(x, r.Components)
-	}
-	x.EncodeToken(xo.End())
+	return xo
 }
 
 func (e *Encoder) writeComponents(x spec.Encoder, comps *Components) {
@@ -432,22 +668,11 @@ func (e *Encoder) writeVertices(x spec.Encoder, m *Mesh) {
 	xvs := xml.StartElement{Name: xml.Name{Local: attrVertices}}
 	m.Vertices.AnyAttr.Marshal3MF(x, &xvs)
 	x.EncodeToken(xvs)
-	prec := x.FloatPresicion()
-	start := xml.StartElement{
-		Name: xml.Name{Local: attrVertex},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Local: attrX}},
-			{Name: xml.Name{Local: attrY}},
-			{Name: xml.Name{Local: attrZ}},
-		},
-	}
+	enc := newVertexEncoder(x)
 	x.SetAutoClose(true)
 	x.SetSkipAttrEscape(true)
 	for _, v := range m.Vertices.Vertex {
-		start.Attr[0].Value = strconv.FormatFloat(float64(v.X()), 'f', prec, 32)
-		start.Attr[1].Value = strconv.FormatFloat(float64(v.Y()), 'f', prec, 32)
-		start.Attr[2].Value = strconv.FormatFloat(float64(v.Z()), 'f', prec, 32)
-		x.EncodeToken(start)
+		enc.encode(v)
 	}
 	x.SetSkipAttrEscape(false)
 	x.SetAutoClose(false)
@@ -458,46 +683,96 @@ func (e *Encoder) writeTriangles(x spec.Encoder, r *Object, m *Mesh) {
 	xvt := xml.StartElement{Name: xml.Name{Local: attrTriangles}}
 	m.Triangles.AnyAttr.Marshal3MF(x, &xvt)
 	x.EncodeToken(xvt)
-	start := xml.StartElement{
-		Name: xml.Name{Local: attrTriangle},
-	}
-	attrs := []xml.Attr{
-		{Name: xml.Name{Local: attrV1}},
-		{Name: xml.Name{Local: attrV2}},
-		{Name: xml.Name{Local: attrV3}},
-		{Name: xml.Name{Local: attrPID}},
-		{Name: xml.Name{Local: attrP1}},
-		{Name: xml.Name{Local: attrP2}},
-		{Name: xml.Name{Local: attrP3}},
-	}
+	enc := newTriangleEncoder(x, r)
 	x.SetAutoClose(true)
 	x.SetSkipAttrEscape(true)
 	for _, t := range m.Triangles.Triangle {
-		attrs[0].Value = strconv.FormatUint(uint64(t.V1), 10)
-		attrs[1].Value = strconv.FormatUint(uint64(t.V2), 10)
-		attrs[2].Value = strconv.FormatUint(uint64(t.V3), 10)
-		start.Attr = attrs[:3]
-		if t.PID != 0 {
-			if (t.P1 != t.P2) || (t.P1 != t.P3) {
-				attrs[3].Value = strconv.FormatUint(uint64(t.PID), 10)
-				attrs[4].Value = strconv.FormatUint(uint64(t.P1), 10)
-				attrs[5].Value = strconv.FormatUint(uint64(t.P2), 10)
-				attrs[6].Value = strconv.FormatUint(uint64(t.P3), 10)
-				start.Attr = attrs[:7]
-			} else if (t.PID != r.PID) || (t.P1 != r.PIndex) {
-				attrs[3].Value = strconv.FormatUint(uint64(t.PID), 10)
-				attrs[4].Value = strconv.FormatUint(uint64(t.P1), 10)
-				start.Attr = attrs[:5]
-			}
-		}
-		t.AnyAttr.Marshal3MF(x, &start)
-		x.EncodeToken(start)
+		enc.encode(t)
 	}
 	x.SetSkipAttrEscape(false)
 	x.SetAutoClose(false)
 	x.EncodeToken(xvt.End())
 }
 
+// vertexEncoder encodes vertex elements reusing the same token.
+// The encoder must be in auto close and skip attribute escape mode.
+type vertexEncoder struct {
+	x     spec.Encoder
+	prec  int
+	start xml.StartElement
+}
+
+func newVertexEncoder(x spec.Encoder) *vertexEncoder {
+	return &vertexEncoder{
+		x:    x,
+		prec: x.FloatPresicion(),
+		start: xml.StartElement{
+			Name: xml.Name{Local: attrVertex},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: attrX}},
+				{Name: xml.Name{Local: attrY}},
+				{Name: xml.Name{Local: attrZ}},
+			},
+		},
+	}
+}
+
+func (enc *vertexEncoder) encode(v Point3D) {
+	enc.start.Attr[0].Value = strconv.FormatFloat(float64(v.X()), 'f', enc.prec, 32)
+	enc.start.Attr[1].Value = strconv.FormatFloat(float64(v.Y()), 'f', enc.prec, 32)
+	enc.start.Attr[2].Value = strconv.FormatFloat(float64(v.Z()), 'f', enc.prec, 32)
+	enc.x.EncodeToken(enc.start)
+}
+
+// triangleEncoder encodes triangle elements reusing the same token.
+// The encoder must be in auto close and skip attribute escape mode.
+type triangleEncoder struct {
+	x     spec.Encoder
+	r     *Object
+	start xml.StartElement
+	attrs []xml.Attr
+}
+
+func newTriangleEncoder(x spec.Encoder, r *Object) *triangleEncoder {
+	return &triangleEncoder{
+		x:     x,
+		r:     r,
+		start: xml.StartElement{Name: xml.Name{Local: attrTriangle}},
+		attrs: []xml.Attr{
+			{Name: xml.Name{Local: attrV1}},
+			{Name: xml.Name{Local: attrV2}},
+			{Name: xml.Name{Local: attrV3}},
+			{Name: xml.Name{Local: attrPID}},
+			{Name: xml.Name{Local: attrP1}},
+			{Name: xml.Name{Local: attrP2}},
+			{Name: xml.Name{Local: attrP3}},
+		},
+	}
+}
+
+func (enc *triangleEncoder) encode(t Triangle) {
+	attrs, r := enc.attrs, enc.r
+	attrs[0].Value = strconv.FormatUint(uint64(t.V1), 10)
+	attrs[1].Value = strconv.FormatUint(uint64(t.V2), 10)
+	attrs[2].Value = strconv.FormatUint(uint64(t.V3), 10)
+	enc.start.Attr = attrs[:3]
+	if t.PID != 0 {
+		if (t.P1 != t.P2) || (t.P1 != t.P3) {
+			attrs[3].Value = strconv.FormatUint(uint64(t.PID), 10)
+			attrs[4].Value = strconv.FormatUint(uint64(t.P1), 10)
+			attrs[5].Value = strconv.FormatUint(uint64(t.P2), 10)
+			attrs[6].Value = strconv.FormatUint(uint64(t.P3), 10)
+			enc.start.Attr = attrs[:7]
+		} else if (t.PID != r.PID) || (t.P1 != r.PIndex) {
+			attrs[3].Value = strconv.FormatUint(uint64(t.PID), 10)
+			attrs[4].Value = strconv.FormatUint(uint64(t.P1), 10)
+			enc.start.Attr = attrs[:5]
+		}
+	}
+	t.AnyAttr.Marshal3MF(enc.x, &enc.start)
+	enc.x.EncodeToken(enc.start)
+}
+
 func (e *Encoder) writeMesh(x spec.Encoder, r *Object, m *Mesh) {
 	xm := xml.StartElement{Name: xml.Name{Local: attrMesh}}
 	m.AnyAttr.Marshal3MF(x, &xm)
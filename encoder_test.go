@@ -4,12 +4,18 @@
 package go3mf
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/flate"
 	"encoding/xml"
 	"errors"
 	"image/color"
+	"io"
+	"io/ioutil"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -156,7 +162,7 @@ func TestEncoder_writeAttachements(t *testing.T) {
 			m := new(mockPackage)
 			mp := new(mockPackagePart)
 			mp.On("Write", mock.Anything).Return(mock.Anything, mock.Anything)
-			m.On("Create", mock.Anything, mock.Anything).Return(mp, argErr)
+			m.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(mp, argErr)
 			m.On("AddRelationship", mock.Anything).Return()
 			tt.e.w = m
 			if err := tt.e.writeAttachements(tt.args.m.Attachments); (err != nil) != tt.wantErr {
@@ -166,6 +172,55 @@ func TestEncoder_writeAttachements(t *testing.T) {
 	}
 }
 
+func TestEncoder_create(t *testing.T) {
+	e := &Encoder{
+		Compression:            CompressionFast,
+		ContentTypeCompression: map[string]Compression{"image/png": CompressionNone, ContentType3DModel: CompressionMaximum},
+		PartCompression:        map[string]Compression{"/3D/a.model": CompressionNormal},
+	}
+	tests := []struct {
+		name        string
+		part        string
+		contentType string
+		want        Compression
+	}{
+		{"global", "/a.txt", "text/plain", CompressionFast},
+		{"contentType", "/a.png", "image/png", CompressionNone},
+		{"part", "/3D/a.model", ContentType3DModel, CompressionNormal},
+		{"partOverContentType", "/3D/b.model", ContentType3DModel, CompressionMaximum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(mockPackage)
+			m.On("Create", tt.part, tt.contentType, tt.want).Return(new(mockPackagePart), nil)
+			e.w = m
+			if _, err := e.create(tt.part, tt.contentType); err != nil {
+				t.Errorf("Encoder.create() error = %v", err)
+			}
+			m.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEncoder_SetCompressor(t *testing.T) {
+	var buff bytes.Buffer
+	var calls int
+	e := NewEncoder(&buff)
+	e.SetCompressor(func(w io.Writer, level int) (io.WriteCloser, error) {
+		calls++
+		return flate.NewWriter(w, level)
+	})
+	if err := e.Encode(new(Model)); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	if calls == 0 {
+		t.Error("Encoder.SetCompressor() compressor not called")
+	}
+	if err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(new(Model)); err != nil {
+		t.Errorf("Encoder.SetCompressor() malformed = %v", err)
+	}
+}
+
 func TestNewEncoder(t *testing.T) {
 	tests := []struct {
 		name string
@@ -193,9 +248,9 @@ func TestEncoder_Encode_Normalize(t *testing.T) {
 	}{
 		{"empty", args{new(Model)}, &Model{Path: DefaultModelPath}},
 		{"withAttrs", args{&Model{Path: "a/other.ml", Thumbnail: "/Metadata/thumbnail.png", Attachments: []Attachment{
-			{ContentType: "image/png", Path: "Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake")},
+			{ContentType: "image/png", Path: "Metadata/thumbnail.png", Data: []byte("fake")},
 		}}}, &Model{Path: "/a/other.ml", Units: UnitMillimeter, Thumbnail: "/Metadata/thumbnail.png", Attachments: []Attachment{
-			{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake")},
+			{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Data: []byte("fake")},
 		}, RootRelationships: []Relationship{
 			{Path: "/Metadata/thumbnail.png", Type: RelTypeThumbnail, ID: "rId1"},
 		}}},
@@ -205,15 +260,15 @@ func TestEncoder_Encode_Normalize(t *testing.T) {
 				{Path: "Metadata/thumbnail.png", Type: RelTypeThumbnail, ID: "2"},
 			},
 			Attachments: []Attachment{
-				{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Stream: bytes.NewBufferString("other")},
-				{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake")},
+				{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Data: []byte("other")},
+				{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Data: []byte("fake")},
 			}}},
 			&Model{Path: DefaultModelPath,
 				RootRelationships: []Relationship{
 					{Path: "Metadata/thumbnail.png", Type: RelTypeThumbnail, ID: "2"},
 				},
 				Attachments: []Attachment{
-					{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake")},
+					{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Data: []byte("fake")},
 				}}},
 		{"withChildModel", args{&Model{
 			Childs: map[string]*ChildModel{
@@ -236,7 +291,9 @@ func TestEncoder_Encode_Normalize(t *testing.T) {
 				return
 			}
 			newModel := new(Model)
-			err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(newModel)
+			d := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+			d.BufferAttachments = true
+			err := d.Decode(newModel)
 			if err != nil {
 				t.Errorf("Encoder.Encode() malformed = %v", err)
 				return
@@ -271,13 +328,13 @@ func TestEncoder_Encode_Roundtrip(t *testing.T) {
 				{Path: "/Metadata/thumbnail.png", Type: "http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail", ID: "2"},
 			},
 			Attachments: []Attachment{
-				{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Stream: bytes.NewBufferString("other")},
-				{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake")},
+				{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Data: []byte("other")},
+				{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Data: []byte("fake")},
 			}}},
 		},
 		{"withChildModel", args{&Model{
 			Attachments: []Attachment{
-				{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Stream: bytes.NewBufferString("other")},
+				{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Data: []byte("other")},
 			},
 			Childs: map[string]*ChildModel{
 				"/empty.model": {},
@@ -295,7 +352,9 @@ func TestEncoder_Encode_Roundtrip(t *testing.T) {
 				return
 			}
 			newModel := new(Model)
-			err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(newModel)
+			d := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+			d.BufferAttachments = true
+			err := d.Decode(newModel)
 			if err != nil {
 				t.Errorf("Encoder.Encode() malformed = %v", err)
 				return
@@ -309,3 +368,302 @@ func TestEncoder_Encode_Roundtrip(t *testing.T) {
 		})
 	}
 }
+
+func TestEncoder_Encode_Deterministic(t *testing.T) {
+	newModel := func(swap bool) *Model {
+		exts := []Extension{{Namespace: "http://a.com", LocalName: "a"}, {Namespace: "http://b.com", LocalName: "b"}}
+		attrs := spec.AnyAttr{
+			&spec.UnknownAttrs{Space: "http://a.com", Attr: []xml.Attr{{Name: xml.Name{Space: "http://a.com", Local: "x"}, Value: "1"}}},
+			&spec.UnknownAttrs{Space: "http://b.com", Attr: []xml.Attr{{Name: xml.Name{Space: "http://b.com", Local: "y"}, Value: "2"}}},
+		}
+		if swap {
+			exts[0], exts[1] = exts[1], exts[0]
+			attrs[0], attrs[1] = attrs[1], attrs[0]
+		}
+		m := &Model{
+			Extensions: exts,
+			Attachments: []Attachment{
+				{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Data: []byte("pt")},
+			},
+			Relationships: []Relationship{{Path: "/3D/Metadata/pt.xml", Type: "http://schemas.microsoft.com/3dmanufacturing/2013/01/printticket"}},
+			Childs:        make(map[string]*ChildModel),
+		}
+		m.Resources.Objects = []*Object{{ID: 1, AnyAttr: attrs, Mesh: new(Mesh)}}
+		for _, path := range []string{"/3D/a.model", "/3D/b.model", "/3D/c.model", "/3D/d.model"} {
+			m.Childs[path] = &ChildModel{Resources: Resources{Objects: []*Object{{ID: 1, Mesh: new(Mesh)}}}}
+		}
+		return m
+	}
+	encode := func(m *Model) []byte {
+		var buff bytes.Buffer
+		e := NewEncoder(&buff)
+		e.Deterministic = true
+		if err := e.Encode(m); err != nil {
+			t.Fatalf("Encoder.Encode() error = %v", err)
+		}
+		return buff.Bytes()
+	}
+	want := encode(newModel(false))
+	for i := 0; i < 5; i++ {
+		if got := encode(newModel(i%2 == 1)); !bytes.Equal(got, want) {
+			t.Fatalf("Encoder.Encode() is not deterministic")
+		}
+	}
+	zr, err := zip.NewReader(bytes.NewReader(want), int64(len(want)))
+	if err != nil {
+		t.Fatalf("Encoder.Encode() malformed = %v", err)
+	}
+	for _, f := range zr.File {
+		if !f.Modified.Equal(deterministicTime) {
+			t.Errorf("Encoder.Encode() %s modified = %v, want %v", f.Name, f.Modified, deterministicTime)
+		}
+	}
+	got := new(Model)
+	if err = NewDecoder(bytes.NewReader(want), int64(len(want))).Decode(got); err != nil {
+		t.Fatalf("Encoder.Encode() malformed = %v", err)
+	}
+	if len(got.Childs) != 4 || len(got.Relationships) != 1 || got.Relationships[0].ID == "" {
+		t.Errorf("Encoder.Encode() = %v", got)
+	}
+}
+
+func Test_canonicalAttrs(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs []xml.Attr
+		want  []xml.Attr
+	}{
+		{"empty", nil, nil},
+		{"sorted", []xml.Attr{
+			{Name: xml.Name{Local: attrXmlns}}, {Name: xml.Name{Local: "id"}}, {Name: xml.Name{Space: "a", Local: "x"}},
+		}, []xml.Attr{
+			{Name: xml.Name{Local: attrXmlns}}, {Name: xml.Name{Local: "id"}}, {Name: xml.Name{Space: "a", Local: "x"}},
+		}},
+		{"unsorted", []xml.Attr{
+			{Name: xml.Name{Space: "b", Local: "x"}},
+			{Name: xml.Name{Local: "type"}},
+			{Name: xml.Name{Space: attrXmlns, Local: "b"}},
+			{Name: xml.Name{Space: "a", Local: "y"}},
+			{Name: xml.Name{Local: "id"}},
+			{Name: xml.Name{Space: "a", Local: "x"}},
+			{Name: xml.Name{Space: attrXmlns, Local: "a"}},
+		}, []xml.Attr{
+			{Name: xml.Name{Space: attrXmlns, Local: "a"}},
+			{Name: xml.Name{Space: attrXmlns, Local: "b"}},
+			{Name: xml.Name{Local: "type"}},
+			{Name: xml.Name{Local: "id"}},
+			{Name: xml.Name{Space: "a", Local: "x"}},
+			{Name: xml.Name{Space: "a", Local: "y"}},
+			{Name: xml.Name{Space: "b", Local: "x"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := append([]xml.Attr(nil), tt.attrs...)
+			if got := canonicalAttrs(tt.attrs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("canonicalAttrs() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.attrs, orig) {
+				t.Errorf("canonicalAttrs() modified the input")
+			}
+		})
+	}
+}
+
+// xorCipher implements PartEncrypter and PartDecrypter
+// by xoring the content of the parts listed in the "/enc.txt" part.
+type xorCipher struct {
+	parts []string
+}
+
+type xorWriter struct {
+	w io.Writer
+}
+
+func (x *xorWriter) Write(b []byte) (int, error) {
+	c := make([]byte, len(b))
+	for i := range b {
+		c[i] = b[i] ^ 0xff
+	}
+	return x.w.Write(c)
+}
+
+func (x *xorWriter) Close() error { return nil }
+
+func (x *xorCipher) Encrypt(name, _ string, w io.Writer) (io.WriteCloser, error) {
+	x.parts = append(x.parts, name)
+	return &xorWriter{w}, nil
+}
+
+func (x *xorCipher) Close(create func(string, string) (io.Writer, error), addRel func(Relationship)) error {
+	w, err := create("/enc.txt", "text/plain")
+	if err != nil {
+		return err
+	}
+	addRel(Relationship{Path: "/enc.txt", Type: "enc"})
+	_, err = io.WriteString(w, strings.Join(x.parts, ","))
+	return err
+}
+
+func (x *xorCipher) Init(rels []Relationship, open func(string) (io.ReadCloser, error)) error {
+	for _, r := range rels {
+		if r.Type == "enc" {
+			rc, err := open(r.Path)
+			if err != nil {
+				return err
+			}
+			defer rc.Close()
+			b, err := ioutil.ReadAll(rc)
+			x.parts = strings.Split(string(b), ",")
+			return err
+		}
+	}
+	return errors.New("not encrypted")
+}
+
+func (x *xorCipher) Decrypt(name string, r io.ReadCloser) (io.ReadCloser, error) {
+	for _, p := range x.parts {
+		if p == name {
+			defer r.Close()
+			var buf bytes.Buffer
+			if _, err := io.Copy(&xorWriter{&buf}, r); err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(&buf), nil
+		}
+	}
+	return r, nil
+}
+
+func TestEncoder_Encode_Encrypter(t *testing.T) {
+	want := &Model{
+		Path: DefaultModelPath,
+		Attachments: []Attachment{
+			{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Data: []byte("pt")},
+		},
+		Relationships: []Relationship{{Path: "/3D/Metadata/pt.xml", Type: "http://schemas.microsoft.com/3dmanufacturing/2013/01/printticket", ID: "1"}},
+		Resources:     Resources{Objects: []*Object{{ID: 1, Mesh: new(Mesh)}}},
+		Childs: map[string]*ChildModel{
+			"/3D/other.model": {Resources: Resources{Objects: []*Object{{ID: 2, Mesh: new(Mesh)}}}},
+		},
+	}
+	var buff bytes.Buffer
+	e := NewEncoder(&buff)
+	enc := new(xorCipher)
+	e.Encrypter = enc
+	if err := e.Encode(want); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	if wantParts := []string{"/3D/Metadata/pt.xml", "/3D/other.model"}; !reflect.DeepEqual(enc.parts, wantParts) {
+		t.Errorf("Encoder.Encode() encrypted = %v, want %v", enc.parts, wantParts)
+	}
+	got := new(Model)
+	d := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	d.BufferAttachments = true
+	if err := d.Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if string(got.Attachments[0].Data) == "pt" {
+		t.Error("Encoder.Encode() attachment is not encrypted")
+	}
+	got = new(Model)
+	d = NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	d.BufferAttachments = true
+	d.Decrypter = new(xorCipher)
+	if err := d.Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	want.RootRelationships = []Relationship{{Path: "/enc.txt", Type: "enc", ID: "rId1"}}
+	want.Attachments = append(want.Attachments, Attachment{ContentType: "text/plain", Path: "/enc.txt", Data: []byte("/3D/Metadata/pt.xml,/3D/other.model")})
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Decoder.Decode() = %v", diff)
+	}
+}
+
+func TestEncoder_Encode_Concurrent(t *testing.T) {
+	var src bytes.Buffer
+	if err := NewEncoder(&src).Encode(&Model{
+		Thumbnail: "/Metadata/thumbnail.png",
+		Attachments: []Attachment{
+			{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Data: []byte("fake")},
+			{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", OpenFunc: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewBufferString("other")), nil
+			}},
+		},
+		Childs: map[string]*ChildModel{
+			"/other.model": {Relationships: []Relationship{
+				{Path: "/3D/Metadata/pt.xml", Type: "http://schemas.microsoft.com/3dmanufacturing/2013/01/printticket", ID: "1"}},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	m := new(Model)
+	if err := NewDecoder(bytes.NewReader(src.Bytes()), int64(src.Len())).Decode(m); err != nil {
+		t.Fatal(err)
+	}
+	var first bytes.Buffer
+	if err := NewEncoder(&first).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	want := new(Model)
+	d := NewDecoder(bytes.NewReader(first.Bytes()), int64(first.Len()))
+	d.BufferAttachments = true
+	if err := d.Decode(want); err != nil {
+		t.Fatal(err)
+	}
+	if len(want.Attachments) != 2 || len(want.Attachments[0].Data) == 0 || len(want.Attachments[1].Data) == 0 {
+		t.Fatalf("Encoder.Encode() attachments = %v", want.Attachments)
+	}
+
+	const n = 4
+	var (
+		wg    sync.WaitGroup
+		buffs [n]bytes.Buffer
+		errs  [n]error
+	)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = NewEncoder(&buffs[i]).Encode(m)
+		}(i)
+	}
+	wg.Wait()
+	for i := range buffs {
+		if errs[i] != nil {
+			t.Fatalf("Encoder.Encode() error = %v", errs[i])
+		}
+		got := new(Model)
+		d := NewDecoder(bytes.NewReader(buffs[i].Bytes()), int64(buffs[i].Len()))
+		d.BufferAttachments = true
+		if err := d.Decode(got); err != nil {
+			t.Fatalf("Encoder.Encode() malformed = %v", err)
+		}
+		if diff := deep.Equal(got, want); diff != nil {
+			t.Errorf("Encoder.Encode() = %v", diff)
+		}
+	}
+}
+
+func TestEncoder_Encode_StreamTwice(t *testing.T) {
+	m := &Model{
+		Thumbnail:   "/Metadata/thumbnail.png",
+		Attachments: []Attachment{{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake")}},
+	}
+	for i := 0; i < 2; i++ {
+		var buff bytes.Buffer
+		if err := NewEncoder(&buff).Encode(m); err != nil {
+			t.Fatal(err)
+		}
+		got := new(Model)
+		d := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+		d.BufferAttachments = true
+		if err := d.Decode(got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Attachments) != 1 || string(got.Attachments[0].Data) != "fake" {
+			t.Errorf("Encoder.Encode() #%d attachments = %v, want fake", i, got.Attachments)
+		}
+	}
+}
@@ -4,7 +4,6 @@
 package errors
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 )
@@ -12,30 +11,32 @@ import (
 // Error guards.
 var (
 	// core
-	ErrMissingID              = errors.New("resource ID MUST be greater than zero")
-	ErrDuplicatedID           = errors.New("IDs MUST be unique among all resources under same Model")
-	ErrMissingResource        = errors.New("resource MUST be defined prior to referencing")
-	ErrDuplicatedIndices      = errors.New("indices v1, v2 and v3 MUST be distinct")
-	ErrIndexOutOfBounds       = errors.New("index is bigger than referenced slice")
-	ErrInsufficientVertices   = errors.New("mesh MUST contain at least 3 vertices to form a solid body")
-	ErrInsufficientTriangles  = errors.New("mesh MUST contain at least 4 triangles to form a solid body")
-	ErrComponentsPID          = errors.New("MUST NOT assign pid to objects that contain components")
-	ErrOPCPartName            = errors.New("part name MUST conform to the syntax specified in the OPC specification")
-	ErrOPCRelTarget           = errors.New("relationship target part MUST be included in the 3MF document")
-	ErrOPCDuplicatedRel       = errors.New("there MUST NOT be more than one relationship of a given type from one part to a second part")
-	ErrOPCContentType         = errors.New("part MUST use an appropriate content type specified")
-	ErrOPCDuplicatedTicket    = errors.New("each model part MUST attach no more than one PrintTicket")
-	ErrOPCDuplicatedModelName = errors.New("model part names MUST be unique")
-	ErrMetadataName           = errors.New("names without a namespace MUST be restricted to predefined values")
-	ErrMetadataNamespace      = errors.New("namespace MUST be declared on the model")
-	ErrMetadataDuplicated     = errors.New("names MUST NOT be duplicated")
-	ErrOtherItem              = errors.New("MUST NOT reference objects of type other")
-	ErrNonObject              = errors.New("MUST NOT reference non-object resources")
-	ErrRequiredExt            = errors.New("unsupported required extension")
-	ErrEmptyResourceProps     = errors.New("resource properties MUST NOT be empty")
-	ErrRecursion              = errors.New("MUST NOT contain recursive references")
-	ErrInvalidObject          = errors.New("MUST contain a mesh or components")
-	ErrMeshConsistency        = errors.New("mesh has non-manifold edges without consistent triangle orientation")
+	ErrMissingID              = NewRule("core-3.3-missing-id", SeverityError, "resource ID MUST be greater than zero")
+	ErrDuplicatedID           = NewRule("core-3.3-duplicated-id", SeverityError, "IDs MUST be unique among all resources under same Model")
+	ErrMissingResource        = NewRule("core-3.3-missing-resource", SeverityError, "resource MUST be defined prior to referencing")
+	ErrDuplicatedIndices      = NewRule("core-4.1.2-distinct-indices", SeverityError, "indices v1, v2 and v3 MUST be distinct")
+	ErrIndexOutOfBounds       = NewRule("core-4.1.2-index-bounds", SeverityError, "index is bigger than referenced slice")
+	ErrInsufficientVertices   = NewRule("core-4.1-vertices", SeverityError, "mesh MUST contain at least 3 vertices to form a solid body")
+	ErrInsufficientTriangles  = NewRule("core-4.1-triangles", SeverityError, "mesh MUST contain at least 4 triangles to form a solid body")
+	ErrComponentsPID          = NewRule("core-4-components-pid", SeverityError, "MUST NOT assign pid to objects that contain components")
+	ErrOPCPartName            = NewRule("core-2-part-name", SeverityError, "part name MUST conform to the syntax specified in the OPC specification")
+	ErrOPCRelTarget           = NewRule("core-2-rel-target", SeverityError, "relationship target part MUST be included in the 3MF document")
+	ErrOPCDuplicatedRel       = NewRule("core-2-duplicated-rel", SeverityError, "there MUST NOT be more than one relationship of a given type from one part to a second part")
+	ErrOPCContentType         = NewRule("core-2.1.4-content-type", SeverityError, "part MUST use an appropriate content type specified")
+	ErrOPCDuplicatedTicket    = NewRule("core-2.1.4-printticket", SeverityError, "each model part MUST attach no more than one PrintTicket")
+	ErrOPCDuplicatedModelName = NewRule("core-2.1.1-model-name", SeverityError, "model part names MUST be unique")
+	ErrMetadataName           = NewRule("core-3.2.1-metadata-name", SeverityError, "names without a namespace MUST be restricted to predefined values")
+	ErrMetadataNamespace      = NewRuleSince("core-3.2.1-metadata-namespace", "1.1", SeverityError, "namespace MUST be declared on the model")
+	ErrMetadataDuplicated     = NewRule("core-3.2.1-metadata-duplicated", SeverityError, "names MUST NOT be duplicated")
+	ErrOtherItem              = NewRule("core-3.4.1-other-item", SeverityError, "MUST NOT reference objects of type other")
+	ErrNonObject              = NewRule("core-3.4.1-non-object", SeverityError, "MUST NOT reference non-object resources")
+	ErrRequiredExt            = NewRule("core-3.2-required-extension", SeverityError, "unsupported required extension")
+	ErrEmptyResourceProps     = NewRule("core-5.1-empty-properties", SeverityError, "resource properties MUST NOT be empty")
+	ErrRecursion              = NewRule("core-4.2-recursion", SeverityError, "MUST NOT contain recursive references")
+	ErrInvalidObject          = NewRule("core-4-object-content", SeverityError, "MUST contain a mesh or components")
+	ErrMeshConsistency        = NewRule("core-4.1-consistency", SeverityError, "mesh has non-manifold edges without consistent triangle orientation")
+	ErrMetadataNameCase       = NewRule("core-3.2.1-metadata-name-case", SeverityWarning, "predefined names SHOULD use the case defined by the spec")
+	ErrModelFolder            = NewRule("core-2.2-model-folder", SeverityWarning, "model parts SHOULD be stored in the /3D folder")
 )
 
 type Level struct {
@@ -54,6 +55,9 @@ type Error struct {
 	Target []Level
 	Err    error
 	Path   string
+	// Line and Column locate the element in the part,
+	// both 1-based and zero if unknown.
+	Line, Column int
 }
 
 func Wrap(err error, name string) error {
@@ -95,6 +99,26 @@ func WrapPath(err error, name string, path string) error {
 	return &Error{Target: []Level{{name, -1}}, Err: err, Path: path}
 }
 
+// WithPosition sets the path, line and column of err,
+// or of all the errors in err if it is a List,
+// unless they are already known.
+func WithPosition(err error, path string, line, column int) error {
+	switch e := err.(type) {
+	case *Error:
+		if e.Path == "" {
+			e.Path = path
+		}
+		if e.Line == 0 {
+			e.Line, e.Column = line, column
+		}
+	case *List:
+		for _, e1 := range e.Errors {
+			WithPosition(e1, path, line, column)
+		}
+	}
+	return err
+}
+
 func (e *Error) Unwrap() error {
 	return e.Err
 }
@@ -108,10 +132,14 @@ func (e *Error) XPath() string {
 }
 
 func (e *Error) Error() string {
-	if e.Path == "" {
-		return fmt.Sprintf("go3mf: XPath: %s: %v", e.XPath(), e.Err)
+	var pos string
+	if e.Path != "" {
+		pos = fmt.Sprintf("Path: %s ", e.Path)
+	}
+	if e.Line > 0 {
+		pos += fmt.Sprintf("Line: %d Column: %d ", e.Line, e.Column)
 	}
-	return fmt.Sprintf("go3mf: Path: %s XPath: %s: %v", e.Path, e.XPath(), e.Err)
+	return fmt.Sprintf("go3mf: %sXPath: %s: %v", pos, e.XPath(), e.Err)
 }
 
 func NewMissingFieldError(name string) error {
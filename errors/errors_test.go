@@ -0,0 +1,45 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package errors
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestError_Error(t *testing.T) {
+	err := errors.New("foo")
+	target := []Level{{"object", 1}, {"model", -1}}
+	tests := []struct {
+		err  *Error
+		want string
+	}{
+		{&Error{Target: target, Err: err}, "go3mf: XPath: /model/object[1]: foo"},
+		{&Error{Target: target, Err: err, Path: "/3D/a.model"}, "go3mf: Path: /3D/a.model XPath: /model/object[1]: foo"},
+		{&Error{Target: target, Err: err, Line: 2, Column: 3}, "go3mf: Line: 2 Column: 3 XPath: /model/object[1]: foo"},
+		{&Error{Target: target, Err: err, Path: "/3D/a.model", Line: 2, Column: 3}, "go3mf: Path: /3D/a.model Line: 2 Column: 3 XPath: /model/object[1]: foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error.Error() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithPosition(t *testing.T) {
+	known := &Error{Err: errors.New("foo"), Path: "/a.model", Line: 1, Column: 1}
+	unknown := &Error{Err: errors.New("bar")}
+	err := WithPosition(&List{Errors: []error{known, unknown, errors.New("baz")}}, "/b.model", 5, 6)
+	if known.Path != "/a.model" || known.Line != 1 || known.Column != 1 {
+		t.Errorf("WithPosition() overwritten = %v", known)
+	}
+	if unknown.Path != "/b.model" || unknown.Line != 5 || unknown.Column != 6 {
+		t.Errorf("WithPosition() not set = %v", unknown)
+	}
+	if len(err.(*List).Errors) != 3 {
+		t.Errorf("WithPosition() = %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package errors
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Severity of a validation error.
+type Severity int
+
+// Severities.
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return "error"
+}
+
+// MarshalText encodes the severity as its name.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Generic rule codes, used for the errors that are not tied to a spec rule.
+const (
+	CodeParseAttr    = "parse-attribute"
+	CodeMissingField = "missing-field"
+	CodeXMLSyntax    = "xml-syntax"
+	CodeUnknown      = "unknown"
+)
+
+// Code returns the stable code of the rule violated by err.
+//
+// Errors implementing Code() string define their own code.
+func Code(err error) string {
+	var coder interface{ Code() string }
+	if errors.As(err, &coder) {
+		return coder.Code()
+	}
+	var (
+		perr *ParseAttrError
+		merr *MissingFieldError
+		serr *xml.SyntaxError
+	)
+	switch {
+	case errors.As(err, &perr):
+		return CodeParseAttr
+	case errors.As(err, &merr):
+		return CodeMissingField
+	case errors.As(err, &serr):
+		return CodeXMLSyntax
+	}
+	return CodeUnknown
+}
+
+// SeverityOf returns the severity of err.
+//
+// Errors implementing Severity() Severity define their own severity,
+// the rest are SeverityError.
+func SeverityOf(err error) Severity {
+	var s interface{ Severity() Severity }
+	if errors.As(err, &s) {
+		return s.Severity()
+	}
+	return SeverityError
+}
+
+// A Record is a flattened validation or decoding error.
+type Record struct {
+	Path     string   `json:"path,omitempty"`
+	XPath    string   `json:"xpath,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (r *Record) location() string {
+	var sb strings.Builder
+	sb.WriteString(r.Path)
+	if r.Line > 0 {
+		fmt.Fprintf(&sb, ":%d:%d", r.Line, r.Column)
+	}
+	if r.XPath != "" {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(r.XPath)
+	}
+	return sb.String()
+}
+
+// Records flattens err, as returned by the decoder or the validation functions,
+// into one record per error.
+func Records(err error) []Record {
+	var records []Record
+	var walk func(error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case nil:
+		case *List:
+			for _, e1 := range e.Errors {
+				walk(e1)
+			}
+		case *Error:
+			records = append(records, Record{
+				Path: e.Path, XPath: e.XPath(), Line: e.Line, Column: e.Column,
+				Code: Code(e.Err), Severity: SeverityOf(e.Err), Message: e.Err.Error(),
+			})
+		default:
+			records = append(records, Record{Code: Code(err), Severity: SeverityOf(err), Message: err.Error()})
+		}
+	}
+	walk(err)
+	return records
+}
+
+// WriteJSON writes the records of err as a JSON array.
+func WriteJSON(w io.Writer, err error) error {
+	records := Records(err)
+	if records == nil {
+		records = []Record{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the records of err as a JUnit XML report
+// with a test suite called name.
+//
+// Each record is a test case, failed if its severity is SeverityError.
+// A report without records contains a single passing test case.
+func WriteJUnit(w io.Writer, name string, err error) error {
+	records := Records(err)
+	suite := junitSuite{Name: name, Tests: len(records)}
+	for _, r := range records {
+		c := junitCase{Name: r.location(), ClassName: r.Code}
+		text := fmt.Sprintf("%s: %s", r.Severity, r.Message)
+		if c.Name == "" {
+			c.Name = r.Message
+		} else {
+			text = fmt.Sprintf("%s: %s: %s", r.Severity, c.Name, r.Message)
+		}
+		if r.Severity == SeverityError {
+			suite.Failures++
+			c.Failure = &junitFailure{Type: r.Code, Message: r.Message, Text: text}
+		} else {
+			c.SystemOut = text
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	if len(records) == 0 {
+		suite.Tests = 1
+		suite.Cases = []junitCase{{Name: name, ClassName: name}}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "note"
+	}
+	return "error"
+}
+
+// WriteSARIF writes the records of err as a SARIF 2.1.0 log.
+//
+// The part paths are written as URIs relative to the package root
+// and the XPaths as logical locations.
+func WriteSARIF(w io.Writer, err error) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "go3mf", InformationURI: "https://github.com/hpinc/go3mf"}},
+		Results: []sarifResult{},
+	}
+	rules := make(map[string]int)
+	for _, r := range Records(err) {
+		index, ok := rules[r.Code]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			rules[r.Code] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: r.Code})
+		}
+		res := sarifResult{RuleID: r.Code, RuleIndex: index, Level: sarifLevel(r.Severity), Message: sarifMessage{Text: r.Message}}
+		var loc sarifLocation
+		if r.Path != "" {
+			loc.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: strings.TrimPrefix(r.Path, "/")}}
+			if r.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: r.Line, StartColumn: r.Column}
+			}
+		}
+		if r.XPath != "" {
+			loc.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: r.XPath, Kind: "element"}}
+		}
+		if loc.PhysicalLocation != nil || loc.LogicalLocations != nil {
+			res.Locations = []sarifLocation{loc}
+		}
+		run.Results = append(run.Results, res)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
@@ -0,0 +1,146 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+type warning struct{}
+
+func (warning) Error() string      { return "not recommended" }
+func (warning) Code() string       { return "core-test" }
+func (warning) Severity() Severity { return SeverityWarning }
+
+func newReportError() error {
+	return &List{Errors: []error{
+		&Error{Target: []Level{{"object", 1}, {"resources", -1}, {"model", -1}}, Err: NewParseAttrError("id", true), Path: "/3D/3dmodel.model", Line: 3, Column: 4},
+		&Error{Target: []Level{{"metadata", 0}, {"model", -1}}, Err: warning{}},
+		&xml.SyntaxError{Msg: "unexpected EOF", Line: 2},
+	}}
+}
+
+func TestRecords(t *testing.T) {
+	want := []Record{
+		{Path: "/3D/3dmodel.model", XPath: "/model/resources/object[1]", Line: 3, Column: 4, Code: CodeParseAttr, Message: "error parsing required attribute 'id'"},
+		{XPath: "/model/metadata[0]", Code: "core-test", Severity: SeverityWarning, Message: "not recommended"},
+		{Code: CodeXMLSyntax, Message: "XML syntax error on line 2: unexpected EOF"},
+	}
+	if diff := deep.Equal(Records(newReportError()), want); diff != nil {
+		t.Errorf("Records() = %v", diff)
+	}
+	if got := Records(nil); got != nil {
+		t.Errorf("Records() = %v, want nil", got)
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{NewMissingFieldError("id"), CodeMissingField},
+		{WrapIndex(ErrDuplicatedIndices, "triangle", 1), "core-4.1.2-distinct-indices"},
+		{&Error{Err: warning{}}, "core-test"},
+		{errors.New("other"), CodeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := Code(tt.err); got != tt.want {
+				t.Errorf("Code() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, newReportError()); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("WriteJSON() invalid JSON = %v", err)
+	}
+	want := map[string]interface{}{
+		"path": "/3D/3dmodel.model", "xpath": "/model/resources/object[1]", "line": 3.0, "column": 4.0,
+		"code": CodeParseAttr, "severity": "error", "message": "error parsing required attribute 'id'",
+	}
+	if len(got) != 3 {
+		t.Fatalf("WriteJSON() = %s", buf.String())
+	}
+	if diff := deep.Equal(got[0], want); diff != nil {
+		t.Errorf("WriteJSON() = %v", diff)
+	}
+	buf.Reset()
+	WriteJSON(&buf, nil)
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("WriteJSON() = %v, want []", got)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, "cube.3mf", newReportError()); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="cube.3mf" tests="3" failures="2">
+    <testcase name="/3D/3dmodel.model:3:4 /model/resources/object[1]" classname="parse-attribute">
+      <failure type="parse-attribute" message="error parsing required attribute &#39;id&#39;">error: /3D/3dmodel.model:3:4 /model/resources/object[1]: error parsing required attribute &#39;id&#39;</failure>
+    </testcase>
+    <testcase name="/model/metadata[0]" classname="core-test">
+      <system-out>warning: /model/metadata[0]: not recommended</system-out>
+    </testcase>
+    <testcase name="XML syntax error on line 2: unexpected EOF" classname="xml-syntax">
+      <failure type="xml-syntax" message="XML syntax error on line 2: unexpected EOF">error: XML syntax error on line 2: unexpected EOF</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteJUnit() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, newReportError()); err != nil {
+		t.Fatalf("WriteSARIF() error = %v", err)
+	}
+	var got sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("WriteSARIF() invalid JSON = %v", err)
+	}
+	if got.Version != sarifVersion || len(got.Runs) != 1 {
+		t.Fatalf("WriteSARIF() = %s", buf.String())
+	}
+	run := got.Runs[0]
+	if diff := deep.Equal(run.Tool.Driver.Rules, []sarifRule{{CodeParseAttr}, {"core-test"}, {CodeXMLSyntax}}); diff != nil {
+		t.Errorf("WriteSARIF() rules = %v", diff)
+	}
+	want := sarifResult{
+		RuleID: CodeParseAttr, Level: "error", Message: sarifMessage{"error parsing required attribute 'id'"},
+		Locations: []sarifLocation{{
+			PhysicalLocation: &sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{"3D/3dmodel.model"},
+				Region:           &sarifRegion{StartLine: 3, StartColumn: 4},
+			},
+			LogicalLocations: []sarifLogicalLocation{{"/model/resources/object[1]", "element"}},
+		}},
+	}
+	if diff := deep.Equal(run.Results[0], want); diff != nil {
+		t.Errorf("WriteSARIF() result = %v", diff)
+	}
+	if run.Results[1].Level != "warning" || run.Results[1].RuleIndex != 1 || run.Results[2].Locations != nil {
+		t.Errorf("WriteSARIF() results = %v", run.Results[1:])
+	}
+}
@@ -0,0 +1,111 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package errors
+
+import (
+	"errors"
+	"strings"
+)
+
+// A Rule is a requirement of a 3MF spec.
+// Rules are used as sentinel errors, to be reported when they are violated.
+//
+// Rule codes have the form <spec>-<section>-<rule>, where spec is
+// the spec the rule belongs to and section the number of the chapter
+// or section of that spec defining it, such as core-4.1.2-distinct-indices.
+// Rules shared by several specs use the section of the core spec defining them.
+//
+// MUST requirements have SeverityError, SHOULD recommendations
+// have SeverityWarning and informative notes SeverityInfo.
+type Rule struct {
+	code     string
+	since    string
+	severity Severity
+	msg      string
+}
+
+// NewRule returns a Rule with the given code, severity and message.
+func NewRule(code string, severity Severity, msg string) error {
+	return &Rule{code: code, severity: severity, msg: msg}
+}
+
+// NewRuleSince is like NewRule for a rule introduced by the spec version since.
+func NewRuleSince(code, since string, severity Severity, msg string) error {
+	return &Rule{code: code, since: since, severity: severity, msg: msg}
+}
+
+func (r *Rule) Error() string {
+	return r.msg
+}
+
+// Code returns the stable code of the rule.
+func (r *Rule) Code() string {
+	return r.code
+}
+
+// Section returns the number of the spec section defining the rule, such as 4.1.2.
+func (r *Rule) Section() string {
+	s := r.code[strings.IndexByte(r.code, '-')+1:]
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// Since returns the spec version introducing the rule,
+// empty if it applies to all the versions.
+func (r *Rule) Since() string {
+	return r.since
+}
+
+// Severity returns the severity of the rule violations.
+func (r *Rule) Severity() Severity {
+	return r.severity
+}
+
+// Since returns the spec version introducing the rule violated by err,
+// empty if it is not a Rule or it applies to all the versions.
+func Since(err error) string {
+	var r *Rule
+	if errors.As(err, &r) {
+		return r.since
+	}
+	return ""
+}
+
+// Filter returns the errors in err for which keep returns true,
+// nil if there is none.
+// The Err of an Error is passed to keep.
+func Filter(err error, keep func(error) bool) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *List:
+		var errs []error
+		for _, e1 := range e.Errors {
+			if e1 = Filter(e1, keep); e1 != nil {
+				errs = append(errs, e1)
+			}
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		return &List{Errors: errs}
+	case *Error:
+		if keep(e.Err) {
+			return e
+		}
+		return nil
+	}
+	if keep(err) {
+		return err
+	}
+	return nil
+}
+
+// HasErrors reports whether err contains any error with SeverityError,
+// that is, whether err is not only made of warnings and infos.
+func HasErrors(err error) bool {
+	return Filter(err, func(e error) bool { return SeverityOf(e) == SeverityError }) != nil
+}
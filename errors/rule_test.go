@@ -0,0 +1,97 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestRule(t *testing.T) {
+	err := WrapIndex(ErrMetadataNameCase, "metadata", 0)
+	if !errors.Is(err, ErrMetadataNameCase) {
+		t.Errorf("errors.Is() = false, want true")
+	}
+	if got := Code(err); got != "core-3.2.1-metadata-name-case" {
+		t.Errorf("Code() = %v", got)
+	}
+	if got := ErrMetadataNameCase.(*Rule).Section(); got != "3.2.1" {
+		t.Errorf("Rule.Section() = %v, want 3.2.1", got)
+	}
+	if got := SeverityOf(err); got != SeverityWarning {
+		t.Errorf("SeverityOf() = %v, want %v", got, SeverityWarning)
+	}
+	if got := err.Error(); got != "go3mf: XPath: /metadata[0]: predefined names SHOULD use the case defined by the spec" {
+		t.Errorf("Rule.Error() = %v", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	warn := Wrap(ErrMetadataNameCase, "model")
+	fail := Wrap(ErrMissingID, "model")
+	other := errors.New("other")
+	isError := func(err error) bool { return SeverityOf(err) == SeverityError }
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"nil", nil, nil},
+		{"kept", fail, fail},
+		{"filtered", warn, nil},
+		{"plain", other, other},
+		{"list", &List{Errors: []error{warn, fail, &List{Errors: []error{warn}}, other}}, &List{Errors: []error{fail, other}}},
+		{"emptyList", &List{Errors: []error{warn}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := deep.Equal(Filter(tt.err, isError), tt.want); diff != nil {
+				t.Errorf("Filter() = %v", diff)
+			}
+		})
+	}
+}
+
+func TestHasErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"warnings", &List{Errors: []error{ErrMetadataNameCase, Wrap(ErrMetadataNameCase, "model")}}, false},
+		{"errors", &List{Errors: []error{ErrMetadataNameCase, Wrap(ErrMissingID, "model")}}, true},
+		{"plain", errors.New("other"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasErrors(tt.err); got != tt.want {
+				t.Errorf("HasErrors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSince(t *testing.T) {
+	rule := NewRuleSince("core-0-test", "1.3", SeverityError, "test")
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"since", WrapIndex(rule, "metadata", 0), "1.3"},
+		{"tagged", Wrap(ErrMetadataNamespace, "model"), "1.1"},
+		{"always", Wrap(ErrMissingID, "model"), ""},
+		{"plain", errors.New("other"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Since(tt.err); got != tt.want {
+				t.Errorf("Since() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+// Package amf implements a decoder for the Additive Manufacturing File Format
+// as defined in ISO/ASTM 52915.
+package amf
+
+import (
+	"encoding/xml"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hpinc/go3mf"
+)
+
+type document struct {
+	XMLName        xml.Name        `xml:"amf"`
+	Unit           string          `xml:"unit,attr"`
+	Objects        []object        `xml:"object"`
+	Materials      []material      `xml:"material"`
+	Constellations []constellation `xml:"constellation"`
+}
+
+type metadata struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type metadataGroup []metadata
+
+// name returns the value of the name metadata.
+func (m metadataGroup) name() string {
+	for _, md := range m {
+		if strings.EqualFold(md.Type, "name") {
+			return strings.TrimSpace(md.Value)
+		}
+	}
+	return ""
+}
+
+// amfColor defines a color with components between 0 and 1.
+// Components can also be formulas, which are not supported.
+type amfColor struct {
+	R string `xml:"r"`
+	G string `xml:"g"`
+	B string `xml:"b"`
+	A string `xml:"a"`
+}
+
+// rgba returns the color c, if defined and not defined by a formula.
+func (c *amfColor) rgba() (color.RGBA, bool) {
+	if c == nil {
+		return color.RGBA{}, false
+	}
+	var rgba [4]uint8
+	for i, s := range [4]string{c.R, c.G, c.B, c.A} {
+		s = strings.TrimSpace(s)
+		if s == "" && i == 3 {
+			rgba[i] = 255
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return color.RGBA{}, false
+		}
+		if v < 0 {
+			v = 0
+		} else if v > 1 {
+			v = 1
+		}
+		rgba[i] = uint8(v*255 + 0.5)
+	}
+	return color.RGBA{R: rgba[0], G: rgba[1], B: rgba[2], A: rgba[3]}, true
+}
+
+type object struct {
+	ID       string        `xml:"id,attr"`
+	Metadata metadataGroup `xml:"metadata"`
+	Color    *amfColor     `xml:"color"`
+	Mesh     mesh          `xml:"mesh"`
+}
+
+type mesh struct {
+	Vertices []vertex `xml:"vertices>vertex"`
+	Volumes  []volume `xml:"volume"`
+}
+
+type vertex struct {
+	Coordinates struct {
+		X float32 `xml:"x"`
+		Y float32 `xml:"y"`
+		Z float32 `xml:"z"`
+	} `xml:"coordinates"`
+	Color *amfColor `xml:"color"`
+}
+
+type volume struct {
+	MaterialID string        `xml:"materialid,attr"`
+	Metadata   metadataGroup `xml:"metadata"`
+	Color      *amfColor     `xml:"color"`
+	Triangles  []triangle    `xml:"triangle"`
+}
+
+type triangle struct {
+	V1    uint32    `xml:"v1"`
+	V2    uint32    `xml:"v2"`
+	V3    uint32    `xml:"v3"`
+	Color *amfColor `xml:"color"`
+}
+
+type material struct {
+	ID       string        `xml:"id,attr"`
+	Metadata metadataGroup `xml:"metadata"`
+	Color    *amfColor     `xml:"color"`
+}
+
+type constellation struct {
+	ID        string     `xml:"id,attr"`
+	Instances []instance `xml:"instance"`
+}
+
+type instance struct {
+	ObjectID string  `xml:"objectid,attr"`
+	DeltaX   float32 `xml:"deltax"`
+	DeltaY   float32 `xml:"deltay"`
+	DeltaZ   float32 `xml:"deltaz"`
+	RX       float32 `xml:"rx"`
+	RY       float32 `xml:"ry"`
+	RZ       float32 `xml:"rz"`
+}
+
+// transform returns the instance transform.
+// The rotations, in degrees, are applied first around the X axis,
+// then around the Y axis and then around the Z axis, followed by the translation.
+func (i *instance) transform() go3mf.Matrix {
+	sin, cos := func(deg float32) float32 {
+		return float32(math.Sin(float64(deg) * math.Pi / 180))
+	}, func(deg float32) float32 {
+		return float32(math.Cos(float64(deg) * math.Pi / 180))
+	}
+	rx := go3mf.Matrix{1, 0, 0, 0, 0, cos(i.RX), sin(i.RX), 0, 0, -sin(i.RX), cos(i.RX), 0, 0, 0, 0, 1}
+	ry := go3mf.Matrix{cos(i.RY), 0, -sin(i.RY), 0, 0, 1, 0, 0, sin(i.RY), 0, cos(i.RY), 0, 0, 0, 0, 1}
+	rz := go3mf.Matrix{cos(i.RZ), sin(i.RZ), 0, 0, -sin(i.RZ), cos(i.RZ), 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+	return rz.Mul(ry.Mul(rx)).Translate(i.DeltaX, i.DeltaY, i.DeltaZ)
+}
+
+func newUnits(s string) (go3mf.Units, bool) {
+	u, ok := map[string]go3mf.Units{
+		"":           go3mf.UnitMillimeter,
+		"millimeter": go3mf.UnitMillimeter,
+		"micron":     go3mf.UnitMicrometer,
+		"micrometer": go3mf.UnitMicrometer,
+		"centimeter": go3mf.UnitCentimeter,
+		"inch":       go3mf.UnitInch,
+		"feet":       go3mf.UnitFoot,
+		"foot":       go3mf.UnitFoot,
+		"meter":      go3mf.UnitMeter,
+	}[strings.ToLower(s)]
+	return u, ok
+}
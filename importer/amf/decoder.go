@@ -0,0 +1,447 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package amf
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/importer"
+	"github.com/hpinc/go3mf/materials"
+)
+
+var zipMagic = []byte("PK\x03\x04")
+
+var (
+	errEmptyArchive      = errors.New("amf: zip archive does not contain any file")
+	errIndexOutOfBounds  = errors.New("amf: triangle references an unexisting vertex")
+	errMissingReference  = errors.New("amf: instance references an unexisting object")
+	errRecursiveInstance = errors.New("amf: constellation MUST NOT contain recursive references")
+)
+
+func init() {
+	importer.Register("amf", format{})
+}
+
+// format implements the importer.Importer interface.
+type format struct{}
+
+// Sniff reports whether head is an XML document with an amf element
+// or a zip archive whose first entry is an AMF file.
+func (format) Sniff(head []byte) bool {
+	const sizeOfLocalHeader = 30
+	if !bytes.HasPrefix(head, zipMagic) {
+		return bytes.Contains(head, []byte("<amf"))
+	}
+	if len(head) < sizeOfLocalHeader {
+		return false
+	}
+	name := head[sizeOfLocalHeader:]
+	if n := int(binary.LittleEndian.Uint16(head[26:28])); n < len(name) {
+		name = name[:n]
+	}
+	return strings.HasSuffix(strings.ToLower(string(name)), ".amf")
+}
+
+func (format) DecodeContext(ctx context.Context, r io.Reader, m *go3mf.Model) error {
+	return NewDecoder(r).DecodeContext(ctx, m)
+}
+
+// Decoder can decode AMF files, both plain XML and zip compressed.
+//
+// Every volume is decoded as a mesh object. Objects with more than one volume
+// are decoded as a components object referencing the volume objects.
+// Constellations are decoded as components objects, the top level constellations
+// being the build items. If there are no constellations every object
+// is added to the build.
+//
+// Materials are decoded as go3mf.BaseMaterials and colors defined
+// in objects, volumes, vertices or triangles as a materials.ColorGroup.
+// Curved triangles, textures and composite materials are not supported.
+type Decoder struct {
+	r io.Reader
+}
+
+// NewDecoder creates a new decoder.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: r,
+	}
+}
+
+// Decode adds the content of the read stream to m.
+func (d *Decoder) Decode(m *go3mf.Model) error {
+	return d.DecodeContext(context.Background(), m)
+}
+
+// DecodeContext adds the content of the read stream to m.
+func (d *Decoder) DecodeContext(ctx context.Context, m *go3mf.Model) error {
+	b, err := ioutil.ReadAll(d.r)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(b, zipMagic) {
+		if b, err = unzip(b); err != nil {
+			return err
+		}
+	}
+	var doc document
+	if err = xml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	units, ok := newUnits(doc.Unit)
+	if !ok {
+		return fmt.Errorf("amf: unsupported unit %q", doc.Unit)
+	}
+	m.Units = units
+	dec := docDecoder{
+		ctx:        ctx,
+		m:          m,
+		rs:         go3mf.NewResourceIndex(&m.Resources),
+		doc:        &doc,
+		ids:        make(map[string]uint32),
+		baseIndex:  make(map[string]uint32),
+		colorIndex: make(map[color.RGBA]uint32),
+	}
+	return dec.decode()
+}
+
+// unzip returns the content of the first file of a zip archive.
+func unzip(b []byte) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return ioutil.ReadAll(rc)
+	}
+	return nil, errEmptyArchive
+}
+
+// docDecoder adds the content of an AMF document to a model.
+type docDecoder struct {
+	ctx        context.Context
+	m          *go3mf.Model
+	rs         *go3mf.ResourceIndex
+	doc        *document
+	ids        map[string]uint32 // AMF id -> object ID
+	base       *go3mf.BaseMaterials
+	baseIndex  map[string]uint32 // material id -> base index
+	colors     *materials.ColorGroup
+	colorIndex map[color.RGBA]uint32
+}
+
+func (d *docDecoder) decode() error {
+	for i := range d.doc.Objects {
+		select {
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		default: // Default is must to avoid blocking
+		}
+		if err := d.addObject(&d.doc.Objects[i]); err != nil {
+			return err
+		}
+	}
+	if len(d.doc.Constellations) == 0 {
+		for _, o := range d.doc.Objects {
+			if id, ok := d.ids[o.ID]; ok {
+				d.m.Build.Items = append(d.m.Build.Items, &go3mf.Item{ObjectID: id})
+			}
+		}
+		return nil
+	}
+	referenced := make(map[string]bool)
+	for _, c := range d.doc.Constellations {
+		for _, inst := range c.Instances {
+			referenced[inst.ObjectID] = true
+		}
+	}
+	for i := range d.doc.Constellations {
+		c := &d.doc.Constellations[i]
+		id, ok, err := d.addConstellation(c, nil)
+		if err != nil {
+			return err
+		}
+		if ok && !referenced[c.ID] {
+			d.m.Build.Items = append(d.m.Build.Items, &go3mf.Item{ObjectID: id})
+		}
+	}
+	return nil
+}
+
+func (d *docDecoder) addObject(o *object) error {
+	name := o.Metadata.name()
+	var components []*go3mf.Component
+	for i := range o.Mesh.Volumes {
+		v := &o.Mesh.Volumes[i]
+		obj, err := d.volumeObject(o, v)
+		if err != nil {
+			return err
+		}
+		if obj == nil {
+			continue
+		}
+		if obj.Name = v.Metadata.name(); obj.Name == "" {
+			obj.Name = name
+		}
+		obj.ID = d.rs.NewID()
+		d.rs.AddObject(obj)
+		components = append(components, &go3mf.Component{ObjectID: obj.ID})
+	}
+	switch len(components) {
+	case 0:
+		return nil
+	case 1:
+		d.ids[o.ID] = components[0].ObjectID
+	default:
+		id := d.rs.NewID()
+		d.rs.AddObject(&go3mf.Object{
+			ID:         id,
+			Name:       name,
+			Components: &go3mf.Components{Component: components},
+		})
+		d.ids[o.ID] = id
+	}
+	return nil
+}
+
+// volumeObject returns a mesh object containing the triangles of v
+// and the vertices referenced by them.
+// It returns nil if the volume does not contain any triangle.
+func (d *docDecoder) volumeObject(o *object, v *volume) (*go3mf.Object, error) {
+	if len(v.Triangles) == 0 {
+		return nil, nil
+	}
+	var (
+		mesh     go3mf.Mesh
+		vertices = make(map[uint32]uint32)
+		count    = uint32(len(o.Mesh.Vertices))
+	)
+	addVertex := func(i uint32) uint32 {
+		if j, ok := vertices[i]; ok {
+			return j
+		}
+		j := uint32(len(mesh.Vertices.Vertex))
+		c := o.Mesh.Vertices[i].Coordinates
+		mesh.Vertices.Vertex = append(mesh.Vertices.Vertex, go3mf.Point3D{c.X, c.Y, c.Z})
+		vertices[i] = j
+		return j
+	}
+	mesh.Triangles.Triangle = make([]go3mf.Triangle, 0, len(v.Triangles))
+	for _, t := range v.Triangles {
+		if t.V1 >= count || t.V2 >= count || t.V3 >= count {
+			return nil, errIndexOutOfBounds
+		}
+		tr := go3mf.Triangle{V1: addVertex(t.V1), V2: addVertex(t.V2), V3: addVertex(t.V3)}
+		tr.PID, tr.P1, tr.P2, tr.P3 = d.triangleProperties(o, v, t)
+		mesh.Triangles.Triangle = append(mesh.Triangles.Triangle, tr)
+	}
+	obj := &go3mf.Object{Mesh: &mesh}
+	for _, t := range mesh.Triangles.Triangle {
+		if t.PID != 0 {
+			obj.PID, obj.PIndex = t.PID, t.P1
+			break
+		}
+	}
+	return obj, nil
+}
+
+// triangleProperties returns the properties of a triangle.
+// The AMF colors precedence is, from highest to lowest:
+// triangle, vertex, volume, material and object.
+func (d *docDecoder) triangleProperties(o *object, v *volume, t triangle) (pid, p1, p2, p3 uint32) {
+	if c, ok := t.Color.rgba(); ok {
+		index := d.addColor(c)
+		return d.colors.ID, index, index, index
+	}
+	var (
+		colors         [3]color.RGBA
+		hasColor       [3]bool
+		hasVertexColor bool
+	)
+	for i, vi := range [3]uint32{t.V1, t.V2, t.V3} {
+		colors[i], hasColor[i] = o.Mesh.Vertices[vi].Color.rgba()
+		hasVertexColor = hasVertexColor || hasColor[i]
+	}
+	if !hasVertexColor {
+		pid, index := d.volumeProperty(o, v)
+		return pid, index, index, index
+	}
+	var p [3]uint32
+	for i := range p {
+		if !hasColor[i] {
+			if colors[i], hasColor[i] = d.volumeColor(o, v); !hasColor[i] {
+				colors[i] = color.RGBA{255, 255, 255, 255}
+			}
+		}
+		p[i] = d.addColor(colors[i])
+	}
+	return d.colors.ID, p[0], p[1], p[2]
+}
+
+// volumeColor returns the color of the volume, its material or its object.
+func (d *docDecoder) volumeColor(o *object, v *volume) (color.RGBA, bool) {
+	if c, ok := v.Color.rgba(); ok {
+		return c, true
+	}
+	if mat, ok := d.material(v.MaterialID); ok {
+		if c, ok := mat.Color.rgba(); ok {
+			return c, true
+		}
+	}
+	return o.Color.rgba()
+}
+
+// volumeProperty returns the property shared by all the triangles
+// of a volume without triangle nor vertex colors.
+func (d *docDecoder) volumeProperty(o *object, v *volume) (pid, index uint32) {
+	if c, ok := v.Color.rgba(); ok {
+		index = d.addColor(c)
+		return d.colors.ID, index
+	}
+	if mat, ok := d.material(v.MaterialID); ok {
+		index = d.addBaseMaterial(mat)
+		return d.base.ID, index
+	}
+	if c, ok := o.Color.rgba(); ok {
+		index = d.addColor(c)
+		return d.colors.ID, index
+	}
+	return 0, 0
+}
+
+func (d *docDecoder) material(id string) (*material, bool) {
+	if id == "" {
+		return nil, false
+	}
+	for i := range d.doc.Materials {
+		if d.doc.Materials[i].ID == id {
+			return &d.doc.Materials[i], true
+		}
+	}
+	return nil, false
+}
+
+func (d *docDecoder) addBaseMaterial(mat *material) uint32 {
+	if d.base == nil {
+		d.base = &go3mf.BaseMaterials{ID: d.rs.NewID()}
+		d.rs.AddAsset(d.base)
+	}
+	if index, ok := d.baseIndex[mat.ID]; ok {
+		return index
+	}
+	name := mat.Metadata.name()
+	if name == "" {
+		name = "material" + mat.ID
+	}
+	c, ok := mat.Color.rgba()
+	if !ok {
+		c = color.RGBA{255, 255, 255, 255}
+	}
+	index := uint32(len(d.base.Materials))
+	d.base.Materials = append(d.base.Materials, go3mf.Base{Name: name, Color: c})
+	d.baseIndex[mat.ID] = index
+	return index
+}
+
+func (d *docDecoder) addColor(c color.RGBA) uint32 {
+	if d.colors == nil {
+		d.colors = &materials.ColorGroup{ID: d.rs.NewID()}
+		d.rs.AddAsset(d.colors)
+		importer.AddExtension(d.m, materials.DefaultExtension)
+	}
+	if index, ok := d.colorIndex[c]; ok {
+		return index
+	}
+	index := uint32(len(d.colors.Colors))
+	d.colors.Colors = append(d.colors.Colors, c)
+	d.colorIndex[c] = index
+	return index
+}
+
+func (d *docDecoder) findConstellation(id string) (*constellation, bool) {
+	for i := range d.doc.Constellations {
+		if d.doc.Constellations[i].ID == id {
+			return &d.doc.Constellations[i], true
+		}
+	}
+	return nil, false
+}
+
+// addConstellation adds a components object with the instances of c.
+// It returns false if none of the instances reference an object with content.
+func (d *docDecoder) addConstellation(c *constellation, visiting []string) (uint32, bool, error) {
+	if id, ok := d.ids[c.ID]; ok {
+		return id, true, nil
+	}
+	for _, v := range visiting {
+		if v == c.ID {
+			return 0, false, errRecursiveInstance
+		}
+	}
+	visiting = append(visiting, c.ID)
+	var components []*go3mf.Component
+	for i := range c.Instances {
+		inst := &c.Instances[i]
+		id, ok := d.ids[inst.ObjectID]
+		if !ok {
+			ref, found := d.findConstellation(inst.ObjectID)
+			if !found {
+				if d.isObject(inst.ObjectID) {
+					continue // empty object
+				}
+				return 0, false, errMissingReference
+			}
+			var err error
+			if id, ok, err = d.addConstellation(ref, visiting); err != nil {
+				return 0, false, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		comp := &go3mf.Component{ObjectID: id}
+		if t := inst.transform(); t != go3mf.Identity() {
+			comp.Transform = t
+		}
+		components = append(components, comp)
+	}
+	if len(components) == 0 {
+		return 0, false, nil
+	}
+	id := d.rs.NewID()
+	d.rs.AddObject(&go3mf.Object{
+		ID:         id,
+		Components: &go3mf.Components{Component: components},
+	})
+	d.ids[c.ID] = id
+	return id, true, nil
+}
+
+func (d *docDecoder) isObject(id string) bool {
+	for _, o := range d.doc.Objects {
+		if o.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package amf
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"image/color"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/materials"
+)
+
+const twoVolumes = `<?xml version="1.0" encoding="UTF-8"?>
+<amf unit="inch" version="1.1">
+  <metadata type="name">test</metadata>
+  <material id="1">
+    <metadata type="name">Red</metadata>
+    <color><r>1</r><g>0</g><b>0</b></color>
+  </material>
+  <material id="2">
+    <color><r>0.5*x</r><g>0</g><b>0</b></color>
+  </material>
+  <object id="0">
+    <metadata type="name">part</metadata>
+    <mesh>
+      <vertices>
+        <vertex><coordinates><x>0</x><y>0</y><z>0</z></coordinates></vertex>
+        <vertex><coordinates><x>1</x><y>0</y><z>0</z></coordinates></vertex>
+        <vertex><coordinates><x>0</x><y>1</y><z>0</z></coordinates></vertex>
+        <vertex><coordinates><x>0</x><y>0</y><z>1</z></coordinates><color><r>0</r><g>0</g><b>1</b><a>0.5</a></color></vertex>
+      </vertices>
+      <volume materialid="1">
+        <metadata type="name">first</metadata>
+        <triangle><v1>0</v1><v2>2</v2><v3>1</v3></triangle>
+        <triangle><v1>0</v1><v2>1</v2><v3>2</v3><color><r>0</r><g>1</g><b>0</b></color></triangle>
+      </volume>
+      <volume materialid="2">
+        <triangle><v1>0</v1><v2>1</v2><v3>3</v3></triangle>
+      </volume>
+      <volume/>
+    </mesh>
+  </object>
+  <object id="1">
+    <color><r>0</r><g>0</g><b>1</b></color>
+    <mesh>
+      <vertices>
+        <vertex><coordinates><x>0</x><y>0</y><z>0</z></coordinates></vertex>
+        <vertex><coordinates><x>1</x><y>0</y><z>0</z></coordinates></vertex>
+        <vertex><coordinates><x>0</x><y>1</y><z>0</z></coordinates></vertex>
+      </vertices>
+      <volume>
+        <triangle><v1>0</v1><v2>2</v2><v3>1</v3></triangle>
+      </volume>
+    </mesh>
+  </object>
+  <object id="2">
+    <mesh><vertices/></mesh>
+  </object>
+</amf>`
+
+func twoVolumesModel() *go3mf.Model {
+	return &go3mf.Model{
+		Units:      go3mf.UnitInch,
+		Extensions: []go3mf.Extension{materials.DefaultExtension},
+		Resources: go3mf.Resources{
+			Assets: []go3mf.Asset{
+				&go3mf.BaseMaterials{ID: 1, Materials: []go3mf.Base{{Name: "Red", Color: color.RGBA{255, 0, 0, 255}}}},
+				&materials.ColorGroup{ID: 2, Colors: []color.RGBA{{0, 255, 0, 255}, {255, 255, 255, 255}, {0, 0, 255, 128}, {0, 0, 255, 255}}},
+			},
+			Objects: []*go3mf.Object{
+				{ID: 3, Name: "first", PID: 1, Mesh: &go3mf.Mesh{
+					Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {0, 1, 0}, {1, 0, 0}}},
+					Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+						{V1: 0, V2: 1, V3: 2, PID: 1},
+						{V1: 0, V2: 2, V3: 1, PID: 2},
+					}},
+				}},
+				{ID: 4, Name: "part", PID: 2, PIndex: 1, Mesh: &go3mf.Mesh{
+					Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {0, 0, 1}}},
+					Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+						{V1: 0, V2: 1, V3: 2, PID: 2, P1: 1, P2: 1, P3: 2},
+					}},
+				}},
+				{ID: 5, Name: "part", Components: &go3mf.Components{Component: []*go3mf.Component{
+					{ObjectID: 3}, {ObjectID: 4},
+				}}},
+				{ID: 6, PID: 2, PIndex: 3, Mesh: &go3mf.Mesh{
+					Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {0, 1, 0}, {1, 0, 0}}},
+					Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+						{V1: 0, V2: 1, V3: 2, PID: 2, P1: 3, P2: 3, P3: 3},
+					}},
+				}},
+			},
+		},
+		Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 5}, {ObjectID: 6}}},
+	}
+}
+
+const constellations = `<amf>
+  <object id="1">
+    <mesh>
+      <vertices>
+        <vertex><coordinates><x>0</x><y>0</y><z>0</z></coordinates></vertex>
+        <vertex><coordinates><x>1</x><y>0</y><z>0</z></coordinates></vertex>
+        <vertex><coordinates><x>0</x><y>1</y><z>0</z></coordinates></vertex>
+      </vertices>
+      <volume><triangle><v1>0</v1><v2>2</v2><v3>1</v3></triangle></volume>
+    </mesh>
+  </object>
+  <object id="2"/>
+  <constellation id="4">
+    <instance objectid="3"><deltax>5</deltax></instance>
+    <instance objectid="2"/>
+  </constellation>
+  <constellation id="3">
+    <instance objectid="1"><deltax>1</deltax><deltay>2</deltay><deltaz>3</deltaz><rz>90</rz></instance>
+    <instance objectid="1"/>
+  </constellation>
+</amf>`
+
+func zipped(t *testing.T, name, content string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	if name != "" {
+		if _, err := w.Create("dir/"); err != nil {
+			t.Fatal(err)
+		}
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(f, content)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestNewDecoder(t *testing.T) {
+	type args struct {
+		r io.Reader
+	}
+	tests := []struct {
+		name string
+		args args
+		want *Decoder
+	}{
+		{"base", args{new(bytes.Buffer)}, &Decoder{r: new(bytes.Buffer)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewDecoder(tt.args.r); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewDecoder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	rotated := go3mf.Matrix{0, 1, 0, 0, -1, 0, 0, 0, 0, 0, 1, 0, 1, 2, 3, 1}
+	constellationsModel := &go3mf.Model{
+		Resources: go3mf.Resources{
+			Objects: []*go3mf.Object{
+				{ID: 1, Mesh: &go3mf.Mesh{
+					Vertices:  go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {0, 1, 0}, {1, 0, 0}}},
+					Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{{V1: 0, V2: 1, V3: 2}}},
+				}},
+				{ID: 2, Components: &go3mf.Components{Component: []*go3mf.Component{
+					{ObjectID: 1, Transform: rotated}, {ObjectID: 1},
+				}}},
+				{ID: 3, Components: &go3mf.Components{Component: []*go3mf.Component{
+					{ObjectID: 2, Transform: go3mf.Identity().Translate(5, 0, 0)},
+				}}},
+			},
+		},
+		Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 3}}},
+	}
+	tests := []struct {
+		name    string
+		b       []byte
+		want    *go3mf.Model
+		wantErr bool
+	}{
+		{"empty", nil, nil, true},
+		{"invalidXML", []byte("<amf>"), nil, true},
+		{"invalidUnit", []byte(`<amf unit="parsec"/>`), nil, true},
+		{"outOfBounds", []byte(`<amf><object id="1"><mesh><volume><triangle><v1>0</v1><v2>1</v2><v3>2</v3></triangle></volume></mesh></object></amf>`), nil, true},
+		{"missingReference", []byte(`<amf><constellation id="1"><instance objectid="2"/></constellation></amf>`), nil, true},
+		{"recursive", []byte(`<amf><constellation id="1"><instance objectid="2"/></constellation><constellation id="2"><instance objectid="1"/></constellation></amf>`), nil, true},
+		{"emptyZip", zipped(t, "", ""), nil, true},
+		{"invalidZip", []byte("PK\x03\x04"), nil, true},
+		{"xml", []byte(twoVolumes), twoVolumesModel(), false},
+		{"zip", zipped(t, "model.amf", twoVolumes), twoVolumesModel(), false},
+		{"constellations", []byte(constellations), constellationsModel, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := new(go3mf.Model)
+			err := NewDecoder(bytes.NewReader(tt.b)).Decode(got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Decoder.Decode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			// Remove rounding errors from the rotations.
+			for _, o := range got.Resources.Objects {
+				if o.Components != nil {
+					for _, c := range o.Components.Component {
+						for i, v := range c.Transform {
+							c.Transform[i] = float32(math.Round(float64(v)*1e6) / 1e6)
+						}
+					}
+				}
+			}
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Errorf("Decoder.Decode() = %v", diff)
+			}
+		})
+	}
+}
+
+func TestDecoder_DecodeContext_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := NewDecoder(bytes.NewBufferString(twoVolumes)).DecodeContext(ctx, new(go3mf.Model))
+	if err != context.Canceled {
+		t.Errorf("Decoder.DecodeContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func Test_instance_transform(t *testing.T) {
+	tests := []struct {
+		name string
+		i    instance
+		want go3mf.Point3D
+	}{
+		{"identity", instance{}, go3mf.Point3D{1, 2, 3}},
+		{"translate", instance{DeltaX: 1, DeltaY: 1, DeltaZ: 1}, go3mf.Point3D{2, 3, 4}},
+		{"rx", instance{RX: 90}, go3mf.Point3D{1, -3, 2}},
+		{"ry", instance{RY: 90}, go3mf.Point3D{3, 2, -1}},
+		{"rz", instance{RZ: 90}, go3mf.Point3D{-2, 1, 3}},
+		{"xyz", instance{RX: 90, RY: 90, DeltaZ: 1}, go3mf.Point3D{2, -3, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.i.transform().Mul3D(go3mf.Point3D{1, 2, 3})
+			for i := range got {
+				if math.Abs(float64(got[i]-tt.want[i])) > 1e-5 {
+					t.Errorf("instance.transform() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func Test_format_Sniff(t *testing.T) {
+	var zipAMF bytes.Buffer
+	w := zip.NewWriter(&zipAMF)
+	w.Create("model.AMF")
+	w.Close()
+	tests := []struct {
+		name string
+		head []byte
+		want bool
+	}{
+		{"empty", nil, false},
+		{"xml", []byte(twoVolumes), true},
+		{"noHeader", []byte("<amf unit=\"inch\">"), true},
+		{"otherXML", []byte("<?xml version=\"1.0\"?><model>"), false},
+		{"zip", zipAMF.Bytes(), true},
+		{"otherZip", zipped(t, "3D/3dmodel.model", "")[:60], false},
+		{"shortZip", zipMagic, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (format{}).Sniff(tt.head); got != tt.want {
+				t.Errorf("format.Sniff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,866 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package gltf
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/importer"
+	"github.com/hpinc/go3mf/materials"
+)
+
+var (
+	errUnresolvedURI = errors.New("gltf: cannot resolve external URI")
+	errOutOfBounds   = errors.New("gltf: index out of bounds")
+	errAccessor      = errors.New("gltf: invalid accessor")
+)
+
+func init() {
+	importer.Register("gltf", format{})
+}
+
+// format implements the importer.Importer interface.
+// External URIs cannot be resolved when decoding through the importer package.
+type format struct{}
+
+// Sniff reports whether head is a GLB file or a JSON object,
+// glTF being the only registered JSON format.
+func (format) Sniff(head []byte) bool {
+	if len(head) >= 4 && binary.LittleEndian.Uint32(head) == glbMagic {
+		return true
+	}
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return len(head) > 0 && head[0] == '{'
+}
+
+func (format) DecodeContext(ctx context.Context, r io.Reader, m *go3mf.Model) error {
+	return NewDecoder(r).DecodeContext(ctx, m)
+}
+
+// Decoder can decode glTF 2.0 files, both the JSON and the binary (GLB) flavors.
+//
+// Every glTF mesh is decoded as a mesh object and every node with
+// children as a components object, the root nodes of the scene
+// being the build items. The root transforms convert from meters and
+// the Y up axis used by glTF to the model units and the Z up axis used by 3MF.
+//
+// Base color textures are decoded as materials.Texture2D with a
+// materials.Texture2DGroup holding the texture coordinates, vertex colors
+// as a materials.ColorGroup and the base color of the rest of the
+// materials as go3mf.BaseMaterials.
+type Decoder struct {
+	// ReadURI opens the external buffers and images referenced by uri,
+	// as written in the glTF file.
+	// If nil, only resources embedded in the GLB or as data URIs can be decoded.
+	ReadURI func(uri string) (io.ReadCloser, error)
+	// Limits bound the bytes read from the glTF file and from the external URIs,
+	// so files from untrusted sources can be decoded safely.
+	// MaxPartSize applies to each file and MaxTotalSize to all of them,
+	// the rest of the fields are not used.
+	Limits go3mf.Limits
+	r      io.Reader
+}
+
+// NewDecoder creates a new decoder.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: r,
+	}
+}
+
+// Decode adds the content of the read stream to m.
+func (d *Decoder) Decode(m *go3mf.Model) error {
+	return d.DecodeContext(context.Background(), m)
+}
+
+// DecodeContext adds the content of the read stream to m.
+func (d *Decoder) DecodeContext(ctx context.Context, m *go3mf.Model) error {
+	var total int64
+	b, err := readAll(d.r, "", &d.Limits, &total)
+	if err != nil {
+		return err
+	}
+	js, bin := b, []byte(nil)
+	if len(b) >= 4 && binary.LittleEndian.Uint32(b) == glbMagic {
+		if js, bin, err = readGLB(b); err != nil {
+			return err
+		}
+	}
+	dec := docDecoder{
+		ctx:       ctx,
+		m:         m,
+		rs:        go3mf.NewResourceIndex(&m.Resources),
+		bin:       bin,
+		readURI:   d.ReadURI,
+		limits:    &d.Limits,
+		read:      total,
+		nodes:     make(map[int]uint32),
+		meshes:    make(map[int]uint32),
+		baseIndex: make(map[int]uint32),
+		textures:  make(map[int]*materials.Texture2DGroup),
+		images:    make(map[int]string),
+	}
+	if err = json.Unmarshal(js, &dec.doc); err != nil {
+		return err
+	}
+	return dec.decode()
+}
+
+// docDecoder adds the content of a glTF document to a model.
+type docDecoder struct {
+	ctx       context.Context
+	m         *go3mf.Model
+	rs        *go3mf.ResourceIndex
+	doc       document
+	bin       []byte
+	readURI   func(string) (io.ReadCloser, error)
+	limits    *go3mf.Limits
+	read      int64 // bytes read from the files
+	buffers   [][]byte
+	nodes     map[int]uint32 // node index -> object ID
+	meshes    map[int]uint32 // mesh index -> object ID
+	base      *go3mf.BaseMaterials
+	baseIndex map[int]uint32 // material index -> base index
+	colors    *materials.ColorGroup
+	colorIdx  map[color.RGBA]uint32
+	textures  map[int]*materials.Texture2DGroup // texture index -> group
+	images    map[int]string                    // image index -> attachment path
+}
+
+func (d *docDecoder) decode() error {
+	var roots []int
+	if len(d.doc.Scenes) > 0 {
+		s := 0
+		if d.doc.Scene != nil {
+			s = *d.doc.Scene
+		}
+		if s < 0 || s >= len(d.doc.Scenes) {
+			return errOutOfBounds
+		}
+		roots = d.doc.Scenes[s].Nodes
+	} else {
+		roots = d.rootNodes()
+	}
+	k := float32(1 / unitScale(d.m.Units))
+	zUp := go3mf.Matrix{k, 0, 0, 0, 0, 0, k, 0, 0, -k, 0, 0, 0, 0, 0, 1}
+	for _, i := range roots {
+		id, ok, err := d.addNode(i, nil)
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.m.Build.Items = append(d.m.Build.Items, &go3mf.Item{
+				ObjectID:  id,
+				Transform: zUp.Mul(d.doc.Nodes[i].transform()),
+			})
+		}
+	}
+	return nil
+}
+
+// rootNodes returns the nodes that are not children of other nodes.
+func (d *docDecoder) rootNodes() []int {
+	isChild := make([]bool, len(d.doc.Nodes))
+	for _, n := range d.doc.Nodes {
+		for _, c := range n.Children {
+			if c >= 0 && c < len(isChild) {
+				isChild[c] = true
+			}
+		}
+	}
+	var roots []int
+	for i, child := range isChild {
+		if !child {
+			roots = append(roots, i)
+		}
+	}
+	return roots
+}
+
+func (d *docDecoder) addNode(i int, visiting []int) (uint32, bool, error) {
+	if i < 0 || i >= len(d.doc.Nodes) {
+		return 0, false, errOutOfBounds
+	}
+	if id, ok := d.nodes[i]; ok {
+		return id, id != 0, nil
+	}
+	for _, v := range visiting {
+		if v == i {
+			return 0, false, nil // recursive reference
+		}
+	}
+	n := &d.doc.Nodes[i]
+	var components []*go3mf.Component
+	if n.Mesh != nil {
+		id, ok, err := d.addMesh(*n.Mesh, n.Name)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			components = append(components, &go3mf.Component{ObjectID: id})
+		}
+	}
+	visiting = append(visiting, i)
+	for _, child := range n.Children {
+		id, ok, err := d.addNode(child, visiting)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			c := &go3mf.Component{ObjectID: id}
+			if t := d.doc.Nodes[child].transform(); t != go3mf.Identity() {
+				c.Transform = t
+			}
+			components = append(components, c)
+		}
+	}
+	var id uint32
+	if len(components) == 1 && n.Mesh != nil && len(n.Children) == 0 {
+		id = components[0].ObjectID
+	} else if len(components) > 0 {
+		id = d.rs.NewID()
+		d.rs.AddObject(&go3mf.Object{
+			ID:         id,
+			Name:       n.Name,
+			Components: &go3mf.Components{Component: components},
+		})
+	}
+	d.nodes[i] = id
+	return id, id != 0, nil
+}
+
+func (d *docDecoder) addMesh(i int, name string) (uint32, bool, error) {
+	if i < 0 || i >= len(d.doc.Meshes) {
+		return 0, false, errOutOfBounds
+	}
+	if id, ok := d.meshes[i]; ok {
+		return id, id != 0, nil
+	}
+	select {
+	case <-d.ctx.Done():
+		return 0, false, d.ctx.Err()
+	default: // Default is must to avoid blocking
+	}
+	mb := meshBuilder{mesh: new(go3mf.Mesh), vertices: make(map[go3mf.Point3D]uint32)}
+	for _, p := range d.doc.Meshes[i].Primitives {
+		if err := d.addPrimitive(&mb, p); err != nil {
+			return 0, false, err
+		}
+	}
+	if len(mb.mesh.Triangles.Triangle) == 0 {
+		d.meshes[i] = 0
+		return 0, false, nil
+	}
+	o := &go3mf.Object{
+		ID:   d.rs.NewID(),
+		Name: d.doc.Meshes[i].Name,
+		Mesh: mb.mesh,
+	}
+	if o.Name == "" {
+		o.Name = name
+	}
+	for _, t := range mb.mesh.Triangles.Triangle {
+		if t.PID != 0 {
+			o.PID, o.PIndex = t.PID, t.P1
+			break
+		}
+	}
+	d.rs.AddObject(o)
+	d.meshes[i] = o.ID
+	return o.ID, true, nil
+}
+
+// meshBuilder merges the primitives of a glTF mesh into a single mesh,
+// sharing the vertices with the same position.
+type meshBuilder struct {
+	mesh     *go3mf.Mesh
+	vertices map[go3mf.Point3D]uint32
+}
+
+func (mb *meshBuilder) addVertex(v go3mf.Point3D) uint32 {
+	if i, ok := mb.vertices[v]; ok {
+		return i
+	}
+	i := uint32(len(mb.mesh.Vertices.Vertex))
+	mb.mesh.Vertices.Vertex = append(mb.mesh.Vertices.Vertex, v)
+	mb.vertices[v] = i
+	return i
+}
+
+func (d *docDecoder) addPrimitive(mb *meshBuilder, p primitive) error {
+	mode := modeTriangles
+	if p.Mode != nil {
+		mode = *p.Mode
+	}
+	if mode != modeTriangles && mode != modeTriangleStrip && mode != modeTriangleFan {
+		return nil // points and lines are not supported
+	}
+	pos, ok := p.Attributes[attrPosition]
+	if !ok {
+		return nil
+	}
+	positions, count, err := d.readFloats(pos, 3)
+	if err != nil {
+		return err
+	}
+	var indices []uint32
+	if p.Indices != nil {
+		if indices, err = d.readIndices(*p.Indices, count); err != nil {
+			return err
+		}
+	} else {
+		indices = make([]uint32, count)
+		for i := range indices {
+			indices[i] = uint32(i)
+		}
+	}
+	pid, props, err := d.primitiveProperties(p, count)
+	if err != nil {
+		return err
+	}
+	vertices := make([]uint32, count)
+	for i := range vertices {
+		vertices[i] = mb.addVertex(go3mf.Point3D{positions[3*i], positions[3*i+1], positions[3*i+2]})
+	}
+	for _, t := range triangulate(indices, mode) {
+		tr := go3mf.Triangle{V1: vertices[t[0]], V2: vertices[t[1]], V3: vertices[t[2]]}
+		if tr.V1 == tr.V2 || tr.V1 == tr.V3 || tr.V2 == tr.V3 {
+			continue
+		}
+		if pid != 0 {
+			tr.PID, tr.P1, tr.P2, tr.P3 = pid, props[t[0]], props[t[1]], props[t[2]]
+		}
+		mb.mesh.Triangles.Triangle = append(mb.mesh.Triangles.Triangle, tr)
+	}
+	return nil
+}
+
+// triangulate returns the vertex indices of the triangles defined by indices.
+func triangulate(indices []uint32, mode int) [][3]uint32 {
+	var ts [][3]uint32
+	switch mode {
+	case modeTriangles:
+		for i := 2; i < len(indices); i += 3 {
+			ts = append(ts, [3]uint32{indices[i-2], indices[i-1], indices[i]})
+		}
+	case modeTriangleStrip:
+		for i := 2; i < len(indices); i++ {
+			if i%2 == 0 {
+				ts = append(ts, [3]uint32{indices[i-2], indices[i-1], indices[i]})
+			} else {
+				ts = append(ts, [3]uint32{indices[i-1], indices[i-2], indices[i]})
+			}
+		}
+	case modeTriangleFan:
+		for i := 2; i < len(indices); i++ {
+			ts = append(ts, [3]uint32{indices[i-1], indices[i], indices[0]})
+		}
+	}
+	return ts
+}
+
+// primitiveProperties returns the property group and the per vertex property indices
+// of a primitive. The returned pid is zero if the primitive has no properties.
+func (d *docDecoder) primitiveProperties(p primitive, count int) (uint32, []uint32, error) {
+	var mat *material
+	factor := [4]float32{1, 1, 1, 1}
+	if p.Material != nil {
+		if *p.Material < 0 || *p.Material >= len(d.doc.Materials) {
+			return 0, nil, errOutOfBounds
+		}
+		mat = &d.doc.Materials[*p.Material]
+		if pbr := mat.PBRMetallicRoughness; pbr != nil && pbr.BaseColorFactor != nil {
+			factor = *pbr.BaseColorFactor
+		}
+	}
+	props := make([]uint32, count)
+	if mat != nil && mat.PBRMetallicRoughness != nil && mat.PBRMetallicRoughness.BaseColorTexture != nil {
+		info := mat.PBRMetallicRoughness.BaseColorTexture
+		if a, ok := p.Attributes[fmt.Sprintf("TEXCOORD_%d", info.TexCoord)]; ok {
+			group, ok, err := d.addTexture(info.Index)
+			if err != nil {
+				return 0, nil, err
+			}
+			if ok {
+				uvs, n, err := d.readFloats(a, 2)
+				if err != nil {
+					return 0, nil, err
+				}
+				if n < count {
+					return 0, nil, errAccessor
+				}
+				for i := range props {
+					props[i] = uint32(len(group.Coords))
+					// glTF places the origin of the texture space at the top left corner.
+					group.Coords = append(group.Coords, materials.TextureCoord{uvs[2*i], 1 - uvs[2*i+1]})
+				}
+				return group.ID, props, nil
+			}
+		}
+	}
+	if a, ok := p.Attributes[attrColor]; ok {
+		size := 4
+		if a >= 0 && a < len(d.doc.Accessors) && d.doc.Accessors[a].Type == accessorVec3 {
+			size = 3
+		}
+		colors, n, err := d.readFloats(a, size)
+		if err != nil {
+			return 0, nil, err
+		}
+		if n < count {
+			return 0, nil, errAccessor
+		}
+		for i := range props {
+			c := [4]float32{colors[size*i], colors[size*i+1], colors[size*i+2], 1}
+			if size == 4 {
+				c[3] = colors[size*i+3]
+			}
+			props[i] = d.addColor(toRGBA(c, factor))
+		}
+		return d.colors.ID, props, nil
+	}
+	if mat == nil {
+		return 0, nil, nil
+	}
+	index := d.addBaseMaterial(*p.Material, mat.Name, toRGBA([4]float32{1, 1, 1, 1}, factor))
+	for i := range props {
+		props[i] = index
+	}
+	return d.base.ID, props, nil
+}
+
+// toRGBA converts the product of the linear colors c and factor to sRGB.
+func toRGBA(c, factor [4]float32) color.RGBA {
+	alpha := c[3] * factor[3]
+	if alpha < 0 {
+		alpha = 0
+	} else if alpha > 1 {
+		alpha = 1
+	}
+	return color.RGBA{
+		R: linearToSRGB(c[0] * factor[0]),
+		G: linearToSRGB(c[1] * factor[1]),
+		B: linearToSRGB(c[2] * factor[2]),
+		A: uint8(alpha*255 + 0.5),
+	}
+}
+
+func (d *docDecoder) addColor(c color.RGBA) uint32 {
+	if d.colors == nil {
+		d.colors = &materials.ColorGroup{ID: d.rs.NewID()}
+		d.colorIdx = make(map[color.RGBA]uint32)
+		d.addAsset(d.colors)
+	}
+	if i, ok := d.colorIdx[c]; ok {
+		return i
+	}
+	i := uint32(len(d.colors.Colors))
+	d.colors.Colors = append(d.colors.Colors, c)
+	d.colorIdx[c] = i
+	return i
+}
+
+func (d *docDecoder) addBaseMaterial(i int, name string, c color.RGBA) uint32 {
+	if d.base == nil {
+		d.base = &go3mf.BaseMaterials{ID: d.rs.NewID()}
+		d.rs.AddAsset(d.base)
+	}
+	if index, ok := d.baseIndex[i]; ok {
+		return index
+	}
+	if name == "" {
+		name = fmt.Sprintf("material%d", i)
+	}
+	index := uint32(len(d.base.Materials))
+	d.base.Materials = append(d.base.Materials, go3mf.Base{Name: name, Color: c})
+	d.baseIndex[i] = index
+	return index
+}
+
+func (d *docDecoder) addAsset(a go3mf.Asset) {
+	d.rs.AddAsset(a)
+	importer.AddExtension(d.m, materials.DefaultExtension)
+}
+
+// addTexture adds the texture referenced by i and returns its texture group.
+// It returns false if the texture image is not a PNG nor a JPEG.
+func (d *docDecoder) addTexture(i int) (*materials.Texture2DGroup, bool, error) {
+	if i < 0 || i >= len(d.doc.Textures) {
+		return nil, false, errOutOfBounds
+	}
+	if group, ok := d.textures[i]; ok {
+		return group, group != nil, nil
+	}
+	d.textures[i] = nil
+	t := d.doc.Textures[i]
+	if t.Source == nil {
+		return nil, false, nil
+	}
+	attPath, contentType, ok, err := d.addImage(*t.Source)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	tex := &materials.Texture2D{Path: attPath, ContentType: contentType}
+	if t.Sampler != nil {
+		if *t.Sampler < 0 || *t.Sampler >= len(d.doc.Samplers) {
+			return nil, false, errOutOfBounds
+		}
+		s := d.doc.Samplers[*t.Sampler]
+		tex.TileStyleU, tex.TileStyleV = newTileStyle(s.WrapS), newTileStyle(s.WrapT)
+		switch s.MagFilter {
+		case filterNearest:
+			tex.Filter = materials.TextureFilterNearest
+		case filterLinear:
+			tex.Filter = materials.TextureFilterLinear
+		}
+	}
+	tex.ID = d.rs.NewID()
+	d.addAsset(tex)
+	group := &materials.Texture2DGroup{ID: d.rs.NewID(), TextureID: tex.ID}
+	d.addAsset(group)
+	d.textures[i] = group
+	return group, true, nil
+}
+
+func newTileStyle(wrap int) materials.TileStyle {
+	switch wrap {
+	case wrapMirroredRepeat:
+		return materials.TileMirror
+	case wrapClampToEdge:
+		return materials.TileClamp
+	}
+	return materials.TileWrap
+}
+
+// addImage adds the image referenced by i as an attachment.
+func (d *docDecoder) addImage(i int) (string, materials.Texture2DType, bool, error) {
+	if i < 0 || i >= len(d.doc.Images) {
+		return "", 0, false, errOutOfBounds
+	}
+	img := d.doc.Images[i]
+	var (
+		data     []byte
+		mimeType = img.MimeType
+		err      error
+	)
+	if img.BufferView != nil {
+		data, err = d.bufferView(*img.BufferView)
+	} else {
+		var uriType string
+		data, uriType, err = d.readURIData(img.URI)
+		if mimeType == "" {
+			mimeType = uriType
+		}
+		if mimeType == "" {
+			switch strings.ToLower(path.Ext(img.URI)) {
+			case ".png":
+				mimeType = "image/png"
+			case ".jpg", ".jpeg":
+				mimeType = "image/jpeg"
+			}
+		}
+	}
+	if err != nil {
+		return "", 0, false, err
+	}
+	var (
+		contentType materials.Texture2DType
+		ext         string
+	)
+	switch mimeType {
+	case "image/png":
+		contentType, ext = materials.TextureTypePNG, ".png"
+	case "image/jpeg":
+		contentType, ext = materials.TextureTypeJPEG, ".jpeg"
+	default:
+		return "", 0, false, nil
+	}
+	if p, ok := d.images[i]; ok {
+		return p, contentType, true, nil
+	}
+	p := d.attachmentPath(fmt.Sprintf("/3D/Texture/image%d%s", i, ext))
+	d.m.Attachments = append(d.m.Attachments, go3mf.Attachment{
+		Path:        p,
+		ContentType: mimeType,
+		Data:        data,
+	})
+	d.images[i] = p
+	return p, contentType, true, nil
+}
+
+// attachmentPath returns p, or a variant of it, so it does not collide
+// with existing attachments.
+func (d *docDecoder) attachmentPath(p string) string {
+	ext := path.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	candidate := p
+	for n := 1; ; n++ {
+		var found bool
+		for _, a := range d.m.Attachments {
+			if strings.EqualFold(a.Path, candidate) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d%s", base, n, ext)
+	}
+}
+
+// readURIData returns the content of a data URI or of an external resource.
+func (d *docDecoder) readURIData(uri string) ([]byte, string, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.IndexByte(uri, ',')
+		if comma < 0 {
+			return nil, "", errUnresolvedURI
+		}
+		meta, payload := uri[len("data:"):comma], uri[comma+1:]
+		mimeType := meta
+		if i := strings.IndexByte(meta, ';'); i >= 0 {
+			mimeType = meta[:i]
+		}
+		if strings.HasSuffix(meta, ";base64") {
+			b, err := base64.StdEncoding.DecodeString(payload)
+			return b, mimeType, err
+		}
+		s, err := url.PathUnescape(payload)
+		return []byte(s), mimeType, err
+	}
+	if d.readURI == nil || uri == "" {
+		return nil, "", errUnresolvedURI
+	}
+	r, err := d.readURI(uri)
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Close()
+	b, err := readAll(r, uri, d.limits, &d.read)
+	return b, "", err
+}
+
+// readAll reads r, named name, within the limits of l
+// and adds the bytes read to total.
+func readAll(r io.Reader, name string, l *go3mf.Limits, total *int64) ([]byte, error) {
+	if l.MaxPartSize <= 0 && l.MaxTotalSize <= 0 {
+		b, err := ioutil.ReadAll(r)
+		*total += int64(len(b))
+		return b, err
+	}
+	max := l.MaxPartSize
+	if l.MaxTotalSize > 0 && (max <= 0 || l.MaxTotalSize-*total < max) {
+		max = l.MaxTotalSize - *total
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	*total += int64(len(b))
+	if l.MaxPartSize > 0 && int64(len(b)) > l.MaxPartSize {
+		return nil, &go3mf.LimitError{Path: name, Limit: "MaxPartSize", Max: l.MaxPartSize}
+	}
+	if l.MaxTotalSize > 0 && *total > l.MaxTotalSize {
+		return nil, &go3mf.LimitError{Limit: "MaxTotalSize", Max: l.MaxTotalSize}
+	}
+	return b, nil
+}
+
+func (d *docDecoder) buffer(i int) ([]byte, error) {
+	if i < 0 || i >= len(d.doc.Buffers) {
+		return nil, errOutOfBounds
+	}
+	if d.buffers == nil {
+		d.buffers = make([][]byte, len(d.doc.Buffers))
+	}
+	if d.buffers[i] != nil {
+		return d.buffers[i], nil
+	}
+	b := d.doc.Buffers[i]
+	var (
+		data []byte
+		err  error
+	)
+	if b.URI == "" && i == 0 && d.bin != nil {
+		data = d.bin
+	} else if data, _, err = d.readURIData(b.URI); err != nil {
+		return nil, err
+	}
+	if b.ByteLength < 0 || len(data) < b.ByteLength {
+		return nil, errOutOfBounds
+	}
+	d.buffers[i] = data[:b.ByteLength]
+	return d.buffers[i], nil
+}
+
+func (d *docDecoder) bufferView(i int) ([]byte, error) {
+	if i < 0 || i >= len(d.doc.BufferViews) {
+		return nil, errOutOfBounds
+	}
+	v := d.doc.BufferViews[i]
+	b, err := d.buffer(v.Buffer)
+	if err != nil {
+		return nil, err
+	}
+	if v.ByteOffset < 0 || v.ByteLength < 0 || v.ByteOffset > len(b) || v.ByteLength > len(b)-v.ByteOffset {
+		return nil, errOutOfBounds
+	}
+	return b[v.ByteOffset : v.ByteOffset+v.ByteLength], nil
+}
+
+func componentSize(componentType int) int {
+	switch componentType {
+	case componentByte, componentUnsignedByte:
+		return 1
+	case componentShort, componentUnsignedShort:
+		return 2
+	case componentUnsignedInt, componentFloat:
+		return 4
+	}
+	return 0
+}
+
+func typeSize(typ string) int {
+	switch typ {
+	case accessorScalar:
+		return 1
+	case accessorVec2:
+		return 2
+	case accessorVec3:
+		return 3
+	case accessorVec4:
+		return 4
+	}
+	return 0
+}
+
+// accessorElements returns the raw content of every element of an accessor.
+func (d *docDecoder) accessorElements(i, size int) (*accessor, [][]byte, error) {
+	if i < 0 || i >= len(d.doc.Accessors) {
+		return nil, nil, errOutOfBounds
+	}
+	a := &d.doc.Accessors[i]
+	csize := componentSize(a.ComponentType)
+	if csize == 0 || typeSize(a.Type) != size || a.Count < 0 {
+		return nil, nil, errAccessor
+	}
+	elemSize := csize * size
+	if a.BufferView == nil {
+		// Accessors without buffer view are initialized with zeros.
+		if a.Count > 1<<24 {
+			return nil, nil, errAccessor
+		}
+		zero := make([]byte, elemSize)
+		elems := make([][]byte, a.Count)
+		for j := range elems {
+			elems[j] = zero
+		}
+		return a, elems, nil
+	}
+	data, err := d.bufferView(*a.BufferView)
+	if err != nil {
+		return nil, nil, err
+	}
+	stride := elemSize
+	if s := d.doc.BufferViews[*a.BufferView].ByteStride; s > 0 {
+		stride = s
+	}
+	if a.ByteOffset < 0 || a.ByteOffset > len(data) {
+		return nil, nil, errAccessor
+	}
+	data = data[a.ByteOffset:]
+	if a.Count > 0 && (a.Count-1 > len(data)/stride || (a.Count-1)*stride+elemSize > len(data)) {
+		return nil, nil, errAccessor
+	}
+	elems := make([][]byte, a.Count)
+	for j := range elems {
+		elems[j] = data[j*stride : j*stride+elemSize]
+	}
+	return a, elems, nil
+}
+
+// readFloats returns the values of an accessor with size components per element
+// and the number of elements.
+func (d *docDecoder) readFloats(i, size int) ([]float32, int, error) {
+	a, elems, err := d.accessorElements(i, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	csize := componentSize(a.ComponentType)
+	values := make([]float32, 0, len(elems)*size)
+	for _, e := range elems {
+		for j := 0; j < size; j++ {
+			values = append(values, component(e[j*csize:], a.ComponentType, a.Normalized))
+		}
+	}
+	return values, len(elems), nil
+}
+
+func component(b []byte, componentType int, normalized bool) float32 {
+	var v, max float32
+	switch componentType {
+	case componentFloat:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	case componentByte:
+		v, max = float32(int8(b[0])), 127
+	case componentUnsignedByte:
+		v, max = float32(b[0]), 255
+	case componentShort:
+		v, max = float32(int16(binary.LittleEndian.Uint16(b))), 32767
+	case componentUnsignedShort:
+		v, max = float32(binary.LittleEndian.Uint16(b)), 65535
+	case componentUnsignedInt:
+		v, max = float32(binary.LittleEndian.Uint32(b)), math.MaxUint32
+	}
+	if normalized {
+		v /= max
+		if v < -1 {
+			v = -1
+		}
+	}
+	return v
+}
+
+// readIndices returns the values of an indices accessor,
+// which must be lower than count.
+func (d *docDecoder) readIndices(i, count int) ([]uint32, error) {
+	a, elems, err := d.accessorElements(i, 1)
+	if err != nil {
+		return nil, err
+	}
+	indices := make([]uint32, len(elems))
+	for j, e := range elems {
+		var v uint32
+		switch a.ComponentType {
+		case componentUnsignedByte:
+			v = uint32(e[0])
+		case componentUnsignedShort:
+			v = uint32(binary.LittleEndian.Uint16(e))
+		case componentUnsignedInt:
+			v = binary.LittleEndian.Uint32(e)
+		default:
+			return nil, errAccessor
+		}
+		if int64(v) >= int64(count) {
+			return nil, errOutOfBounds
+		}
+		indices[j] = v
+	}
+	return indices, nil
+}
@@ -0,0 +1,441 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package gltf
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/materials"
+)
+
+func float32Bytes(v ...float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+// quadDocument returns a document with a quad defined with a triangle fan
+// and a triangle strip sharing the same buffer.
+func quadDocument() *document {
+	positions := float32Bytes(0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0)
+	indices := []byte{0, 1, 2, 3, 1, 0, 2, 3}
+	colors := []byte{255, 0, 0, 255, 0, 255, 0, 255, 0, 0, 255, 255, 255, 0, 0, 255}
+	data := append(append(positions, indices...), colors...)
+	uri := "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(data)
+	return &document{
+		Asset:  asset{Version: "2.0"},
+		Scene:  intPtr(0),
+		Scenes: []scene{{Nodes: []int{0}}},
+		Nodes: []node{
+			{Name: "group", Children: []int{1, 2}, Translation: &[3]float32{0, 2, 0}},
+			{Name: "fan", Mesh: intPtr(0), Scale: &[3]float32{2, 2, 2}},
+			{Name: "strip", Mesh: intPtr(1)},
+		},
+		Meshes: []mesh{
+			{Primitives: []primitive{{
+				Attributes: map[string]int{attrPosition: 0},
+				Indices:    intPtr(1),
+				Material:   intPtr(0),
+				Mode:       intPtr(modeTriangleFan),
+			}}},
+			{Name: "colored", Primitives: []primitive{{
+				Attributes: map[string]int{attrPosition: 0, attrColor: 3},
+				Indices:    intPtr(2),
+				Mode:       intPtr(modeTriangleStrip),
+			}, {
+				Attributes: map[string]int{attrPosition: 0},
+				Mode:       intPtr(1), // lines
+			}}},
+		},
+		Accessors: []accessor{
+			{BufferView: intPtr(0), ComponentType: componentFloat, Count: 4, Type: accessorVec3},
+			{BufferView: intPtr(1), ComponentType: componentUnsignedByte, Count: 4, Type: accessorScalar},
+			{BufferView: intPtr(1), ByteOffset: 4, ComponentType: componentUnsignedByte, Count: 4, Type: accessorScalar},
+			{BufferView: intPtr(2), ComponentType: componentUnsignedByte, Normalized: true, Count: 4, Type: accessorVec4},
+		},
+		BufferViews: []bufferView{
+			{Buffer: 0, ByteLength: len(positions)},
+			{Buffer: 0, ByteOffset: len(positions), ByteLength: len(indices)},
+			{Buffer: 0, ByteOffset: len(positions) + len(indices), ByteLength: len(colors)},
+		},
+		Buffers:   []buffer{{URI: uri, ByteLength: len(data)}},
+		Materials: []material{{Name: "red", PBRMetallicRoughness: &pbrMetallicRoughness{BaseColorFactor: &[4]float32{1, 0, 0, 1}}}},
+	}
+}
+
+func encodeDocument(t *testing.T, doc *document) []byte {
+	t.Helper()
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNewDecoder(t *testing.T) {
+	type args struct {
+		r io.Reader
+	}
+	tests := []struct {
+		name string
+		args args
+		want *Decoder
+	}{
+		{"base", args{new(bytes.Buffer)}, &Decoder{r: new(bytes.Buffer)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewDecoder(tt.args.r); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewDecoder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	quad := []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}
+	want := &go3mf.Model{
+		Extensions: []go3mf.Extension{materials.DefaultExtension},
+		Resources: go3mf.Resources{
+			Assets: []go3mf.Asset{
+				&go3mf.BaseMaterials{ID: 1, Materials: []go3mf.Base{{Name: "red", Color: color.RGBA{255, 0, 0, 255}}}},
+				&materials.ColorGroup{ID: 3, Colors: []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}},
+			},
+			Objects: []*go3mf.Object{
+				{ID: 2, Name: "fan", PID: 1, Mesh: &go3mf.Mesh{
+					Vertices: go3mf.Vertices{Vertex: quad},
+					Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+						{V1: 1, V2: 2, V3: 0, PID: 1},
+						{V1: 2, V2: 3, V3: 0, PID: 1},
+					}},
+				}},
+				{ID: 4, Name: "colored", PID: 3, PIndex: 1, Mesh: &go3mf.Mesh{
+					Vertices: go3mf.Vertices{Vertex: quad},
+					Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+						{V1: 1, V2: 0, V3: 2, PID: 3, P1: 1, P2: 0, P3: 2},
+						{V1: 2, V2: 0, V3: 3, PID: 3, P1: 2, P2: 0, P3: 0},
+					}},
+				}},
+				{ID: 5, Name: "group", Components: &go3mf.Components{Component: []*go3mf.Component{
+					{ObjectID: 2, Transform: go3mf.Matrix{2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 1}},
+					{ObjectID: 4},
+				}}},
+			},
+		},
+		Build: go3mf.Build{Items: []*go3mf.Item{
+			{ObjectID: 5, Transform: go3mf.Matrix{1000, 0, 0, 0, 0, 0, 1000, 0, 0, -1000, 0, 0, 0, 0, 2000, 1}},
+		}},
+	}
+	got := new(go3mf.Model)
+	if err := NewDecoder(bytes.NewReader(encodeDocument(t, quadDocument()))).Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Decoder.Decode() = %v", diff)
+	}
+}
+
+func TestDecoder_Decode_ReadURI(t *testing.T) {
+	doc := quadDocument()
+	data, _, _ := new(docDecoder).readURIData(doc.Buffers[0].URI)
+	doc.Buffers[0].URI = "quad.bin"
+	doc.Scenes = nil
+	doc.Scene = nil
+	var requested []string
+	d := NewDecoder(bytes.NewReader(encodeDocument(t, doc)))
+	d.ReadURI = func(uri string) (io.ReadCloser, error) {
+		requested = append(requested, uri)
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	got := new(go3mf.Model)
+	if err := d.Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if diff := deep.Equal(requested, []string{"quad.bin"}); diff != nil {
+		t.Errorf("Decoder.ReadURI() = %v", diff)
+	}
+	if len(got.Build.Items) != 1 || len(got.Resources.Objects) != 3 {
+		t.Errorf("Decoder.Decode() = %v", got)
+	}
+}
+
+func TestDecoder_Decode_Roundtrip(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G'}
+	cube := &go3mf.Mesh{
+		Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}, {0, 0, 1}}},
+		Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+			{V1: 0, V2: 2, V3: 1, PID: 1, P1: 0, P2: 0, P3: 0},
+			{V1: 0, V2: 3, V3: 2, PID: 2, P1: 0, P2: 1, P3: 2},
+			{V1: 0, V2: 1, V3: 4, PID: 3, P1: 0, P2: 1, P3: 1},
+			{V1: 1, V2: 2, V3: 4},
+		}},
+	}
+	m := &go3mf.Model{
+		Units: go3mf.UnitMillimeter,
+		Resources: go3mf.Resources{
+			Assets: []go3mf.Asset{
+				&go3mf.BaseMaterials{ID: 1, Materials: []go3mf.Base{{Name: "blue", Color: color.RGBA{0, 0, 200, 255}}}},
+				&materials.ColorGroup{ID: 2, Colors: []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 100}, {10, 20, 30, 255}}},
+				&materials.Texture2DGroup{ID: 3, TextureID: 4, Coords: []materials.TextureCoord{{0.25, 0.5}, {1, 0}}},
+				&materials.Texture2D{ID: 4, Path: "/3D/Texture/a.png", ContentType: materials.TextureTypePNG, TileStyleV: materials.TileMirror},
+			},
+			Objects: []*go3mf.Object{{ID: 5, Name: "cube", Mesh: cube}},
+		},
+		Build:       go3mf.Build{Items: []*go3mf.Item{{ObjectID: 5, Transform: go3mf.Identity().Translate(10, 20, 30)}}},
+		Attachments: []go3mf.Attachment{{Path: "/3D/Texture/a.png", ContentType: "image/png", Stream: bytes.NewBuffer(png)}},
+	}
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	got := new(go3mf.Model)
+	if err := NewDecoder(&b).Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if len(got.Build.Items) != 1 {
+		t.Fatalf("Decoder.Decode() items = %v", got.Build.Items)
+	}
+	item := got.Build.Items[0]
+	obj, ok := got.FindObject("", item.ObjectID)
+	if !ok || obj.Components == nil || len(obj.Components.Component) != 1 {
+		t.Fatalf("Decoder.Decode() root object = %v", obj)
+	}
+	for i, v := range item.Transform {
+		if math.Abs(float64(v-go3mf.Identity()[i])) > 1e-5 {
+			t.Fatalf("Decoder.Decode() root transform = %v", item.Transform)
+		}
+	}
+	c := obj.Components.Component[0]
+	if c.Transform != go3mf.Identity().Translate(10, 20, 30) {
+		t.Errorf("Decoder.Decode() component transform = %v", c.Transform)
+	}
+	part, _ := got.FindObject("", c.ObjectID)
+	if part.Name != "cube" || len(part.Mesh.Vertices.Vertex) != 5 || len(part.Mesh.Triangles.Triangle) != 4 {
+		t.Fatalf("Decoder.Decode() mesh = %v", part.Mesh)
+	}
+	wantColors := map[go3mf.Point3D]color.RGBA{}
+	for i, tr := range cube.Triangles.Triangle {
+		for j, v := range [3]uint32{tr.V1, tr.V2, tr.V3} {
+			if i == 1 {
+				wantColors[cube.Vertices.Vertex[v]] = m.Resources.Assets[1].(*materials.ColorGroup).Colors[[3]uint32{tr.P1, tr.P2, tr.P3}[j]]
+			}
+		}
+	}
+	for _, tr := range part.Mesh.Triangles.Triangle {
+		a, ok := got.FindAsset("", tr.PID)
+		switch a := a.(type) {
+		case *go3mf.BaseMaterials:
+			// Triangles without properties are encoded with a white material.
+			base := a.Materials[tr.P1]
+			isBlue := base.Name == "blue" && base.Color == color.RGBA{0, 0, 200, 255}
+			if !isBlue && base.Color != (color.RGBA{255, 255, 255, 255}) {
+				t.Errorf("Decoder.Decode() base material = %v", a.Materials[tr.P1])
+			}
+		case *materials.ColorGroup:
+			for j, v := range [3]uint32{tr.V1, tr.V2, tr.V3} {
+				if got, want := a.Colors[[3]uint32{tr.P1, tr.P2, tr.P3}[j]], wantColors[part.Mesh.Vertices.Vertex[v]]; got != want {
+					t.Errorf("Decoder.Decode() color = %v, want %v", got, want)
+				}
+			}
+		case *materials.Texture2DGroup:
+			if diff := deep.Equal([]materials.TextureCoord{a.Coords[tr.P1], a.Coords[tr.P2], a.Coords[tr.P3]}, []materials.TextureCoord{{0.25, 0.5}, {1, 0}, {1, 0}}); diff != nil {
+				t.Errorf("Decoder.Decode() texture coords = %v", diff)
+			}
+			tex, _ := got.FindAsset("", a.TextureID)
+			want := &materials.Texture2D{ID: tex.Identify(), Path: "/3D/Texture/image0.png", ContentType: materials.TextureTypePNG, TileStyleV: materials.TileMirror, Filter: materials.TextureFilterLinear}
+			if diff := deep.Equal(tex, want); diff != nil {
+				t.Errorf("Decoder.Decode() texture = %v", diff)
+			}
+		default:
+			t.Errorf("Decoder.Decode() unexpected asset %v, %v", a, ok)
+		}
+	}
+	if len(got.Attachments) != 1 || got.Attachments[0].Path != "/3D/Texture/image0.png" ||
+		!bytes.Equal(got.Attachments[0].Data, png) {
+		t.Errorf("Decoder.Decode() attachments = %v", got.Attachments)
+	}
+}
+
+func TestDecoder_Decode_Error(t *testing.T) {
+	outOfBounds := quadDocument()
+	outOfBounds.Accessors[0].Count = 5
+	badIndex := quadDocument()
+	badIndex.Meshes[0].Primitives[0].Indices = intPtr(3)
+	external := quadDocument()
+	external.Buffers[0].URI = "quad.bin"
+	badNode := quadDocument()
+	badNode.Nodes[0].Children = []int{10}
+	badScene := quadDocument()
+	badScene.Scene = intPtr(2)
+	badMaterial := quadDocument()
+	badMaterial.Meshes[0].Primitives[0].Material = intPtr(2)
+	badView := quadDocument()
+	badView.BufferViews[0].ByteLength = 1000
+	badBuffer := quadDocument()
+	badBuffer.Buffers[0].ByteLength = -1
+	tests := []struct {
+		name string
+		b    []byte
+		want error
+	}{
+		{"empty", nil, nil},
+		{"json", []byte("{"), nil},
+		{"glbMagic", []byte{'g', 'l', 'T', 'F', 1, 0, 0, 0, 12, 0, 0, 0}, errGLBVersion},
+		{"glbLength", []byte{'g', 'l', 'T', 'F', 2, 0, 0, 0, 100, 0, 0, 0}, errGLBChunk},
+		{"glbShortLength", []byte{'g', 'l', 'T', 'F', 2, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, errGLBChunk},
+		{"outOfBounds", encodeDocument(t, outOfBounds), errAccessor},
+		{"badIndex", encodeDocument(t, badIndex), errAccessor},
+		{"external", encodeDocument(t, external), errUnresolvedURI},
+		{"badNode", encodeDocument(t, badNode), errOutOfBounds},
+		{"badScene", encodeDocument(t, badScene), errOutOfBounds},
+		{"badMaterial", encodeDocument(t, badMaterial), errOutOfBounds},
+		{"badView", encodeDocument(t, badView), errOutOfBounds},
+		{"badBuffer", encodeDocument(t, badBuffer), errOutOfBounds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewDecoder(bytes.NewReader(tt.b)).Decode(new(go3mf.Model))
+			if err == nil {
+				t.Fatal("Decoder.Decode() expected error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Decoder.Decode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_Limits(t *testing.T) {
+	doc := quadDocument()
+	data, _, _ := new(docDecoder).readURIData(doc.Buffers[0].URI)
+	doc.Buffers[0].URI = "quad.bin"
+	b := encodeDocument(t, doc)
+	data = append(data, make([]byte, len(b))...)
+	tests := []struct {
+		name   string
+		limits go3mf.Limits
+		want   *go3mf.LimitError
+	}{
+		{"none", go3mf.Limits{}, nil},
+		{"fit", go3mf.Limits{MaxPartSize: int64(len(data)), MaxTotalSize: int64(len(b) + len(data))}, nil},
+		{"file", go3mf.Limits{MaxPartSize: int64(len(b)) - 1}, &go3mf.LimitError{Limit: "MaxPartSize", Max: int64(len(b)) - 1}},
+		{"uri", go3mf.Limits{MaxPartSize: int64(len(b))}, &go3mf.LimitError{Path: "quad.bin", Limit: "MaxPartSize", Max: int64(len(b))}},
+		{"total", go3mf.Limits{MaxTotalSize: int64(len(b) + len(data) - 1)}, &go3mf.LimitError{Limit: "MaxTotalSize", Max: int64(len(b) + len(data) - 1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(bytes.NewReader(b))
+			d.Limits = tt.limits
+			d.ReadURI = func(uri string) (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(data)), nil
+			}
+			err := d.Decode(new(go3mf.Model))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Decoder.Decode() error = %v", err)
+				}
+				return
+			}
+			var lerr *go3mf.LimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("Decoder.Decode() error = %v, want %v", err, tt.want)
+			}
+			if diff := deep.Equal(lerr, tt.want); diff != nil {
+				t.Errorf("Decoder.Decode() error = %v", diff)
+			}
+		})
+	}
+}
+
+func TestDecoder_DecodeContext_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := NewDecoder(bytes.NewReader(encodeDocument(t, quadDocument()))).DecodeContext(ctx, new(go3mf.Model))
+	if err != context.Canceled {
+		t.Errorf("Decoder.DecodeContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func Test_triangulate(t *testing.T) {
+	tests := []struct {
+		name    string
+		indices []uint32
+		mode    int
+		want    [][3]uint32
+	}{
+		{"triangles", []uint32{0, 1, 2, 3, 4, 5, 6}, modeTriangles, [][3]uint32{{0, 1, 2}, {3, 4, 5}}},
+		{"strip", []uint32{0, 1, 2, 3}, modeTriangleStrip, [][3]uint32{{0, 1, 2}, {2, 1, 3}}},
+		{"fan", []uint32{0, 1, 2, 3}, modeTriangleFan, [][3]uint32{{1, 2, 0}, {2, 3, 0}}},
+		{"short", []uint32{0, 1}, modeTriangles, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := triangulate(tt.indices, tt.mode); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("triangulate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_node_transform(t *testing.T) {
+	s := float32(math.Sqrt2 / 2)
+	tests := []struct {
+		name string
+		n    node
+		want go3mf.Matrix
+	}{
+		{"empty", node{}, go3mf.Identity()},
+		{"matrix", node{Matrix: []float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 1, 2, 3, 1}}, go3mf.Identity().Translate(1, 2, 3)},
+		{"trs", node{Translation: &[3]float32{1, 2, 3}, Rotation: &[4]float32{0, 0, s, s}, Scale: &[3]float32{2, 2, 2}},
+			go3mf.Matrix{0, 2, 0, 0, -2, 0, 0, 0, 0, 0, 2, 0, 1, 2, 3, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.n.transform()
+			for i := range got {
+				if math.Abs(float64(got[i]-tt.want[i])) > 1e-6 {
+					t.Errorf("node.transform() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func Test_format_Sniff(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want bool
+	}{
+		{"empty", "", false},
+		{"glb", "glTF\x02\x00\x00\x00", true},
+		{"json", `{"asset":{"version":"2.0"}}`, true},
+		{"bom", "\xef\xbb\xbf\n\t {}", true},
+		{"array", "[]", false},
+		{"text", "solid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (format{}).Sniff([]byte(tt.head)); got != tt.want {
+				t.Errorf("format.Sniff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,490 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/materials"
+)
+
+// An Encoder writes a Model as a binary glTF 2.0 (GLB) file.
+//
+// Build items are encoded as nodes with the item transform
+// and components as child nodes with the component transform.
+// All of them are children of a root node that converts from the model units
+// and the Z up axis used by 3MF to meters and the Y up axis used by glTF.
+//
+// Meshes are encoded without shared vertices so every triangle
+// gets a flat normal. Triangles are grouped into primitives by their properties:
+// uniform go3mf.BaseMaterials and materials.ColorGroup colors are encoded
+// as the PBR base color of the material, non uniform colors as vertex colors
+// and materials.Texture2DGroup coordinates as texture coordinates referencing
+// the embedded materials.Texture2D image.
+// Other properties are not encoded.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w: w,
+	}
+}
+
+// Encode writes the GLB encoding of m to the stream.
+func (e *Encoder) Encode(m *go3mf.Model) error {
+	enc := encoder{
+		m:         m,
+		meshes:    make(map[objectKey]int),
+		materials: make(map[materialKey]int),
+		textures:  make(map[objectKey]int),
+	}
+	if err := enc.encode(); err != nil {
+		return err
+	}
+	if enc.bin.Len() > 0 {
+		enc.doc.Buffers = []buffer{{ByteLength: enc.bin.Len()}}
+	}
+	js, err := json.Marshal(&enc.doc)
+	if err != nil {
+		return err
+	}
+	return writeGLB(e.w, js, enc.bin.Bytes())
+}
+
+type objectKey struct {
+	path string
+	id   uint32
+}
+
+type materialKey struct {
+	name    string
+	color   color.RGBA
+	texture int // texture index plus one, zero if not textured
+	blend   bool
+}
+
+type primitiveKind uint8
+
+const (
+	primitivePlain primitiveKind = iota
+	primitiveColor
+	primitiveVertexColor
+	primitiveTexture
+)
+
+type primitiveKey struct {
+	kind  primitiveKind
+	id    uint32
+	name  string
+	color color.RGBA
+}
+
+// primitiveBuilder accumulates the non indexed vertices of a primitive.
+type primitiveBuilder struct {
+	key       primitiveKey
+	texture   int
+	positions []float32
+	normals   []float32
+	colors    []float32
+	uvs       []float32
+	blend     bool
+}
+
+func (p *primitiveBuilder) addTriangle(v [3]go3mf.Point3D) {
+	n := normal(v)
+	for _, c := range v {
+		p.positions = append(p.positions, c[0], c[1], c[2])
+		p.normals = append(p.normals, n[0], n[1], n[2])
+	}
+}
+
+func (p *primitiveBuilder) addColors(c [3]color.RGBA) {
+	for _, c := range c {
+		p.colors = append(p.colors, srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B), float32(c.A)/255)
+		if c.A < 255 {
+			p.blend = true
+		}
+	}
+}
+
+func (p *primitiveBuilder) addUVs(uv [3]materials.TextureCoord) {
+	for _, c := range uv {
+		// glTF places the origin of the texture space at the top left corner.
+		p.uvs = append(p.uvs, c.U(), 1-c.V())
+	}
+}
+
+type encoder struct {
+	m         *go3mf.Model
+	doc       document
+	bin       bytes.Buffer
+	meshes    map[objectKey]int
+	materials map[materialKey]int
+	textures  map[objectKey]int
+}
+
+func (e *encoder) encode() error {
+	e.doc.Asset = asset{Version: version, Generator: generator}
+	up := upAxis(e.m.Units)
+	e.doc.Nodes = append(e.doc.Nodes, node{Matrix: up[:]})
+	var children []int
+	for _, item := range e.m.Build.Items {
+		index, ok, err := e.addNode(item.ObjectPath(), item.ObjectID, item.Transform, nil)
+		if err != nil {
+			return err
+		}
+		if ok {
+			children = append(children, index)
+		}
+	}
+	e.doc.Nodes[0].Children = children
+	e.doc.Scene = intPtr(0)
+	e.doc.Scenes = []scene{{Nodes: []int{0}}}
+	return nil
+}
+
+func (e *encoder) addNode(path string, id uint32, t go3mf.Matrix, visiting []*go3mf.Object) (int, bool, error) {
+	o, ok := e.m.FindObject(path, id)
+	if !ok {
+		return 0, false, nil
+	}
+	for _, v := range visiting {
+		if v == o {
+			return 0, false, nil // recursive reference
+		}
+	}
+	n := node{Name: o.Name}
+	if t != (go3mf.Matrix{}) && t != go3mf.Identity() {
+		n.Matrix = t[:]
+	}
+	index := len(e.doc.Nodes)
+	e.doc.Nodes = append(e.doc.Nodes, n)
+	if o.Mesh != nil {
+		mesh, ok, err := e.addMesh(path, o)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			e.doc.Nodes[index].Mesh = intPtr(mesh)
+		}
+	} else if o.Components != nil {
+		visiting = append(visiting, o)
+		var children []int
+		for _, c := range o.Components.Component {
+			child, ok, err := e.addNode(c.ObjectPath(path), c.ObjectID, c.Transform, visiting)
+			if err != nil {
+				return 0, false, err
+			}
+			if ok {
+				children = append(children, child)
+			}
+		}
+		e.doc.Nodes[index].Children = children
+	}
+	return index, true, nil
+}
+
+func (e *encoder) addMesh(path string, o *go3mf.Object) (int, bool, error) {
+	key := objectKey{path: path, id: o.ID}
+	if index, ok := e.meshes[key]; ok {
+		return index, index >= 0, nil
+	}
+	var (
+		builders []*primitiveBuilder
+		lookup   = make(map[primitiveKey]*primitiveBuilder)
+	)
+	vertices := o.Mesh.Vertices.Vertex
+	nodeCount := uint32(len(vertices))
+	for _, tr := range o.Mesh.Triangles.Triangle {
+		if tr.V1 >= nodeCount || tr.V2 >= nodeCount || tr.V3 >= nodeCount {
+			continue
+		}
+		pid, p := tr.PID, [3]uint32{tr.P1, tr.P2, tr.P3}
+		if pid == 0 {
+			pid, p = o.PID, [3]uint32{o.PIndex, o.PIndex, o.PIndex}
+		}
+		pkey, colors, uvs, texture, err := e.triangleProperties(path, pid, p)
+		if err != nil {
+			return 0, false, err
+		}
+		b, ok := lookup[pkey]
+		if !ok {
+			b = &primitiveBuilder{key: pkey, texture: texture}
+			lookup[pkey] = b
+			builders = append(builders, b)
+		}
+		b.addTriangle([3]go3mf.Point3D{vertices[tr.V1], vertices[tr.V2], vertices[tr.V3]})
+		switch pkey.kind {
+		case primitiveVertexColor:
+			b.addColors(colors)
+		case primitiveTexture:
+			b.addUVs(uvs)
+		}
+	}
+	if len(builders) == 0 {
+		e.meshes[key] = -1
+		return 0, false, nil
+	}
+	m := mesh{Name: o.Name}
+	for _, b := range builders {
+		m.Primitives = append(m.Primitives, e.addPrimitive(b))
+	}
+	index := len(e.doc.Meshes)
+	e.doc.Meshes = append(e.doc.Meshes, m)
+	e.meshes[key] = index
+	return index, true, nil
+}
+
+// triangleProperties resolves the properties of a triangle.
+func (e *encoder) triangleProperties(path string, pid uint32, p [3]uint32) (
+	key primitiveKey, colors [3]color.RGBA, uvs [3]materials.TextureCoord, texture int, err error) {
+	if pid == 0 {
+		return
+	}
+	a, ok := e.m.FindAsset(path, pid)
+	if !ok {
+		return
+	}
+	switch a := a.(type) {
+	case *go3mf.BaseMaterials:
+		for i, index := range p {
+			if int(index) >= len(a.Materials) {
+				return
+			}
+			colors[i] = a.Materials[index].Color
+		}
+		if colors[0] == colors[1] && colors[0] == colors[2] {
+			key = primitiveKey{kind: primitiveColor, color: colors[0]}
+			if p[0] == p[1] && p[0] == p[2] {
+				key.name = a.Materials[p[0]].Name
+			}
+		} else {
+			key.kind = primitiveVertexColor
+		}
+	case *materials.ColorGroup:
+		for i, index := range p {
+			if int(index) >= len(a.Colors) {
+				return
+			}
+			colors[i] = a.Colors[index]
+		}
+		if colors[0] == colors[1] && colors[0] == colors[2] {
+			key = primitiveKey{kind: primitiveColor, color: colors[0]}
+		} else {
+			key.kind = primitiveVertexColor
+		}
+	case *materials.Texture2DGroup:
+		for i, index := range p {
+			if int(index) >= len(a.Coords) {
+				return
+			}
+			uvs[i] = a.Coords[index]
+		}
+		texture, ok, err = e.addTexture(path, a.TextureID)
+		if err != nil || !ok {
+			return
+		}
+		key = primitiveKey{kind: primitiveTexture, id: a.TextureID}
+	}
+	return
+}
+
+func (e *encoder) addPrimitive(b *primitiveBuilder) primitive {
+	count := len(b.positions) / 3
+	p := primitive{Attributes: make(map[string]int)}
+	minPos, maxPos := bounds(b.positions)
+	p.Attributes[attrPosition] = e.addAccessor(b.positions, count, accessorVec3, minPos, maxPos)
+	p.Attributes[attrNormal] = e.addAccessor(b.normals, count, accessorVec3, nil, nil)
+	mkey := materialKey{color: color.RGBA{255, 255, 255, 255}}
+	switch b.key.kind {
+	case primitiveColor:
+		mkey.name, mkey.color, mkey.blend = b.key.name, b.key.color, b.key.color.A < 255
+	case primitiveVertexColor:
+		p.Attributes[attrColor] = e.addAccessor(b.colors, count, accessorVec4, nil, nil)
+		mkey.blend = b.blend
+	case primitiveTexture:
+		p.Attributes[attrTexCoord] = e.addAccessor(b.uvs, count, accessorVec2, nil, nil)
+		mkey.texture = b.texture + 1
+	}
+	p.Material = intPtr(e.addMaterial(mkey))
+	return p
+}
+
+func (e *encoder) addMaterial(key materialKey) int {
+	if index, ok := e.materials[key]; ok {
+		return index
+	}
+	var metallic float32
+	mat := material{
+		Name: key.name,
+		PBRMetallicRoughness: &pbrMetallicRoughness{
+			MetallicFactor: &metallic,
+		},
+	}
+	c := key.color
+	if c != (color.RGBA{255, 255, 255, 255}) {
+		mat.PBRMetallicRoughness.BaseColorFactor = &[4]float32{
+			srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B), float32(c.A) / 255,
+		}
+	}
+	if key.blend {
+		mat.AlphaMode = alphaModeBlend
+	}
+	if key.texture > 0 {
+		mat.PBRMetallicRoughness.BaseColorTexture = &textureInfo{Index: key.texture - 1}
+	}
+	index := len(e.doc.Materials)
+	e.doc.Materials = append(e.doc.Materials, mat)
+	e.materials[key] = index
+	return index
+}
+
+// addTexture adds the image of the texture identified by path and id.
+// It returns false if the texture or the image is not found.
+func (e *encoder) addTexture(path string, id uint32) (int, bool, error) {
+	key := objectKey{path: path, id: id}
+	if index, ok := e.textures[key]; ok {
+		return index, index >= 0, nil
+	}
+	e.textures[key] = -1
+	a, ok := e.m.FindAsset(path, id)
+	if !ok {
+		return 0, false, nil
+	}
+	tex, ok := a.(*materials.Texture2D)
+	if !ok {
+		return 0, false, nil
+	}
+	var att *go3mf.Attachment
+	for i := range e.m.Attachments {
+		if e.m.Attachments[i].Path == tex.Path {
+			att = &e.m.Attachments[i]
+			break
+		}
+	}
+	if att == nil {
+		return 0, false, nil
+	}
+	data, err := attachmentData(att)
+	if err != nil {
+		return 0, false, err
+	}
+	contentType := tex.ContentType.String()
+	if contentType == "" {
+		contentType = att.ContentType
+	}
+	imageIndex := len(e.doc.Images)
+	e.doc.Images = append(e.doc.Images, image{
+		BufferView: intPtr(e.addBufferView(data, 0)),
+		MimeType:   contentType,
+	})
+	samplerIndex := len(e.doc.Samplers)
+	e.doc.Samplers = append(e.doc.Samplers, sampler{
+		MagFilter: textureFilter(tex.Filter),
+		MinFilter: textureFilter(tex.Filter),
+		WrapS:     tileStyle(tex.TileStyleU),
+		WrapT:     tileStyle(tex.TileStyleV),
+	})
+	index := len(e.doc.Textures)
+	e.doc.Textures = append(e.doc.Textures, texture{Sampler: intPtr(samplerIndex), Source: intPtr(imageIndex)})
+	e.textures[key] = index
+	return index, true, nil
+}
+
+// attachmentData returns the content of an attachment.
+func attachmentData(a *go3mf.Attachment) ([]byte, error) {
+	r, err := a.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (e *encoder) addBufferView(data []byte, target int) int {
+	for e.bin.Len()%4 != 0 {
+		e.bin.WriteByte(0)
+	}
+	index := len(e.doc.BufferViews)
+	e.doc.BufferViews = append(e.doc.BufferViews, bufferView{
+		ByteOffset: e.bin.Len(),
+		ByteLength: len(data),
+		Target:     target,
+	})
+	e.bin.Write(data)
+	return index
+}
+
+func (e *encoder) addAccessor(data []float32, count int, typ string, min, max []float32) int {
+	b := make([]byte, 4*len(data))
+	for i, v := range data {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	index := len(e.doc.Accessors)
+	e.doc.Accessors = append(e.doc.Accessors, accessor{
+		BufferView:    intPtr(e.addBufferView(b, targetArrayBuffer)),
+		ComponentType: componentFloat,
+		Count:         count,
+		Type:          typ,
+		Min:           min,
+		Max:           max,
+	})
+	return index
+}
+
+func textureFilter(f materials.TextureFilter) int {
+	if f == materials.TextureFilterNearest {
+		return filterNearest
+	}
+	return filterLinear
+}
+
+func tileStyle(t materials.TileStyle) int {
+	switch t {
+	case materials.TileMirror:
+		return wrapMirroredRepeat
+	case materials.TileClamp, materials.TileNone:
+		return wrapClampToEdge
+	}
+	return wrapRepeat
+}
+
+// bounds returns the minimum and maximum values of a VEC3 array.
+func bounds(v []float32) ([]float32, []float32) {
+	min := []float32{v[0], v[1], v[2]}
+	max := []float32{v[0], v[1], v[2]}
+	for i := 3; i < len(v); i += 3 {
+		for j := 0; j < 3; j++ {
+			if v[i+j] < min[j] {
+				min[j] = v[i+j]
+			} else if v[i+j] > max[j] {
+				max[j] = v[i+j]
+			}
+		}
+	}
+	return min, max
+}
+
+// normal returns the unit normal of a triangle.
+// Degenerated triangles get the Z axis as normal.
+func normal(v [3]go3mf.Point3D) [3]float32 {
+	a := [3]float32{v[1][0] - v[0][0], v[1][1] - v[0][1], v[1][2] - v[0][2]}
+	b := [3]float32{v[2][0] - v[0][0], v[2][1] - v[0][1], v[2][2] - v[0][2]}
+	n := [3]float32{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+	l := float32(math.Sqrt(float64(n[0]*n[0] + n[1]*n[1] + n[2]*n[2])))
+	if l == 0 {
+		return [3]float32{0, 0, 1}
+	}
+	return [3]float32{n[0] / l, n[1] / l, n[2] / l}
+}
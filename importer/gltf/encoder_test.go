@@ -0,0 +1,215 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/materials"
+)
+
+func parseTestGLB(t *testing.T, b []byte) (*document, []byte) {
+	t.Helper()
+	var h [5]uint32
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, &h); err != nil {
+		t.Fatal(err)
+	}
+	if h[0] != glbMagic || h[1] != glbVersion || int(h[2]) != len(b) || h[4] != glbChunkJSON {
+		t.Fatalf("invalid GLB header %v", h)
+	}
+	if len(b)%4 != 0 || h[3]%4 != 0 {
+		t.Fatal("GLB chunks are not aligned")
+	}
+	js := b[20 : 20+h[3]]
+	var doc document
+	if err := json.Unmarshal(js, &doc); err != nil {
+		t.Fatal(err)
+	}
+	var bin []byte
+	if rest := b[20+h[3]:]; len(rest) > 0 {
+		n := binary.LittleEndian.Uint32(rest)
+		if binary.LittleEndian.Uint32(rest[4:]) != glbChunkBIN {
+			t.Fatal("invalid BIN chunk")
+		}
+		bin = rest[8 : 8+n]
+	}
+	return &doc, bin
+}
+
+func accessorFloats(doc *document, bin []byte, index int) []float32 {
+	a := doc.Accessors[index]
+	v := doc.BufferViews[*a.BufferView]
+	data := bin[v.ByteOffset : v.ByteOffset+v.ByteLength]
+	f := make([]float32, len(data)/4)
+	for i := range f {
+		f[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return f
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G'}
+	m := &go3mf.Model{
+		Units: go3mf.UnitCentimeter,
+		Resources: go3mf.Resources{
+			Assets: []go3mf.Asset{
+				&go3mf.BaseMaterials{ID: 1, Materials: []go3mf.Base{
+					{Name: "red", Color: color.RGBA{255, 0, 0, 255}},
+					{Name: "glass", Color: color.RGBA{0, 0, 255, 128}},
+				}},
+				&materials.ColorGroup{ID: 2, Colors: []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}}},
+				&materials.Texture2D{ID: 3, Path: "/3D/Texture/a.png", ContentType: materials.TextureTypePNG, TileStyleU: materials.TileClamp, Filter: materials.TextureFilterNearest},
+				&materials.Texture2DGroup{ID: 4, TextureID: 3, Coords: []materials.TextureCoord{{0, 0}, {1, 0}, {1, 1}}},
+			},
+			Objects: []*go3mf.Object{
+				{ID: 5, Name: "part", PID: 1, Mesh: &go3mf.Mesh{
+					Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}},
+					Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+						{V1: 0, V2: 1, V3: 2},
+						{V1: 0, V2: 2, V3: 3, PID: 1, P1: 1, P2: 1, P3: 1},
+						{V1: 0, V2: 1, V3: 3, PID: 2, P1: 0, P2: 1, P3: 1},
+						{V1: 1, V2: 2, V3: 3, PID: 4, P1: 0, P2: 1, P3: 2},
+						{V1: 1, V2: 2, V3: 3, PID: 100},
+						{V1: 1, V2: 2, V3: 30},
+					}},
+				}},
+				{ID: 6, Name: "assembly", Components: &go3mf.Components{Component: []*go3mf.Component{
+					{ObjectID: 5, Transform: go3mf.Identity().Translate(10, 0, 0)},
+					{ObjectID: 5},
+					{ObjectID: 6},
+					{ObjectID: 100},
+				}}},
+			},
+		},
+		Build: go3mf.Build{Items: []*go3mf.Item{
+			{ObjectID: 6, Transform: go3mf.Identity().Translate(0, 0, 5)},
+			{ObjectID: 5},
+			{ObjectID: 100},
+		}},
+		Attachments: []go3mf.Attachment{{Path: "/3D/Texture/a.png", ContentType: "image/png", Stream: bytes.NewBuffer(png)}},
+	}
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	doc, bin := parseTestGLB(t, b.Bytes())
+	s := float32(unitScale(go3mf.UnitCentimeter))
+	var metallic float32
+	red := srgbToLinear(255)
+	wantNodes := []node{
+		{Matrix: []float32{s, 0, 0, 0, 0, 0, -s, 0, 0, s, 0, 0, 0, 0, 0, 1}, Children: []int{1, 4}},
+		{Name: "assembly", Matrix: []float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 5, 1}, Children: []int{2, 3}},
+		{Name: "part", Matrix: []float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 10, 0, 0, 1}, Mesh: intPtr(0)},
+		{Name: "part", Mesh: intPtr(0)},
+		{Name: "part", Mesh: intPtr(0)},
+	}
+	if diff := deep.Equal(doc.Nodes, wantNodes); diff != nil {
+		t.Errorf("Encoder.Encode() nodes = %v", diff)
+	}
+	wantMaterials := []material{
+		{Name: "red", PBRMetallicRoughness: &pbrMetallicRoughness{MetallicFactor: &metallic, BaseColorFactor: &[4]float32{red, 0, 0, 1}}},
+		{Name: "glass", AlphaMode: alphaModeBlend, PBRMetallicRoughness: &pbrMetallicRoughness{MetallicFactor: &metallic, BaseColorFactor: &[4]float32{0, 0, red, float32(128) / 255}}},
+		{PBRMetallicRoughness: &pbrMetallicRoughness{MetallicFactor: &metallic}},
+		{PBRMetallicRoughness: &pbrMetallicRoughness{MetallicFactor: &metallic, BaseColorTexture: &textureInfo{Index: 0}}},
+	}
+	if diff := deep.Equal(doc.Materials, wantMaterials); diff != nil {
+		t.Errorf("Encoder.Encode() materials = %v", diff)
+	}
+	if len(doc.Meshes) != 1 || len(doc.Meshes[0].Primitives) != 5 {
+		t.Fatalf("Encoder.Encode() meshes = %v", doc.Meshes)
+	}
+	wantAttrs := [][]string{
+		{attrPosition, attrNormal},
+		{attrPosition, attrNormal},
+		{attrPosition, attrNormal, attrColor},
+		{attrPosition, attrNormal, attrTexCoord},
+		{attrPosition, attrNormal},
+	}
+	wantMaterial := []int{0, 1, 2, 3, 2}
+	for i, p := range doc.Meshes[0].Primitives {
+		if len(p.Attributes) != len(wantAttrs[i]) || *p.Material != wantMaterial[i] {
+			t.Errorf("Encoder.Encode() primitive %d = %v", i, p)
+		}
+		for _, attr := range wantAttrs[i] {
+			if a, ok := p.Attributes[attr]; !ok || doc.Accessors[a].Count != 3 {
+				t.Errorf("Encoder.Encode() primitive %d attribute %s = %v", i, attr, p.Attributes)
+			}
+		}
+	}
+	pos := doc.Accessors[doc.Meshes[0].Primitives[0].Attributes[attrPosition]]
+	if diff := deep.Equal([][]float32{pos.Min, pos.Max}, [][]float32{{0, 0, 0}, {1, 1, 0}}); diff != nil {
+		t.Errorf("Encoder.Encode() position bounds = %v", diff)
+	}
+	normals := accessorFloats(doc, bin, doc.Meshes[0].Primitives[0].Attributes[attrNormal])
+	if diff := deep.Equal(normals[:3], []float32{0, 0, 1}); diff != nil {
+		t.Errorf("Encoder.Encode() normals = %v", diff)
+	}
+	colors := accessorFloats(doc, bin, doc.Meshes[0].Primitives[2].Attributes[attrColor])
+	if diff := deep.Equal(colors, []float32{red, 0, 0, 1, 0, red, 0, 1, 0, red, 0, 1}); diff != nil {
+		t.Errorf("Encoder.Encode() colors = %v", diff)
+	}
+	uvs := accessorFloats(doc, bin, doc.Meshes[0].Primitives[3].Attributes[attrTexCoord])
+	if diff := deep.Equal(uvs, []float32{0, 1, 1, 1, 1, 0}); diff != nil {
+		t.Errorf("Encoder.Encode() uvs = %v", diff)
+	}
+	wantSamplers := []sampler{{MagFilter: filterNearest, MinFilter: filterNearest, WrapS: wrapClampToEdge, WrapT: wrapRepeat}}
+	if diff := deep.Equal(doc.Samplers, wantSamplers); diff != nil {
+		t.Errorf("Encoder.Encode() samplers = %v", diff)
+	}
+	if len(doc.Images) != 1 || doc.Images[0].MimeType != "image/png" {
+		t.Fatalf("Encoder.Encode() images = %v", doc.Images)
+	}
+	v := doc.BufferViews[*doc.Images[0].BufferView]
+	if got := bin[v.ByteOffset : v.ByteOffset+v.ByteLength]; !bytes.Equal(got, png) {
+		t.Errorf("Encoder.Encode() image = %v, want %v", got, png)
+	}
+	if doc.Buffers[0].ByteLength != len(bin) {
+		t.Errorf("Encoder.Encode() buffer length = %d, want %d", doc.Buffers[0].ByteLength, len(bin))
+	}
+}
+
+func TestEncoder_Encode_Empty(t *testing.T) {
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(new(go3mf.Model)); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	doc, bin := parseTestGLB(t, b.Bytes())
+	if len(bin) != 0 || len(doc.Buffers) != 0 || len(doc.Nodes) != 1 || doc.Asset.Version != "2.0" {
+		t.Errorf("Encoder.Encode() = %v", doc)
+	}
+}
+
+func Test_srgbToLinear(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		if got := linearToSRGB(srgbToLinear(uint8(i))); got != uint8(i) {
+			t.Errorf("linearToSRGB(srgbToLinear(%d)) = %d", i, got)
+		}
+	}
+}
+
+func Test_normal(t *testing.T) {
+	tests := []struct {
+		name string
+		v    [3]go3mf.Point3D
+		want [3]float32
+	}{
+		{"z", [3]go3mf.Point3D{{0, 0, 0}, {2, 0, 0}, {0, 2, 0}}, [3]float32{0, 0, 1}},
+		{"x", [3]go3mf.Point3D{{0, 0, 0}, {0, 1, 0}, {0, 0, 1}}, [3]float32{1, 0, 0}},
+		{"degenerated", [3]go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}}, [3]float32{0, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normal(tt.v); got != tt.want {
+				t.Errorf("normal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+// Package gltf implements a glTF 2.0 encoder and decoder
+// for go3mf models.
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/hpinc/go3mf"
+)
+
+const (
+	glbMagic     = 0x46546C67 // glTF
+	glbVersion   = 2
+	glbChunkJSON = 0x4E4F534A // JSON
+	glbChunkBIN  = 0x004E4942 // BIN
+	glbHeaderLen = 12
+)
+
+// Component types.
+const (
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+)
+
+// Buffer view targets.
+const (
+	targetArrayBuffer        = 34962
+	targetElementArrayBuffer = 34963
+)
+
+// Primitive modes.
+const (
+	modeTriangles     = 4
+	modeTriangleStrip = 5
+	modeTriangleFan   = 6
+)
+
+// Sampler filters and wrapping modes.
+const (
+	filterNearest      = 9728
+	filterLinear       = 9729
+	wrapClampToEdge    = 33071
+	wrapMirroredRepeat = 33648
+	wrapRepeat         = 10497
+)
+
+const (
+	attrPosition   = "POSITION"
+	attrNormal     = "NORMAL"
+	attrColor      = "COLOR_0"
+	attrTexCoord   = "TEXCOORD_0"
+	accessorScalar = "SCALAR"
+	accessorVec2   = "VEC2"
+	accessorVec3   = "VEC3"
+	accessorVec4   = "VEC4"
+	alphaModeBlend = "BLEND"
+	version        = "2.0"
+	generator      = "go3mf"
+)
+
+var (
+	errGLBMagic   = errors.New("gltf: invalid GLB header")
+	errGLBVersion = errors.New("gltf: unsupported GLB version")
+	errGLBChunk   = errors.New("gltf: invalid GLB chunk")
+)
+
+type document struct {
+	Asset       asset        `json:"asset"`
+	Scene       *int         `json:"scene,omitempty"`
+	Scenes      []scene      `json:"scenes,omitempty"`
+	Nodes       []node       `json:"nodes,omitempty"`
+	Meshes      []mesh       `json:"meshes,omitempty"`
+	Accessors   []accessor   `json:"accessors,omitempty"`
+	BufferViews []bufferView `json:"bufferViews,omitempty"`
+	Buffers     []buffer     `json:"buffers,omitempty"`
+	Materials   []material   `json:"materials,omitempty"`
+	Textures    []texture    `json:"textures,omitempty"`
+	Images      []image      `json:"images,omitempty"`
+	Samplers    []sampler    `json:"samplers,omitempty"`
+}
+
+type asset struct {
+	Version    string `json:"version"`
+	MinVersion string `json:"minVersion,omitempty"`
+	Generator  string `json:"generator,omitempty"`
+}
+
+type scene struct {
+	Name  string `json:"name,omitempty"`
+	Nodes []int  `json:"nodes,omitempty"`
+}
+
+type node struct {
+	Name        string      `json:"name,omitempty"`
+	Children    []int       `json:"children,omitempty"`
+	Mesh        *int        `json:"mesh,omitempty"`
+	Matrix      []float32   `json:"matrix,omitempty"`
+	Translation *[3]float32 `json:"translation,omitempty"`
+	Rotation    *[4]float32 `json:"rotation,omitempty"`
+	Scale       *[3]float32 `json:"scale,omitempty"`
+}
+
+// transform returns the local transform of the node.
+func (n *node) transform() go3mf.Matrix {
+	if len(n.Matrix) == 16 {
+		var m go3mf.Matrix
+		copy(m[:], n.Matrix)
+		return m
+	}
+	m := go3mf.Identity()
+	if n.Scale != nil {
+		s := n.Scale
+		m = go3mf.Matrix{s[0], 0, 0, 0, 0, s[1], 0, 0, 0, 0, s[2], 0, 0, 0, 0, 1}
+	}
+	if n.Rotation != nil {
+		x, y, z, w := n.Rotation[0], n.Rotation[1], n.Rotation[2], n.Rotation[3]
+		r := go3mf.Matrix{
+			1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+			2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+			2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+			0, 0, 0, 1,
+		}
+		m = r.Mul(m)
+	}
+	if n.Translation != nil {
+		m = go3mf.Identity().Translate(n.Translation[0], n.Translation[1], n.Translation[2]).Mul(m)
+	}
+	return m
+}
+
+type mesh struct {
+	Name       string      `json:"name,omitempty"`
+	Primitives []primitive `json:"primitives"`
+}
+
+type primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   *int           `json:"material,omitempty"`
+	Mode       *int           `json:"mode,omitempty"`
+}
+
+type accessor struct {
+	BufferView    *int      `json:"bufferView,omitempty"`
+	ByteOffset    int       `json:"byteOffset,omitempty"`
+	ComponentType int       `json:"componentType"`
+	Normalized    bool      `json:"normalized,omitempty"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type bufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset,omitempty"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride,omitempty"`
+	Target     int `json:"target,omitempty"`
+}
+
+type buffer struct {
+	URI        string `json:"uri,omitempty"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type material struct {
+	Name                 string                `json:"name,omitempty"`
+	PBRMetallicRoughness *pbrMetallicRoughness `json:"pbrMetallicRoughness,omitempty"`
+	AlphaMode            string                `json:"alphaMode,omitempty"`
+	DoubleSided          bool                  `json:"doubleSided,omitempty"`
+}
+
+type pbrMetallicRoughness struct {
+	BaseColorFactor  *[4]float32  `json:"baseColorFactor,omitempty"`
+	BaseColorTexture *textureInfo `json:"baseColorTexture,omitempty"`
+	MetallicFactor   *float32     `json:"metallicFactor,omitempty"`
+	RoughnessFactor  *float32     `json:"roughnessFactor,omitempty"`
+}
+
+type textureInfo struct {
+	Index    int `json:"index"`
+	TexCoord int `json:"texCoord,omitempty"`
+}
+
+type texture struct {
+	Sampler *int `json:"sampler,omitempty"`
+	Source  *int `json:"source,omitempty"`
+}
+
+type image struct {
+	Name       string `json:"name,omitempty"`
+	URI        string `json:"uri,omitempty"`
+	MimeType   string `json:"mimeType,omitempty"`
+	BufferView *int   `json:"bufferView,omitempty"`
+}
+
+type sampler struct {
+	MagFilter int `json:"magFilter,omitempty"`
+	MinFilter int `json:"minFilter,omitempty"`
+	WrapS     int `json:"wrapS,omitempty"`
+	WrapT     int `json:"wrapT,omitempty"`
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// writeGLB writes the binary container holding the JSON document and the binary buffer.
+func writeGLB(w io.Writer, js, bin []byte) error {
+	js = pad(js, ' ')
+	bin = pad(bin, 0)
+	length := glbHeaderLen + 8 + len(js)
+	if len(bin) > 0 {
+		length += 8 + len(bin)
+	}
+	var b bytes.Buffer
+	b.Grow(length)
+	binary.Write(&b, binary.LittleEndian, [3]uint32{glbMagic, glbVersion, uint32(length)})
+	binary.Write(&b, binary.LittleEndian, [2]uint32{uint32(len(js)), glbChunkJSON})
+	b.Write(js)
+	if len(bin) > 0 {
+		binary.Write(&b, binary.LittleEndian, [2]uint32{uint32(len(bin)), glbChunkBIN})
+		b.Write(bin)
+	}
+	_, err := b.WriteTo(w)
+	return err
+}
+
+// readGLB returns the JSON and the binary chunks of a GLB file.
+func readGLB(b []byte) (js, bin []byte, err error) {
+	if len(b) < glbHeaderLen || binary.LittleEndian.Uint32(b) != glbMagic {
+		return nil, nil, errGLBMagic
+	}
+	if binary.LittleEndian.Uint32(b[4:]) != glbVersion {
+		return nil, nil, errGLBVersion
+	}
+	length := binary.LittleEndian.Uint32(b[8:])
+	if length < glbHeaderLen || uint64(length) > uint64(len(b)) {
+		return nil, nil, errGLBChunk
+	}
+	b = b[glbHeaderLen:length]
+	for i := 0; len(b) > 0; i++ {
+		if len(b) < 8 {
+			return nil, nil, errGLBChunk
+		}
+		n, typ := binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:])
+		if uint64(n) > uint64(len(b)-8) {
+			return nil, nil, errGLBChunk
+		}
+		chunk := b[8 : 8+n]
+		switch {
+		case i == 0 && typ != glbChunkJSON:
+			return nil, nil, errGLBChunk
+		case i == 0:
+			js = chunk
+		case i == 1 && typ == glbChunkBIN:
+			bin = chunk
+		}
+		b = b[8+n:]
+	}
+	return js, bin, nil
+}
+
+// pad fills b with c until its length is multiple of 4.
+func pad(b []byte, c byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, c)
+	}
+	return b
+}
+
+// srgbToLinear converts a 8-bit sRGB channel to a linear value.
+func srgbToLinear(c uint8) float32 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return float32(v / 12.92)
+	}
+	return float32(math.Pow((v+0.055)/1.055, 2.4))
+}
+
+// linearToSRGB converts a linear value to a 8-bit sRGB channel.
+func linearToSRGB(v float32) uint8 {
+	f := float64(v)
+	if f <= 0 {
+		return 0
+	} else if f >= 1 {
+		return 255
+	}
+	if f <= 0.0031308 {
+		f *= 12.92
+	} else {
+		f = 1.055*math.Pow(f, 1/2.4) - 0.055
+	}
+	return uint8(f*255 + 0.5)
+}
+
+// unitScale returns the factor that converts from u to meters.
+func unitScale(u go3mf.Units) float64 {
+	switch u {
+	case go3mf.UnitMicrometer:
+		return 0.000001
+	case go3mf.UnitCentimeter:
+		return 0.01
+	case go3mf.UnitInch:
+		return 0.0254
+	case go3mf.UnitFoot:
+		return 0.3048
+	case go3mf.UnitMeter:
+		return 1
+	}
+	return 0.001
+}
+
+// upAxis returns the transform that converts from the 3MF space, Z up, with units u
+// to the glTF space, Y up in meters.
+func upAxis(u go3mf.Units) go3mf.Matrix {
+	s := float32(unitScale(u))
+	return go3mf.Matrix{s, 0, 0, 0, 0, 0, -s, 0, 0, s, 0, 0, 0, 0, 0, 1}
+}
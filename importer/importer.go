@@ -0,0 +1,218 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+// Package importer decodes models whose format is not known in advance.
+//
+// The format is detected by sniffing the first bytes of the content
+// and the decoding is dispatched to the importer registered for that format.
+// The 3MF importer is always available, the other importers register
+// themselves when their package is imported:
+//
+//	import _ "github.com/hpinc/go3mf/importer/stl"
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hpinc/go3mf"
+)
+
+// SniffLen is the maximum number of bytes passed to Importer.Sniff.
+const SniffLen = 512
+
+// ErrFormat indicates that decoding encountered an unknown format.
+var ErrFormat = errors.New("importer: unknown format")
+
+// MaxBufferSize is the maximum number of bytes buffered in memory
+// to decode a 3MF package from a reader whose content cannot be read
+// at any offset, as the zip central directory is at the end of the content.
+var MaxBufferSize int64 = 1 << 30
+
+type format struct {
+	name string
+	imp  Importer
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   []format
+)
+
+// Importer is the interface that must be implemented by a model importer.
+//
+// Importers may implement FallbackImporter.
+type Importer interface {
+	// Sniff reports whether head, which holds the first bytes of the content,
+	// is encoded in the importer format.
+	Sniff(head []byte) bool
+	// DecodeContext decodes r into m.
+	DecodeContext(ctx context.Context, r io.Reader, m *go3mf.Model) error
+}
+
+// If an Importer implements FallbackImporter, then SniffFallback
+// is called when no registered importer has sniffed the content.
+// It is intended for formats without a distinctive header, such as binary STL.
+//
+// size is the size of the whole content, or -1 if it is not known.
+type FallbackImporter interface {
+	Importer
+	SniffFallback(head []byte, size int64) bool
+}
+
+// Register makes an importer available by the provided name.
+// Importers are sniffed in registration order.
+// If Register is called twice with the same name the importer is replaced.
+func Register(name string, imp Importer) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for i := range formats {
+		if formats[i].name == name {
+			formats[i].imp = imp
+			return
+		}
+	}
+	formats = append(formats, format{name: name, imp: imp})
+}
+
+// Load returns the importer registered with the provided name.
+func Load(name string) (Importer, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	for _, f := range formats {
+		if f.name == name {
+			return f.imp, true
+		}
+	}
+	return nil, false
+}
+
+// Decode detects the format of r and decodes it into m.
+// The string returned is the name used to register the importer.
+func Decode(r io.Reader, m *go3mf.Model) (string, error) {
+	return DecodeContext(context.Background(), r, m)
+}
+
+// DecodeContext detects the format of r and decodes it into m.
+// The string returned is the name used to register the importer.
+//
+// If r is an io.ReaderAt whose size is known, such as *bytes.Reader or *os.File,
+// its whole content is decoded from the start and the importers
+// receive an *io.SectionReader, so they can avoid buffering it.
+func DecodeContext(ctx context.Context, r io.Reader, m *go3mf.Model) (string, error) {
+	if sr, ok := newSectionReader(r); ok {
+		head := make([]byte, SniffLen)
+		n, err := sr.ReadAt(head, 0)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		f, ok := sniff(head[:n], sr.Size())
+		if !ok {
+			return "", ErrFormat
+		}
+		return f.name, f.imp.DecodeContext(ctx, sr, m)
+	}
+	b := bufio.NewReaderSize(r, SniffLen)
+	head, err := b.Peek(SniffLen)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	f, ok := sniff(head, -1)
+	if !ok {
+		return "", ErrFormat
+	}
+	return f.name, f.imp.DecodeContext(ctx, b, m)
+}
+
+// newSectionReader returns a reader of the whole content of r
+// if r can be read at any offset and its size is known.
+func newSectionReader(r io.Reader) (*io.SectionReader, bool) {
+	ra, ok := r.(io.ReaderAt)
+	if !ok {
+		return nil, false
+	}
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return io.NewSectionReader(ra, 0, r.Size()), true
+	case interface{ Stat() (os.FileInfo, error) }:
+		if fi, err := r.Stat(); err == nil && fi.Mode().IsRegular() {
+			return io.NewSectionReader(ra, 0, fi.Size()), true
+		}
+	}
+	return nil, false
+}
+
+// AddExtension adds ext to the extensions of m,
+// unless m already declares its namespace.
+// It is intended for importers that decode resources of other specs.
+func AddExtension(m *go3mf.Model, ext go3mf.Extension) {
+	for _, e := range m.Extensions {
+		if e.Namespace == ext.Namespace {
+			return
+		}
+	}
+	m.Extensions = append(m.Extensions, ext)
+}
+
+func sniff(head []byte, size int64) (format, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	for _, f := range formats {
+		if f.imp.Sniff(head) {
+			return f, true
+		}
+	}
+	for _, f := range formats {
+		if fb, ok := f.imp.(FallbackImporter); ok && fb.SniffFallback(head, size) {
+			return f, true
+		}
+	}
+	return format{}, false
+}
+
+func init() {
+	Register("3mf", threeMF{})
+}
+
+// threeMF implements the Importer interface for 3MF packages.
+type threeMF struct{}
+
+// Sniff reports whether head is a zip archive whose first entry
+// is not an AMF file, which is the other zipped format.
+func (threeMF) Sniff(head []byte) bool {
+	const sizeOfLocalHeader = 30
+	if len(head) < sizeOfLocalHeader || !bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+		return false
+	}
+	name := head[sizeOfLocalHeader:]
+	if n := int(binary.LittleEndian.Uint16(head[26:28])); n < len(name) {
+		name = name[:n]
+	}
+	return !strings.HasSuffix(strings.ToLower(string(name)), ".amf")
+}
+
+// DecodeContext reads the package in place if r is an *io.SectionReader.
+// Otherwise it reads up to MaxBufferSize bytes in memory, as the
+// zip central directory is at the end of the content.
+func (threeMF) DecodeContext(ctx context.Context, r io.Reader, m *go3mf.Model) error {
+	if sr, ok := r.(*io.SectionReader); ok {
+		return go3mf.NewDecoder(sr, sr.Size()).DecodeContext(ctx, m)
+	}
+	buff, err := ioutil.ReadAll(io.LimitReader(r, MaxBufferSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(buff)) > MaxBufferSize {
+		return fmt.Errorf("importer: 3mf package larger than MaxBufferSize of %d bytes", MaxBufferSize)
+	}
+	return go3mf.NewDecoder(bytes.NewReader(buff), int64(len(buff))).DecodeContext(ctx, m)
+}
@@ -0,0 +1,185 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/hpinc/go3mf"
+)
+
+type fakeImporter struct {
+	magic    string
+	fallback bool
+}
+
+func (f *fakeImporter) Sniff(head []byte) bool {
+	return f.magic != "" && bytes.HasPrefix(head, []byte(f.magic))
+}
+
+func (f *fakeImporter) SniffFallback(head []byte, size int64) bool {
+	return f.fallback
+}
+
+func (f *fakeImporter) DecodeContext(_ context.Context, r io.Reader, m *go3mf.Model) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.Language = string(b)
+	return nil
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("fail") }
+
+func zipped(t *testing.T, name string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	if _, err := w.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestRegister(t *testing.T) {
+	first, second := new(fakeImporter), new(fakeImporter)
+	Register("test_register", first)
+	Register("test_register", second)
+	if got, ok := Load("test_register"); !ok || got != second {
+		t.Errorf("Load() = %v, %v, want %v", got, ok, second)
+	}
+	if _, ok := Load("test_unknown"); ok {
+		t.Error("Load() found an unregistered importer")
+	}
+	if _, ok := Load("3mf"); !ok {
+		t.Error("Load() 3mf importer is not registered")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	Register("test_magic", &fakeImporter{magic: "magic"})
+	Register("test_fallback", &fakeImporter{fallback: true})
+	tests := []struct {
+		name     string
+		r        io.Reader
+		want     string
+		language string
+		wantErr  bool
+	}{
+		{"error", errReader{}, "", "", true},
+		{"magic", bytes.NewBufferString("magic content"), "test_magic", "magic content", false},
+		{"fallback", bytes.NewBufferString("other"), "test_fallback", "other", false},
+		{"readerAt", bytes.NewReader([]byte("magic at")), "test_magic", "magic at", false},
+		{"3mf", bytes.NewReader(zipped(t, "[Content_Types].xml")), "3mf", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(go3mf.Model)
+			got, err := Decode(tt.r, m)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+			if m.Language != tt.language {
+				t.Errorf("Decode() decoded %v, want %v", m.Language, tt.language)
+			}
+		})
+	}
+}
+
+func TestDecode_3MF(t *testing.T) {
+	b, err := ioutil.ReadFile("../testdata/cube.3mf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(go3mf.Model)
+	got, err := Decode(bytes.NewReader(b), m)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got != "3mf" {
+		t.Errorf("Decode() = %v, want 3mf", got)
+	}
+	if len(m.Resources.Objects) == 0 {
+		t.Error("Decode() did not decode any object")
+	}
+}
+
+func TestDecode_MaxBufferSize(t *testing.T) {
+	b, err := ioutil.ReadFile("../testdata/cube.3mf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := MaxBufferSize
+	MaxBufferSize = int64(len(b)) - 1
+	defer func() { MaxBufferSize = old }()
+	if _, err := Decode(bytes.NewBuffer(b), new(go3mf.Model)); err == nil {
+		t.Error("Decode() expected MaxBufferSize error")
+	}
+	// Readers with a known size are not buffered.
+	if _, err := Decode(bytes.NewReader(b), new(go3mf.Model)); err != nil {
+		t.Errorf("Decode() error = %v", err)
+	}
+}
+
+func TestDecode_ErrFormat(t *testing.T) {
+	formatsMu.Lock()
+	old := formats
+	formats = []format{{name: "3mf", imp: threeMF{}}}
+	formatsMu.Unlock()
+	defer func() {
+		formatsMu.Lock()
+		formats = old
+		formatsMu.Unlock()
+	}()
+	if _, err := Decode(bytes.NewBufferString("unknown"), new(go3mf.Model)); err != ErrFormat {
+		t.Errorf("Decode() error = %v, want %v", err, ErrFormat)
+	}
+}
+
+func TestAddExtension(t *testing.T) {
+	ext := go3mf.Extension{Namespace: "http://a.com", LocalName: "a"}
+	m := new(go3mf.Model)
+	AddExtension(m, ext)
+	AddExtension(m, go3mf.Extension{Namespace: "http://a.com", LocalName: "b"})
+	if len(m.Extensions) != 1 || m.Extensions[0] != ext {
+		t.Errorf("AddExtension() = %v, want [%v]", m.Extensions, ext)
+	}
+}
+
+func Test_threeMF_Sniff(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want bool
+	}{
+		{"empty", nil, false},
+		{"noZip", []byte("solid cube"), false},
+		{"shortZip", []byte("PK\x03\x04"), false},
+		{"3mf", zipped(t, "_rels/.rels"), true},
+		{"amf", zipped(t, "model.AMF"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (threeMF{}).Sniff(tt.head); got != tt.want {
+				t.Errorf("threeMF.Sniff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package ply
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"image/color"
+	"io"
+
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/importer"
+	"github.com/hpinc/go3mf/materials"
+)
+
+var checkEveryFaces = 1000
+
+var errIndexOutOfBounds = errors.New("ply: face references an unexisting vertex")
+
+func init() {
+	importer.Register("ply", format{})
+}
+
+// format implements the importer.Importer interface.
+type format struct{}
+
+func (format) Sniff(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("ply\n")) || bytes.HasPrefix(head, []byte("ply\r\n"))
+}
+
+func (format) DecodeContext(ctx context.Context, r io.Reader, m *go3mf.Model) error {
+	return NewDecoder(r).DecodeContext(ctx, m)
+}
+
+// Decoder can decode a PLY mesh.
+// It supports ASCII and binary, both little and big endian, encodings.
+//
+// Per-vertex and per-face colors are decoded as a materials.ColorGroup
+// referenced by the triangles of the resulting object.
+// If a file defines both, per-vertex colors take precedence.
+type Decoder struct {
+	r io.Reader
+}
+
+// NewDecoder creates a new decoder.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: r,
+	}
+}
+
+// Decode creates a mesh from a read stream.
+func (d *Decoder) Decode(m *go3mf.Model) error {
+	return d.DecodeContext(context.Background(), m)
+}
+
+// DecodeContext creates a mesh from a read stream.
+func (d *Decoder) DecodeContext(ctx context.Context, m *go3mf.Model) error {
+	b := bufio.NewReader(d.r)
+	h, err := readHeader(b)
+	if err != nil {
+		return err
+	}
+	dec := bodyDecoder{r: newValueReader(h.format, b), mesh: new(go3mf.Mesh)}
+	if err = dec.decode(ctx, h); err != nil {
+		return err
+	}
+	obj := &go3mf.Object{Mesh: dec.mesh}
+	if len(dec.colors) > 0 && len(dec.mesh.Triangles.Triangle) > 0 {
+		cg := &materials.ColorGroup{ID: m.Resources.UnusedID(), Colors: dec.colors}
+		m.Resources.Assets = append(m.Resources.Assets, cg)
+		obj.PID = cg.ID
+		obj.PIndex = dec.mesh.Triangles.Triangle[0].P1
+		for i := range dec.mesh.Triangles.Triangle {
+			dec.mesh.Triangles.Triangle[i].PID = cg.ID
+		}
+		importer.AddExtension(m, materials.DefaultExtension)
+	}
+	obj.ID = m.Resources.UnusedID()
+	m.Resources.Objects = append(m.Resources.Objects, obj)
+	m.Build.Items = append(m.Build.Items, &go3mf.Item{ObjectID: obj.ID})
+	return nil
+}
+
+type colorIndices struct {
+	r, g, b, a int
+}
+
+func newColorIndices(e *element) (c colorIndices, ok bool) {
+	c.r = e.propertyIndex("red", "diffuse_red", "r")
+	c.g = e.propertyIndex("green", "diffuse_green", "g")
+	c.b = e.propertyIndex("blue", "diffuse_blue", "b")
+	c.a = e.propertyIndex("alpha", "diffuse_alpha", "a")
+	return c, c.r >= 0 && c.g >= 0 && c.b >= 0
+}
+
+func (c colorIndices) color(e *element, values []float64) color.RGBA {
+	rgba := color.RGBA{
+		R: colorComponent(e.properties[c.r].typ, values[c.r]),
+		G: colorComponent(e.properties[c.g].typ, values[c.g]),
+		B: colorComponent(e.properties[c.b].typ, values[c.b]),
+		A: 255,
+	}
+	if c.a >= 0 {
+		rgba.A = colorComponent(e.properties[c.a].typ, values[c.a])
+	}
+	return rgba
+}
+
+func colorComponent(t scalarType, v float64) uint8 {
+	if t.isFloat() {
+		v *= 255
+	}
+	if v < 0 {
+		return 0
+	} else if v > 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// bodyDecoder fills a mesh with the elements of a PLY body.
+type bodyDecoder struct {
+	r            valueReader
+	mesh         *go3mf.Mesh
+	colors       []color.RGBA
+	colorIndex   map[color.RGBA]uint32
+	vertexColors []uint32
+}
+
+func (d *bodyDecoder) decode(ctx context.Context, h *header) error {
+	for i := range h.elements {
+		e := &h.elements[i]
+		var err error
+		switch e.name {
+		case "vertex":
+			err = d.decodeVertices(e)
+		case "face":
+			err = d.decodeFaces(ctx, e)
+		default:
+			err = d.skip(e)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *bodyDecoder) addColor(c color.RGBA) uint32 {
+	if d.colorIndex == nil {
+		d.colorIndex = make(map[color.RGBA]uint32)
+	}
+	if i, ok := d.colorIndex[c]; ok {
+		return i
+	}
+	i := uint32(len(d.colors))
+	d.colors = append(d.colors, c)
+	d.colorIndex[c] = i
+	return i
+}
+
+// readInstance reads one element instance. Scalar values are stored in values
+// and the items of the list properties in lists, both indexed by property.
+func (d *bodyDecoder) readInstance(e *element, values []float64, lists [][]uint32) error {
+	for i, p := range e.properties {
+		if !p.isList {
+			v, err := d.r.read(p.typ)
+			if err != nil {
+				return err
+			}
+			values[i] = v
+			continue
+		}
+		n, err := d.r.read(p.countType)
+		if err != nil {
+			return err
+		}
+		lists[i] = lists[i][:0]
+		for j := 0; j < int(n); j++ {
+			v, err := d.r.read(p.typ)
+			if err != nil {
+				return err
+			}
+			lists[i] = append(lists[i], uint32(v))
+		}
+	}
+	return nil
+}
+
+func (d *bodyDecoder) skip(e *element) error {
+	values := make([]float64, len(e.properties))
+	lists := make([][]uint32, len(e.properties))
+	for i := 0; i < e.count; i++ {
+		if err := d.readInstance(e, values, lists); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *bodyDecoder) decodeVertices(e *element) error {
+	x, y, z := e.propertyIndex("x"), e.propertyIndex("y"), e.propertyIndex("z")
+	if x < 0 || y < 0 || z < 0 {
+		return errors.New("ply: vertex element MUST define x, y and z properties")
+	}
+	ci, hasColor := newColorIndices(e)
+	values := make([]float64, len(e.properties))
+	lists := make([][]uint32, len(e.properties))
+	d.mesh.Vertices.Vertex = make([]go3mf.Point3D, 0, e.prealloc())
+	if hasColor {
+		d.vertexColors = make([]uint32, 0, e.prealloc())
+	}
+	for i := 0; i < e.count; i++ {
+		if err := d.readInstance(e, values, lists); err != nil {
+			return err
+		}
+		d.mesh.Vertices.Vertex = append(d.mesh.Vertices.Vertex, go3mf.Point3D{
+			float32(values[x]), float32(values[y]), float32(values[z]),
+		})
+		if hasColor {
+			d.vertexColors = append(d.vertexColors, d.addColor(ci.color(e, values)))
+		}
+	}
+	return nil
+}
+
+func (d *bodyDecoder) decodeFaces(ctx context.Context, e *element) error {
+	indices := e.propertyIndex("vertex_indices", "vertex_index")
+	if indices < 0 || !e.properties[indices].isList {
+		return errors.New("ply: face element MUST define a vertex_indices list property")
+	}
+	ci, hasColor := newColorIndices(e)
+	values := make([]float64, len(e.properties))
+	lists := make([][]uint32, len(e.properties))
+	d.mesh.Triangles.Triangle = make([]go3mf.Triangle, 0, e.prealloc())
+	nextFaceCheck := checkEveryFaces
+	vertexCount := uint32(len(d.mesh.Vertices.Vertex))
+	for i := 0; i < e.count; i++ {
+		if err := d.readInstance(e, values, lists); err != nil {
+			return err
+		}
+		var faceColor uint32
+		if hasColor && d.vertexColors == nil {
+			faceColor = d.addColor(ci.color(e, values))
+		}
+		poly := lists[indices]
+		for j := 2; j < len(poly); j++ {
+			t := go3mf.Triangle{V1: poly[0], V2: poly[j-1], V3: poly[j]}
+			if t.V1 >= vertexCount || t.V2 >= vertexCount || t.V3 >= vertexCount {
+				return errIndexOutOfBounds
+			}
+			if d.vertexColors != nil {
+				t.P1, t.P2, t.P3 = d.vertexColors[t.V1], d.vertexColors[t.V2], d.vertexColors[t.V3]
+			} else if hasColor {
+				t.P1, t.P2, t.P3 = faceColor, faceColor, faceColor
+			}
+			d.mesh.Triangles.Triangle = append(d.mesh.Triangles.Triangle, t)
+		}
+		if len(d.mesh.Triangles.Triangle) > nextFaceCheck {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default: // Default is must to avoid blocking
+			}
+			nextFaceCheck += checkEveryFaces
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package ply
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image/color"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/materials"
+)
+
+const asciiSquare = `ply
+format ascii 1.0
+element vertex 4
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+element face 1
+property list uchar int vertex_indices
+end_header
+0 0 0 255 0 0
+1 0 0 0 255 0
+1 1 0 255 0 0
+0 1 0 0 0 255
+4 0 1 2 3
+`
+
+const asciiFaceColors = `ply
+format ascii 1.0
+element vertex 3
+property float x
+property float y
+property float z
+element material 1
+property float shininess
+element face 2
+property list uchar int vertex_indices
+property float red
+property float green
+property float blue
+property float alpha
+end_header
+0 0 0
+1 0 0
+1 1 0
+0.5
+3 0 1 2 1 0 0 1
+3 2 1 0 0 0 1 0.5
+`
+
+func binarySquare(order binary.ByteOrder) []byte {
+	var b bytes.Buffer
+	format := "binary_little_endian"
+	if order == binary.BigEndian {
+		format = "binary_big_endian"
+	}
+	b.WriteString("ply\nformat " + format + " 1.0\nelement vertex 4\nproperty float x\nproperty float y\nproperty float z\n")
+	b.WriteString("property uchar red\nproperty uchar green\nproperty uchar blue\n")
+	b.WriteString("element face 1\nproperty list uchar int vertex_indices\nend_header\n")
+	vertices := []struct {
+		p   [3]float32
+		rgb [3]uint8
+	}{
+		{[3]float32{0, 0, 0}, [3]uint8{255, 0, 0}},
+		{[3]float32{1, 0, 0}, [3]uint8{0, 255, 0}},
+		{[3]float32{1, 1, 0}, [3]uint8{255, 0, 0}},
+		{[3]float32{0, 1, 0}, [3]uint8{0, 0, 255}},
+	}
+	for _, v := range vertices {
+		binary.Write(&b, order, v.p)
+		b.Write(v.rgb[:])
+	}
+	b.WriteByte(4)
+	binary.Write(&b, order, [4]int32{0, 1, 2, 3})
+	return b.Bytes()
+}
+
+func squareModel() *go3mf.Model {
+	return &go3mf.Model{
+		Extensions: []go3mf.Extension{materials.DefaultExtension},
+		Resources: go3mf.Resources{
+			Assets: []go3mf.Asset{&materials.ColorGroup{ID: 1, Colors: []color.RGBA{
+				{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255},
+			}}},
+			Objects: []*go3mf.Object{{ID: 2, PID: 1, Mesh: &go3mf.Mesh{
+				Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}},
+				Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+					{V1: 0, V2: 1, V3: 2, PID: 1, P1: 0, P2: 1, P3: 0},
+					{V1: 0, V2: 2, V3: 3, PID: 1, P1: 0, P2: 0, P3: 2},
+				}},
+			}}},
+		},
+		Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 2}}},
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	type args struct {
+		r io.Reader
+	}
+	tests := []struct {
+		name string
+		args args
+		want *Decoder
+	}{
+		{"base", args{new(bytes.Buffer)}, &Decoder{r: new(bytes.Buffer)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewDecoder(tt.args.r); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewDecoder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	faceColors := &go3mf.Model{
+		Extensions: []go3mf.Extension{materials.DefaultExtension},
+		Resources: go3mf.Resources{
+			Assets: []go3mf.Asset{&materials.ColorGroup{ID: 1, Colors: []color.RGBA{
+				{255, 0, 0, 255}, {0, 0, 255, 128},
+			}}},
+			Objects: []*go3mf.Object{{ID: 2, PID: 1, Mesh: &go3mf.Mesh{
+				Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}}},
+				Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+					{V1: 0, V2: 1, V3: 2, PID: 1, P1: 0, P2: 0, P3: 0},
+					{V1: 2, V2: 1, V3: 0, PID: 1, P1: 1, P2: 1, P3: 1},
+				}},
+			}}},
+		},
+		Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 2}}},
+	}
+	noColors := &go3mf.Model{
+		Resources: go3mf.Resources{Objects: []*go3mf.Object{{ID: 1, Mesh: &go3mf.Mesh{
+			Vertices:  go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}}},
+			Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{{V1: 0, V2: 1, V3: 2}}},
+		}}}},
+		Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 1}}},
+	}
+	tests := []struct {
+		name    string
+		d       *Decoder
+		want    *go3mf.Model
+		wantErr bool
+	}{
+		{"empty", NewDecoder(new(bytes.Buffer)), nil, true},
+		{"ascii", NewDecoder(bytes.NewBufferString(asciiSquare)), squareModel(), false},
+		{"binaryLittleEndian", NewDecoder(bytes.NewReader(binarySquare(binary.LittleEndian))), squareModel(), false},
+		{"binaryBigEndian", NewDecoder(bytes.NewReader(binarySquare(binary.BigEndian))), squareModel(), false},
+		{"faceColors", NewDecoder(bytes.NewBufferString(asciiFaceColors)), faceColors, false},
+		{"noColors", NewDecoder(bytes.NewBufferString("ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\nproperty float y\nproperty float z\nelement face 1\nproperty list uchar int vertex_index\nend_header\n0 0 0\n1 0 0\n1 1 0\n3 0 1 2\n")), noColors, false},
+		{"truncated", NewDecoder(bytes.NewReader(binarySquare(binary.LittleEndian)[:250])), nil, true},
+		{"largeCount", NewDecoder(bytes.NewBufferString("ply\nformat ascii 1.0\nelement vertex 2000000000\nproperty float x\nproperty float y\nproperty float z\nelement face 2000000000\nproperty list uchar int vertex_index\nend_header\n0 0 0\n")), nil, true},
+		{"noXYZ", NewDecoder(bytes.NewBufferString("ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nend_header\n0\n")), nil, true},
+		{"noIndices", NewDecoder(bytes.NewBufferString("ply\nformat ascii 1.0\nelement face 1\nproperty int a\nend_header\n0\n")), nil, true},
+		{"outOfBounds", NewDecoder(bytes.NewBufferString("ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nproperty float y\nproperty float z\nelement face 1\nproperty list uchar int vertex_indices\nend_header\n0 0 0\n3 0 1 2\n")), nil, true},
+		{"invalidValue", NewDecoder(bytes.NewBufferString("ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nproperty float y\nproperty float z\nend_header\n0 a 0\n")), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := new(go3mf.Model)
+			err := tt.d.Decode(got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Decoder.Decode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				if diff := deep.Equal(got, tt.want); diff != nil {
+					t.Errorf("Decoder.Decode() = %v", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoder_DecodeContext_Cancel(t *testing.T) {
+	checkEveryFaces = 0
+	defer func() { checkEveryFaces = 1000 }()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := NewDecoder(bytes.NewBufferString(asciiSquare)).DecodeContext(ctx, new(go3mf.Model))
+	if err != context.Canceled {
+		t.Errorf("Decoder.DecodeContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func Test_colorComponent(t *testing.T) {
+	tests := []struct {
+		name string
+		t    scalarType
+		v    float64
+		want uint8
+	}{
+		{"uchar", typeUint8, 200, 200},
+		{"float", typeFloat32, 0.5, 128},
+		{"negative", typeFloat64, -1, 0},
+		{"overflow", typeUint16, math.MaxUint16, 255},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := colorComponent(tt.t, tt.v); got != tt.want {
+				t.Errorf("colorComponent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_format_Sniff(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want bool
+	}{
+		{"empty", "", false},
+		{"lf", "ply\nformat ascii 1.0\n", true},
+		{"crlf", "ply\r\nformat ascii 1.0\r\n", true},
+		{"prefix", "plywood", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (format{}).Sniff([]byte(tt.head)); got != tt.want {
+				t.Errorf("format.Sniff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
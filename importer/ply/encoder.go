@@ -0,0 +1,330 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package ply
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/materials"
+)
+
+// An Encoder writes the build of a Model as a single PLY mesh.
+//
+// Every build item is flattened into world coordinates, applying
+// item and component transforms. Triangle properties are resolved
+// to per-vertex colors when they reference go3mf.BaseMaterials,
+// materials.ColorGroup, materials.CompositeMaterials or
+// materials.MultiProperties combining them. Other properties,
+// such as texture coordinates, are not resolved.
+type Encoder struct {
+	Format Format
+	w      io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w
+// using the binary little endian format.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		Format: FormatBinaryLittleEndian,
+		w:      w,
+	}
+}
+
+// Encode writes the PLY encoding of m to the stream.
+func (e *Encoder) Encode(m *go3mf.Model) error {
+	var fm flatMesh
+	fm.flatten(m)
+	w := bufio.NewWriter(e.w)
+	if err := fm.writeHeader(w, e.Format); err != nil {
+		return err
+	}
+	var err error
+	if e.Format == FormatASCII {
+		err = fm.writeASCII(w)
+	} else {
+		var order binary.ByteOrder = binary.LittleEndian
+		if e.Format == FormatBinaryBigEndian {
+			order = binary.BigEndian
+		}
+		err = fm.writeBinary(w, order)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+type flatVertex struct {
+	index uint32
+	color color.RGBA
+}
+
+// flatMesh is a world space mesh where every vertex has a single color.
+type flatMesh struct {
+	vertices  []go3mf.Point3D
+	colors    []color.RGBA
+	faces     [][3]uint32
+	hasColors bool
+}
+
+func (fm *flatMesh) flatten(m *go3mf.Model) {
+	for _, item := range m.Build.Items {
+		if o, ok := m.FindObject(item.ObjectPath(), item.ObjectID); ok {
+			fm.addObject(m, item.ObjectPath(), o, transform(item.Transform), nil)
+		}
+	}
+}
+
+func (fm *flatMesh) addObject(m *go3mf.Model, path string, o *go3mf.Object, t go3mf.Matrix, visiting []*go3mf.Object) {
+	for _, v := range visiting {
+		if v == o {
+			return // recursive reference
+		}
+	}
+	if o.Mesh != nil {
+		fm.addMesh(m, path, o, t)
+		return
+	}
+	if o.Components == nil {
+		return
+	}
+	visiting = append(visiting, o)
+	for _, c := range o.Components.Component {
+		cpath := c.ObjectPath(path)
+		if obj, ok := m.FindObject(cpath, c.ObjectID); ok {
+			fm.addObject(m, cpath, obj, t.Mul(transform(c.Transform)), visiting)
+		}
+	}
+}
+
+func (fm *flatMesh) addMesh(m *go3mf.Model, path string, o *go3mf.Object, t go3mf.Matrix) {
+	r := resolver{m: m, path: path}
+	vertices := make(map[flatVertex]uint32)
+	nodeCount := uint32(len(o.Mesh.Vertices.Vertex))
+	for _, tr := range o.Mesh.Triangles.Triangle {
+		if tr.V1 >= nodeCount || tr.V2 >= nodeCount || tr.V3 >= nodeCount {
+			continue
+		}
+		pid, p1, p2, p3 := tr.PID, tr.P1, tr.P2, tr.P3
+		if pid == 0 {
+			pid, p1, p2, p3 = o.PID, o.PIndex, o.PIndex, o.PIndex
+		}
+		var face [3]uint32
+		for j, v := range [3]uint32{tr.V1, tr.V2, tr.V3} {
+			fv := flatVertex{index: v}
+			if c, ok := r.color(pid, [3]uint32{p1, p2, p3}[j]); ok {
+				fv.color = c
+				fm.hasColors = true
+			}
+			index, ok := vertices[fv]
+			if !ok {
+				index = uint32(len(fm.vertices))
+				fm.vertices = append(fm.vertices, t.Mul3D(o.Mesh.Vertices.Vertex[v]))
+				fm.colors = append(fm.colors, fv.color)
+				vertices[fv] = index
+			}
+			face[j] = index
+		}
+		fm.faces = append(fm.faces, face)
+	}
+}
+
+func (fm *flatMesh) writeHeader(w *bufio.Writer, f Format) error {
+	fmt.Fprintf(w, "ply\nformat %s 1.0\ncomment generated by go3mf\n", f)
+	fmt.Fprintf(w, "element vertex %d\nproperty float x\nproperty float y\nproperty float z\n", len(fm.vertices))
+	if fm.hasColors {
+		w.WriteString("property uchar red\nproperty uchar green\nproperty uchar blue\nproperty uchar alpha\n")
+	}
+	fmt.Fprintf(w, "element face %d\nproperty list uchar uint vertex_indices\nend_header\n", len(fm.faces))
+	return w.Flush()
+}
+
+func (fm *flatMesh) writeASCII(w *bufio.Writer) error {
+	for i, v := range fm.vertices {
+		w.WriteString(strconv.FormatFloat(float64(v.X()), 'g', -1, 32))
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(float64(v.Y()), 'g', -1, 32))
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(float64(v.Z()), 'g', -1, 32))
+		if fm.hasColors {
+			c := fm.colors[i]
+			fmt.Fprintf(w, " %d %d %d %d", c.R, c.G, c.B, c.A)
+		}
+		w.WriteByte('\n')
+	}
+	for _, f := range fm.faces {
+		if _, err := fmt.Fprintf(w, "3 %d %d %d\n", f[0], f[1], f[2]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fm *flatMesh) writeBinary(w *bufio.Writer, order binary.ByteOrder) error {
+	var buf [16]byte
+	for i, v := range fm.vertices {
+		order.PutUint32(buf[0:], math.Float32bits(v.X()))
+		order.PutUint32(buf[4:], math.Float32bits(v.Y()))
+		order.PutUint32(buf[8:], math.Float32bits(v.Z()))
+		n := 12
+		if fm.hasColors {
+			c := fm.colors[i]
+			buf[12], buf[13], buf[14], buf[15] = c.R, c.G, c.B, c.A
+			n = 16
+		}
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+	}
+	buf[0] = 3
+	for _, f := range fm.faces {
+		order.PutUint32(buf[1:], f[0])
+		order.PutUint32(buf[5:], f[1])
+		order.PutUint32(buf[9:], f[2])
+		if _, err := w.Write(buf[:13]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transform returns t, or the identity if t is not set.
+func transform(t go3mf.Matrix) go3mf.Matrix {
+	if t == (go3mf.Matrix{}) {
+		return go3mf.Identity()
+	}
+	return t
+}
+
+// resolver computes the color of a property.
+//
+// As allowed by the materials spec, composites only mix base materials
+// and multiproperties do not nest, so reference cycles resolve to no color.
+type resolver struct {
+	m    *go3mf.Model
+	path string
+}
+
+func (r *resolver) color(pid, index uint32) (color.RGBA, bool) {
+	if pid == 0 {
+		return color.RGBA{}, false
+	}
+	a, ok := r.m.FindAsset(r.path, pid)
+	if !ok {
+		return color.RGBA{}, false
+	}
+	return r.assetColor(a, index)
+}
+
+func (r *resolver) assetColor(a go3mf.Asset, index uint32) (color.RGBA, bool) {
+	switch a := a.(type) {
+	case *go3mf.BaseMaterials:
+		if int(index) < len(a.Materials) {
+			return a.Materials[index].Color, true
+		}
+	case *materials.ColorGroup:
+		if int(index) < len(a.Colors) {
+			return a.Colors[index], true
+		}
+	case *materials.CompositeMaterials:
+		if int(index) < len(a.Composites) {
+			return r.composite(a, a.Composites[index])
+		}
+	case *materials.MultiProperties:
+		if int(index) < len(a.Multis) {
+			return r.multi(a, a.Multis[index])
+		}
+	}
+	return color.RGBA{}, false
+}
+
+func (r *resolver) composite(cm *materials.CompositeMaterials, c materials.Composite) (color.RGBA, bool) {
+	a, _ := r.m.FindAsset(r.path, cm.MaterialID)
+	base, ok := a.(*go3mf.BaseMaterials)
+	if !ok {
+		return color.RGBA{}, false
+	}
+	var (
+		rgba  [4]float32
+		total float32
+	)
+	for i, v := range c.Values {
+		if i >= len(cm.Indices) {
+			break
+		}
+		if int(cm.Indices[i]) >= len(base.Materials) {
+			return color.RGBA{}, false
+		}
+		bc := base.Materials[cm.Indices[i]].Color
+		rgba[0] += float32(bc.R) * v
+		rgba[1] += float32(bc.G) * v
+		rgba[2] += float32(bc.B) * v
+		rgba[3] += float32(bc.A) * v
+		total += v
+	}
+	if total == 0 {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{
+		R: uint8(rgba[0]/total + 0.5), G: uint8(rgba[1]/total + 0.5),
+		B: uint8(rgba[2]/total + 0.5), A: uint8(rgba[3]/total + 0.5),
+	}, true
+}
+
+func (r *resolver) multi(mp *materials.MultiProperties, multi materials.Multi) (color.RGBA, bool) {
+	var (
+		result   color.RGBA
+		resolved bool
+	)
+	for i, pindex := range multi.PIndices {
+		if i >= len(mp.PIDs) {
+			break
+		}
+		a, ok := r.m.FindAsset(r.path, mp.PIDs[i])
+		if !ok {
+			continue
+		}
+		if _, nested := a.(*materials.MultiProperties); nested {
+			continue
+		}
+		c, ok := r.assetColor(a, pindex)
+		if !ok {
+			continue
+		}
+		if !resolved {
+			result, resolved = c, true
+			continue
+		}
+		method := materials.BlendMix
+		if i-1 < len(mp.BlendMethods) {
+			method = mp.BlendMethods[i-1]
+		}
+		result = blend(result, c, method)
+	}
+	return result, resolved
+}
+
+// blend composes src over dst as defined in the materials spec.
+func blend(dst, src color.RGBA, method materials.BlendMethod) color.RGBA {
+	if method == materials.BlendMultiply {
+		return color.RGBA{
+			R: uint8(uint16(dst.R) * uint16(src.R) / 255),
+			G: uint8(uint16(dst.G) * uint16(src.G) / 255),
+			B: uint8(uint16(dst.B) * uint16(src.B) / 255),
+			A: dst.A,
+		}
+	}
+	a := float32(src.A) / 255
+	mix := func(d, s uint8) uint8 {
+		return uint8(float32(d)*(1-a) + float32(s)*a + 0.5)
+	}
+	return color.RGBA{R: mix(dst.R, src.R), G: mix(dst.G, src.G), B: mix(dst.B, src.B), A: dst.A}
+}
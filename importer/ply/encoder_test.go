@@ -0,0 +1,159 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package ply
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/materials"
+)
+
+func TestEncoder_Encode_Roundtrip(t *testing.T) {
+	for _, f := range []Format{FormatASCII, FormatBinaryLittleEndian, FormatBinaryBigEndian} {
+		t.Run(f.String(), func(t *testing.T) {
+			var b bytes.Buffer
+			enc := NewEncoder(&b)
+			enc.Format = f
+			if err := enc.Encode(squareModel()); err != nil {
+				t.Fatalf("Encoder.Encode() error = %v", err)
+			}
+			got := new(go3mf.Model)
+			if err := NewDecoder(&b).Decode(got); err != nil {
+				t.Fatalf("Decoder.Decode() error = %v", err)
+			}
+			cg := got.Resources.Assets[0].(*materials.ColorGroup)
+			mesh := got.Resources.Objects[0].Mesh
+			if len(mesh.Vertices.Vertex) != 4 || len(mesh.Triangles.Triangle) != 2 {
+				t.Fatalf("Encoder.Encode() got %d vertices and %d triangles", len(mesh.Vertices.Vertex), len(mesh.Triangles.Triangle))
+			}
+			want := [][3]color.RGBA{
+				{{255, 0, 0, 255}, {0, 255, 0, 255}, {255, 0, 0, 255}},
+				{{255, 0, 0, 255}, {255, 0, 0, 255}, {0, 0, 255, 255}},
+			}
+			for i, tr := range mesh.Triangles.Triangle {
+				gotColors := [3]color.RGBA{cg.Colors[tr.P1], cg.Colors[tr.P2], cg.Colors[tr.P3]}
+				if gotColors != want[i] {
+					t.Errorf("Encoder.Encode() triangle %d colors = %v, want %v", i, gotColors, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEncoder_Encode_Flatten(t *testing.T) {
+	m := &go3mf.Model{
+		Resources: go3mf.Resources{
+			Assets: []go3mf.Asset{&go3mf.BaseMaterials{ID: 1, Materials: []go3mf.Base{
+				{Name: "a", Color: color.RGBA{0, 0, 200, 255}},
+				{Name: "b", Color: color.RGBA{200, 0, 0, 255}},
+			}}, &materials.CompositeMaterials{ID: 2, MaterialID: 1, Indices: []uint32{0, 1}, Composites: []materials.Composite{
+				{Values: []float32{0.5, 0.5}},
+			}}},
+			Objects: []*go3mf.Object{
+				{ID: 3, PID: 2, Mesh: &go3mf.Mesh{
+					Vertices:  go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}}},
+					Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 1, V3: 5}}},
+				}},
+				{ID: 4, Components: &go3mf.Components{Component: []*go3mf.Component{
+					{ObjectID: 3, Transform: go3mf.Identity().Translate(10, 0, 0)},
+					{ObjectID: 4},
+				}}},
+			},
+		},
+		Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 4, Transform: go3mf.Identity().Translate(0, 0, 5)}, {ObjectID: 100}}},
+	}
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	enc.Format = FormatASCII
+	if err := enc.Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	want := `ply
+format ascii 1.0
+comment generated by go3mf
+element vertex 3
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+property uchar alpha
+element face 1
+property list uchar uint vertex_indices
+end_header
+10 0 5 100 0 100 255
+11 0 5 100 0 100 255
+11 1 5 100 0 100 255
+3 0 1 2
+`
+	if diff := deep.Equal(b.String(), want); diff != nil {
+		t.Errorf("Encoder.Encode() = %v", diff)
+	}
+}
+
+func Test_blend(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst, src color.RGBA
+		method   materials.BlendMethod
+		want     color.RGBA
+	}{
+		{"mixOpaque", color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}, materials.BlendMix, color.RGBA{0, 255, 0, 255}},
+		{"mixTransparent", color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 0}, materials.BlendMix, color.RGBA{255, 0, 0, 255}},
+		{"multiply", color.RGBA{255, 128, 0, 255}, color.RGBA{255, 255, 255, 255}, materials.BlendMultiply, color.RGBA{255, 128, 0, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blend(tt.dst, tt.src, tt.method); got != tt.want {
+				t.Errorf("blend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolver_multi(t *testing.T) {
+	m := &go3mf.Model{Resources: go3mf.Resources{Assets: []go3mf.Asset{
+		&go3mf.BaseMaterials{ID: 1, Materials: []go3mf.Base{{Name: "a", Color: color.RGBA{255, 255, 255, 255}}}},
+		&materials.ColorGroup{ID: 2, Colors: []color.RGBA{{255, 0, 0, 255}}},
+		&materials.MultiProperties{ID: 3, PIDs: []uint32{1, 2}, BlendMethods: []materials.BlendMethod{materials.BlendMultiply}, Multis: []materials.Multi{
+			{PIndices: []uint32{0, 0}},
+		}},
+	}}}
+	r := resolver{m: m}
+	got, ok := r.color(3, 0)
+	if want := (color.RGBA{255, 0, 0, 255}); !ok || got != want {
+		t.Errorf("resolver.color() = %v, want %v", got, want)
+	}
+	if _, ok := r.color(3, 1); ok {
+		t.Error("resolver.color() expected out of bounds")
+	}
+}
+
+func Test_resolver_cycle(t *testing.T) {
+	m := &go3mf.Model{Resources: go3mf.Resources{
+		Assets: []go3mf.Asset{
+			&materials.MultiProperties{ID: 1, PIDs: []uint32{1}, Multis: []materials.Multi{{PIndices: []uint32{0}}}},
+			&materials.CompositeMaterials{ID: 2, MaterialID: 3, Indices: []uint32{0}, Composites: []materials.Composite{{Values: []float32{1}}}},
+			&materials.MultiProperties{ID: 3, PIDs: []uint32{2}, Multis: []materials.Multi{{PIndices: []uint32{0}}}},
+		},
+		Objects: []*go3mf.Object{{ID: 4, PID: 1, Mesh: &go3mf.Mesh{
+			Vertices:  go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}}},
+			Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{{V1: 0, V2: 1, V3: 2}}},
+		}}},
+	}, Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 4}}}}
+	r := resolver{m: m}
+	for _, pid := range []uint32{1, 2, 3} {
+		if got, ok := r.color(pid, 0); ok {
+			t.Errorf("resolver.color(%d) = %v, want no color", pid, got)
+		}
+	}
+	if err := NewEncoder(new(bytes.Buffer)).Encode(m); err != nil {
+		t.Errorf("Encoder.Encode() error = %v", err)
+	}
+}
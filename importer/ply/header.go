@@ -0,0 +1,233 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package ply
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format defines the allowed PLY body encodings.
+type Format uint8
+
+// Supported formats.
+const (
+	FormatASCII Format = iota
+	FormatBinaryLittleEndian
+	FormatBinaryBigEndian
+)
+
+func (f Format) String() string {
+	return map[Format]string{
+		FormatASCII:              "ascii",
+		FormatBinaryLittleEndian: "binary_little_endian",
+		FormatBinaryBigEndian:    "binary_big_endian",
+	}[f]
+}
+
+func newFormat(s string) (f Format, ok bool) {
+	f, ok = map[string]Format{
+		"ascii":                FormatASCII,
+		"binary_little_endian": FormatBinaryLittleEndian,
+		"binary_big_endian":    FormatBinaryBigEndian,
+	}[s]
+	return
+}
+
+// scalarType defines the PLY scalar data types.
+type scalarType uint8
+
+const (
+	typeInvalid scalarType = iota
+	typeInt8
+	typeUint8
+	typeInt16
+	typeUint16
+	typeInt32
+	typeUint32
+	typeFloat32
+	typeFloat64
+)
+
+func newScalarType(s string) (t scalarType, ok bool) {
+	t, ok = map[string]scalarType{
+		"char": typeInt8, "int8": typeInt8,
+		"uchar": typeUint8, "uint8": typeUint8,
+		"short": typeInt16, "int16": typeInt16,
+		"ushort": typeUint16, "uint16": typeUint16,
+		"int": typeInt32, "int32": typeInt32,
+		"uint": typeUint32, "uint32": typeUint32,
+		"float": typeFloat32, "float32": typeFloat32,
+		"double": typeFloat64, "float64": typeFloat64,
+	}[s]
+	return
+}
+
+// size returns the binary size in bytes.
+func (t scalarType) size() int {
+	switch t {
+	case typeInt8, typeUint8:
+		return 1
+	case typeInt16, typeUint16:
+		return 2
+	case typeInt32, typeUint32, typeFloat32:
+		return 4
+	case typeFloat64:
+		return 8
+	}
+	return 0
+}
+
+func (t scalarType) isFloat() bool {
+	return t == typeFloat32 || t == typeFloat64
+}
+
+// property defines a scalar or a list property of an element.
+type property struct {
+	name      string
+	typ       scalarType
+	isList    bool
+	countType scalarType // only for lists
+}
+
+// element defines a group of properties that is repeated count times.
+type element struct {
+	name       string
+	count      int
+	properties []property
+}
+
+// maxPrealloc bounds the instances preallocated from the header count,
+// so a small file cannot force a large allocation.
+const maxPrealloc = 1 << 16
+
+// prealloc returns the capacity to preallocate for the instances of e.
+func (e *element) prealloc() int {
+	if e.count > maxPrealloc {
+		return maxPrealloc
+	}
+	return e.count
+}
+
+func (e *element) propertyIndex(names ...string) int {
+	for _, name := range names {
+		for i, p := range e.properties {
+			if p.name == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+type header struct {
+	format   Format
+	elements []element
+}
+
+func (h *header) element(name string) *element {
+	for i := range h.elements {
+		if h.elements[i].name == name {
+			return &h.elements[i]
+		}
+	}
+	return nil
+}
+
+var (
+	errMagic  = errors.New("ply: invalid magic number")
+	errFormat = errors.New("ply: missing or invalid format")
+)
+
+func parseHeaderError(line int, msg string) error {
+	return fmt.Errorf("ply: header line %d: %s", line, msg)
+}
+
+// readHeader reads the header up to and including the end_header line,
+// leaving r positioned at the first byte of the body.
+func readHeader(r *bufio.Reader) (*header, error) {
+	var (
+		h         header
+		hasFormat bool
+	)
+	for n := 1; ; n++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if n == 1 {
+				return nil, err
+			}
+			return nil, parseHeaderError(n, "unexpected end of header")
+		}
+		fields := strings.Fields(line)
+		if n == 1 {
+			if len(fields) != 1 || fields[0] != "ply" {
+				return nil, errMagic
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "format":
+			if len(fields) != 3 {
+				return nil, errFormat
+			}
+			var ok bool
+			if h.format, ok = newFormat(fields[1]); !ok {
+				return nil, errFormat
+			}
+			hasFormat = true
+		case "comment", "obj_info":
+		case "element":
+			if len(fields) != 3 {
+				return nil, parseHeaderError(n, "invalid element")
+			}
+			// Vertex indices are 32 bits, so larger counts cannot be decoded anyway.
+			count, err := strconv.ParseInt(fields[2], 10, 32)
+			if err != nil || count < 0 {
+				return nil, parseHeaderError(n, "invalid element count")
+			}
+			h.elements = append(h.elements, element{name: fields[1], count: int(count)})
+		case "property":
+			if len(h.elements) == 0 {
+				return nil, parseHeaderError(n, "property without element")
+			}
+			p, ok := parseProperty(fields[1:])
+			if !ok {
+				return nil, parseHeaderError(n, "invalid property")
+			}
+			e := &h.elements[len(h.elements)-1]
+			e.properties = append(e.properties, p)
+		case "end_header":
+			if !hasFormat {
+				return nil, errFormat
+			}
+			return &h, nil
+		default:
+			return nil, parseHeaderError(n, fmt.Sprintf("unknown keyword '%s'", fields[0]))
+		}
+	}
+}
+
+func parseProperty(fields []string) (p property, ok bool) {
+	if len(fields) == 4 && fields[0] == "list" {
+		p.isList = true
+		if p.countType, ok = newScalarType(fields[1]); !ok || p.countType.isFloat() {
+			return p, false
+		}
+		p.typ, ok = newScalarType(fields[2])
+		p.name = fields[3]
+		return
+	}
+	if len(fields) != 2 {
+		return p, false
+	}
+	p.typ, ok = newScalarType(fields[0])
+	p.name = fields[1]
+	return
+}
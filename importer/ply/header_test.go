@@ -0,0 +1,77 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package ply
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_readHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    *header
+		wantErr bool
+	}{
+		{"empty", "", nil, true},
+		{"magic", "plx\nformat ascii 1.0\nend_header\n", nil, true},
+		{"noFormat", "ply\nend_header\n", nil, true},
+		{"invalidFormat", "ply\nformat other 1.0\nend_header\n", nil, true},
+		{"noEnd", "ply\nformat ascii 1.0\n", nil, true},
+		{"propertyNoElement", "ply\nformat ascii 1.0\nproperty float x\nend_header\n", nil, true},
+		{"invalidProperty", "ply\nformat ascii 1.0\nelement vertex 1\nproperty half x\nend_header\n", nil, true},
+		{"invalidList", "ply\nformat ascii 1.0\nelement face 1\nproperty list float int vertex_indices\nend_header\n", nil, true},
+		{"invalidCount", "ply\nformat ascii 1.0\nelement vertex -1\nend_header\n", nil, true},
+		{"hugeCount", "ply\nformat ascii 1.0\nelement vertex 99999999999999\nend_header\n", nil, true},
+		{"unknown", "ply\nformat ascii 1.0\nother\nend_header\n", nil, true},
+		{"base", `ply
+format binary_big_endian 1.0
+comment made by hand
+obj_info test
+element vertex 2
+property float x
+property double y
+property uchar red
+element face 1
+property list uchar int vertex_indices
+end_header
+`, &header{format: FormatBinaryBigEndian, elements: []element{
+			{name: "vertex", count: 2, properties: []property{{name: "x", typ: typeFloat32}, {name: "y", typ: typeFloat64}, {name: "red", typ: typeUint8}}},
+			{name: "face", count: 1, properties: []property{{name: "vertex_indices", typ: typeInt32, isList: true, countType: typeUint8}}},
+		}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readHeader(bufio.NewReader(strings.NewReader(tt.s)))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readHeader() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat_String(t *testing.T) {
+	tests := []struct {
+		f    Format
+		want string
+	}{
+		{FormatASCII, "ascii"},
+		{FormatBinaryLittleEndian, "binary_little_endian"},
+		{FormatBinaryBigEndian, "binary_big_endian"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.f.String(); got != tt.want {
+				t.Errorf("Format.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
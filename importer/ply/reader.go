@@ -0,0 +1,86 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package ply
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+)
+
+// valueReader reads scalar values from a PLY body.
+type valueReader interface {
+	read(t scalarType) (float64, error)
+}
+
+// asciiReader reads whitespace separated values.
+type asciiReader struct {
+	s *bufio.Scanner
+}
+
+func newASCIIReader(r io.Reader) *asciiReader {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanWords)
+	return &asciiReader{s: s}
+}
+
+func (r *asciiReader) read(t scalarType) (float64, error) {
+	if !r.s.Scan() {
+		if err := r.s.Err(); err != nil {
+			return 0, err
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	if t.isFloat() {
+		return strconv.ParseFloat(r.s.Text(), 64)
+	}
+	v, err := strconv.ParseInt(r.s.Text(), 10, 64)
+	return float64(v), err
+}
+
+// binaryReader reads fixed size values with the given byte order.
+type binaryReader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	buf   [8]byte
+}
+
+func (r *binaryReader) read(t scalarType) (float64, error) {
+	b := r.buf[:t.size()]
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	switch t {
+	case typeInt8:
+		return float64(int8(b[0])), nil
+	case typeUint8:
+		return float64(b[0]), nil
+	case typeInt16:
+		return float64(int16(r.order.Uint16(b))), nil
+	case typeUint16:
+		return float64(r.order.Uint16(b)), nil
+	case typeInt32:
+		return float64(int32(r.order.Uint32(b))), nil
+	case typeUint32:
+		return float64(r.order.Uint32(b)), nil
+	case typeFloat32:
+		return float64(math.Float32frombits(r.order.Uint32(b))), nil
+	}
+	return math.Float64frombits(r.order.Uint64(b)), nil
+}
+
+func newValueReader(f Format, r *bufio.Reader) valueReader {
+	switch f {
+	case FormatBinaryLittleEndian:
+		return &binaryReader{r: r, order: binary.LittleEndian}
+	case FormatBinaryBigEndian:
+		return &binaryReader{r: r, order: binary.BigEndian}
+	}
+	return newASCIIReader(r)
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/hpinc/go3mf"
 )
 
+// maxPrealloc bounds the triangles preallocated from the header count.
+const maxPrealloc = 1 << 16
+
 type binaryHeader struct {
 	_         [80]byte
 	FaceCount uint32
@@ -35,7 +38,12 @@ func (d *binaryDecoder) decode(ctx context.Context, m *go3mf.Mesh) error {
 	if err != nil {
 		return err
 	}
-	mb.Mesh.Triangles.Triangle = make([]go3mf.Triangle, 0, header.FaceCount)
+	// The count is not trusted to preallocate, as a small file can declare any count.
+	prealloc := header.FaceCount
+	if prealloc > maxPrealloc {
+		prealloc = maxPrealloc
+	}
+	mb.Mesh.Triangles.Triangle = make([]go3mf.Triangle, 0, prealloc)
 	nextFaceCheck := checkEveryFaces
 	var facet binaryFace
 	for nFace := 0; nFace < int(header.FaceCount); nFace++ {
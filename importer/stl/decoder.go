@@ -6,17 +6,72 @@ package stl
 import (
 	"bufio"
 	"context"
+	"encoding/binary"
 	"io"
+	"math"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/importer"
 )
 
 var checkEveryFaces = 1000
 
 const sizeOfHeader = 300 // minimum size of a closed mesh in binary is 384 bytes, corresponding to a triangle.
 
+const (
+	sizeOfBinaryHeader = 84 // 80 bytes of header followed by the triangle count.
+	sizeOfBinaryFace   = 50
+)
+
+func init() {
+	importer.Register("stl", format{})
+}
+
+// format implements the importer.FallbackImporter interface.
+// Binary stl has no distinctive header, so it is only sniffed as a fallback.
+type format struct{}
+
+func (format) Sniff(head []byte) bool {
+	s := strings.ToLower(string(head))
+	return strings.HasPrefix(s, "solid") && isASCII(s)
+}
+
+// SniffFallback reports whether head looks like a binary stl.
+// If size is known it must match the triangle count of the header,
+// otherwise the triangles in head must have finite coordinates and unit or zero normals.
+func (format) SniffFallback(head []byte, size int64) bool {
+	if len(head) < sizeOfBinaryHeader {
+		return false
+	}
+	count := int64(binary.LittleEndian.Uint32(head[80:sizeOfBinaryHeader]))
+	if size >= 0 {
+		return size == sizeOfBinaryHeader+count*sizeOfBinaryFace
+	}
+	faces := head[sizeOfBinaryHeader:]
+	if count == 0 || len(faces) < sizeOfBinaryFace {
+		return false
+	}
+	for n := int64(0); n < count && (n+1)*sizeOfBinaryFace <= int64(len(faces)); n++ {
+		var v [12]float64
+		for i := range v {
+			v[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(faces[n*sizeOfBinaryFace+4*int64(i):])))
+			if math.IsNaN(v[i]) || math.IsInf(v[i], 0) {
+				return false
+			}
+		}
+		if l := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2]); l != 0 && (l < 0.9 || l > 1.1) {
+			return false
+		}
+	}
+	return true
+}
+
+func (format) DecodeContext(ctx context.Context, r io.Reader, m *go3mf.Model) error {
+	return NewDecoder(r).DecodeContext(ctx, m)
+}
+
 // Decoder can decode a stl.
 // It supports automatic detection of binary or ascii stl encoding.
 type Decoder struct {
@@ -59,17 +114,15 @@ func (d *Decoder) DecodeContext(ctx context.Context, m *go3mf.Model) error {
 }
 
 func (d *Decoder) isASCII(r *bufio.Reader) (bool, error) {
-	var header string
-	for {
-		buff, err := r.Peek(sizeOfHeader)
-		if err == io.EOF {
-			return false, err
-		}
-		if len(buff) >= sizeOfHeader {
-			header = strings.ToLower(string(buff))
-			break
+	// Binary files of a single triangle are shorter than sizeOfHeader.
+	buff, err := r.Peek(sizeOfHeader)
+	if len(buff) == 0 || (err != nil && err != io.EOF) {
+		if err == nil {
+			err = io.EOF
 		}
+		return false, err
 	}
+	header := strings.ToLower(string(buff))
 	return strings.HasPrefix(header, "solid") && isASCII(header), nil
 }
 
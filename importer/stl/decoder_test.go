@@ -5,12 +5,16 @@ package stl
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/go-test/deep"
 	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/importer"
 )
 
 func TestNewDecoder(t *testing.T) {
@@ -68,3 +72,93 @@ func TestDecoder_Decode(t *testing.T) {
 		})
 	}
 }
+
+func binarySTL(header string, count uint32, faces ...[12]float32) []byte {
+	var b bytes.Buffer
+	b.WriteString(header)
+	b.Write(make([]byte, 80-len(header)))
+	binary.Write(&b, binary.LittleEndian, count)
+	for _, f := range faces {
+		binary.Write(&b, binary.LittleEndian, f)
+		binary.Write(&b, binary.LittleEndian, uint16(0))
+	}
+	return b.Bytes()
+}
+
+func Test_format_Sniff(t *testing.T) {
+	face := [12]float32{0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0}
+	tests := []struct {
+		name         string
+		head         []byte
+		size         int64
+		want         bool
+		wantFallback bool
+	}{
+		{"empty", nil, -1, false, false},
+		{"ascii", []byte("solid cube\n" + strings.Repeat(" ", sizeOfHeader)), -1, true, false},
+		{"upper", []byte("SOLID cube"), -1, true, false},
+		{"binary", binarySTL("", 2, face, face), -1, false, true},
+		{"binarySize", binarySTL("", 2, face, face), 184, false, true},
+		{"binarySolid", binarySTL("solid\xff", 1, face), -1, false, true},
+		{"sizeMismatch", binarySTL("", 2, face, face), 300, false, false},
+		{"noFaces", binarySTL("", 0), -1, false, false},
+		{"invalidNormal", binarySTL("", 1, [12]float32{0, 0, 10}), -1, false, false},
+		{"nan", binarySTL("", 1, [12]float32{0, 0, 1, float32(math.NaN())}), -1, false, false},
+		{"text", []byte(strings.Repeat("not an stl file ", 30)), -1, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (format{}).Sniff(tt.head); got != tt.want {
+				t.Errorf("format.Sniff() = %v, want %v", got, tt.want)
+			}
+			if got := (format{}).SniffFallback(tt.head, tt.size); got != tt.wantFallback {
+				t.Errorf("format.SniffFallback() = %v, want %v", got, tt.wantFallback)
+			}
+		})
+	}
+}
+
+func Test_format_DecodeContext_Sniff(t *testing.T) {
+	face := [12]float32{0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0}
+	tests := []struct {
+		name    string
+		r       io.Reader
+		wantErr error
+	}{
+		{"sized", bytes.NewReader(binarySTL("", 1, face)), nil},
+		{"truncated", bytes.NewReader(binarySTL("", 2, face)), importer.ErrFormat},
+		{"text", bytes.NewReader([]byte(strings.Repeat("not an stl file ", 30))), importer.ErrFormat},
+		{"textStream", bytes.NewBufferString(strings.Repeat("not an stl file ", 30)), importer.ErrFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := importer.Decode(tt.r, new(go3mf.Model))
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("importer.Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("importer.Decode() error = %v", err)
+			}
+		})
+	}
+}
+
+func Test_format_DecodeContext(t *testing.T) {
+	var b bytes.Buffer
+	b.Write(make([]byte, 80))
+	binary.Write(&b, binary.LittleEndian, uint32(1))
+	binary.Write(&b, binary.LittleEndian, [12]float32{0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0})
+	binary.Write(&b, binary.LittleEndian, uint16(0))
+	b.Write(make([]byte, sizeOfHeader))
+	m := new(go3mf.Model)
+	got, err := importer.Decode(&b, m)
+	if err != nil {
+		t.Fatalf("importer.Decode() error = %v", err)
+	}
+	if got != "stl" {
+		t.Errorf("importer.Decode() = %v, want stl", got)
+	}
+	if len(m.Resources.Objects) != 1 {
+		t.Errorf("importer.Decode() decoded %d objects, want 1", len(m.Resources.Objects))
+	}
+}
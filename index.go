@@ -0,0 +1,107 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+// A ResourceIndex indexes the resources of a Resources by ID,
+// so they are found in constant time.
+//
+// The resources added with AddObject and AddAsset are indexed,
+// but the index is not aware of any other modification of the Resources,
+// after which it must be rebuilt with NewResourceIndex.
+// When several resources share an ID the first one is indexed,
+// as it is the one found by Resources.FindObject and Resources.FindAsset.
+type ResourceIndex struct {
+	rs      *Resources
+	objects map[uint32]*Object
+	assets  map[uint32]Asset
+	next    uint32
+}
+
+// NewResourceIndex returns an index of the current resources of rs.
+func NewResourceIndex(rs *Resources) *ResourceIndex {
+	ix := &ResourceIndex{
+		rs:      rs,
+		objects: make(map[uint32]*Object, len(rs.Objects)),
+		assets:  make(map[uint32]Asset, len(rs.Assets)),
+		next:    1,
+	}
+	for _, o := range rs.Objects {
+		ix.addObject(o)
+	}
+	for _, a := range rs.Assets {
+		ix.addAsset(a)
+	}
+	return ix
+}
+
+// FindObject returns the object with the target ID.
+func (ix *ResourceIndex) FindObject(id uint32) (*Object, bool) {
+	o, ok := ix.objects[id]
+	return o, ok
+}
+
+// FindAsset returns the asset with the target ID.
+func (ix *ResourceIndex) FindAsset(id uint32) (Asset, bool) {
+	a, ok := ix.assets[id]
+	return a, ok
+}
+
+// AddObject appends o to the indexed resources.
+func (ix *ResourceIndex) AddObject(o *Object) {
+	ix.rs.Objects = append(ix.rs.Objects, o)
+	ix.addObject(o)
+}
+
+// AddAsset appends a to the indexed resources.
+func (ix *ResourceIndex) AddAsset(a Asset) {
+	ix.rs.Assets = append(ix.rs.Assets, a)
+	ix.addAsset(a)
+}
+
+// NewID returns an ID not used by any indexed resource
+// nor returned by a previous call, in amortized constant time.
+//
+// The IDs are handed out in increasing order, starting from the lowest unused ID.
+func (ix *ResourceIndex) NewID() uint32 {
+	for ix.used(ix.next) {
+		ix.next++
+	}
+	id := ix.next
+	ix.next++
+	return id
+}
+
+func (ix *ResourceIndex) used(id uint32) bool {
+	if _, ok := ix.objects[id]; ok {
+		return true
+	}
+	_, ok := ix.assets[id]
+	return ok
+}
+
+func (ix *ResourceIndex) addObject(o *Object) {
+	if _, ok := ix.objects[o.ID]; !ok {
+		ix.objects[o.ID] = o
+	}
+}
+
+func (ix *ResourceIndex) addAsset(a Asset) {
+	id := a.Identify()
+	if _, ok := ix.assets[id]; !ok {
+		ix.assets[id] = a
+	}
+}
+
+// withIndexes returns a shallow copy of m whose resources are indexed,
+// so the lookups done by the model-wide passes, which do not modify the model,
+// take constant time.
+func (m *Model) withIndexes() *Model {
+	mc := *m
+	mc.indexes = make(map[*Resources]*ResourceIndex, len(m.Childs)+1)
+	mc.indexes[&mc.Resources] = NewResourceIndex(&mc.Resources)
+	for _, c := range m.Childs {
+		mc.indexes[&c.Resources] = NewResourceIndex(&c.Resources)
+	}
+	return &mc
+}
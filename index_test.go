@@ -0,0 +1,77 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import "testing"
+
+func TestResourceIndex(t *testing.T) {
+	base := &BaseMaterials{ID: 2}
+	obj, dup := &Object{ID: 4}, &Object{ID: 4}
+	rs := &Resources{Assets: []Asset{base}, Objects: []*Object{obj, dup}}
+	ix := NewResourceIndex(rs)
+	if got, ok := ix.FindObject(4); !ok || got != obj {
+		t.Errorf("ResourceIndex.FindObject() = %v, %v, want %v", got, ok, obj)
+	}
+	if got, ok := ix.FindAsset(2); !ok || got != base {
+		t.Errorf("ResourceIndex.FindAsset() = %v, %v, want %v", got, ok, base)
+	}
+	if _, ok := ix.FindObject(2); ok {
+		t.Error("ResourceIndex.FindObject() found an asset")
+	}
+	if _, ok := ix.FindAsset(4); ok {
+		t.Error("ResourceIndex.FindAsset() found an object")
+	}
+
+	var ids []uint32
+	for i := 0; i < 2; i++ {
+		ids = append(ids, ix.NewID())
+	}
+	added := &Object{ID: ix.NewID()}
+	ix.AddObject(added)
+	ix.AddAsset(&BaseMaterials{ID: 6})
+	ix.AddAsset(&BaseMaterials{ID: 7})
+	ids = append(ids, added.ID, ix.NewID())
+	want := []uint32{1, 3, 5, 8}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("ResourceIndex.NewID() = %v, want %v", ids, want)
+			break
+		}
+	}
+	if got, ok := ix.FindObject(5); !ok || got != added {
+		t.Errorf("ResourceIndex.FindObject() = %v, %v, want %v", got, ok, added)
+	}
+	if len(rs.Objects) != 3 || len(rs.Assets) != 3 {
+		t.Errorf("ResourceIndex did not append the resources: %d objects and %d assets", len(rs.Objects), len(rs.Assets))
+	}
+}
+
+func TestModel_withIndexes(t *testing.T) {
+	root, child := &Object{ID: 1}, &Object{ID: 1}
+	m := &Model{Path: "/3D/model.model", Resources: Resources{Objects: []*Object{root}}, Childs: map[string]*ChildModel{
+		"/other.model": {Resources: Resources{Objects: []*Object{child}, Assets: []Asset{&BaseMaterials{ID: 2}}}},
+	}}
+	mc := m.withIndexes()
+	for _, path := range []string{"", "/3D/model.model", "/other.model"} {
+		rs, _ := mc.FindResources(path)
+		if _, ok := mc.indexes[rs]; !ok {
+			t.Errorf("Model.withIndexes() missing the index of %q", path)
+		}
+	}
+	if m.indexes != nil {
+		t.Error("Model.withIndexes() modified the model")
+	}
+	if got, ok := mc.FindObject("", 1); !ok || got != root {
+		t.Errorf("Model.FindObject() = %v, %v, want %v", got, ok, root)
+	}
+	if got, ok := mc.FindObject("/other.model", 1); !ok || got != child {
+		t.Errorf("Model.FindObject() = %v, %v, want %v", got, ok, child)
+	}
+	if _, ok := mc.FindAsset("/other.model", 2); !ok {
+		t.Error("Model.FindAsset() = false, want true")
+	}
+	if _, ok := mc.FindAsset("/missing.model", 2); ok {
+		t.Error("Model.FindAsset() = true, want false")
+	}
+}
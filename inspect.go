@@ -0,0 +1,195 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// defaultInspectPartSize bounds the parts parsed by Inspect
+// when the Decoder Limits do not set MaxPartSize.
+const defaultInspectPartSize = 16 << 20
+
+// PackageInfo describes the raw OPC structure of a package.
+type PackageInfo struct {
+	// Parts are the parts in the order they are stored in the package,
+	// including [Content_Types].xml and the relationships parts.
+	Parts []PartInfo
+	// Skipped are the names of the [Content_Types].xml and relationships parts
+	// not parsed because their declared sizes exceed the bounds used by Inspect.
+	Skipped []string
+	// Relationships are the package relationships.
+	Relationships []Relationship
+	// Defaults maps lower case extensions to content types.
+	Defaults map[string]string
+	// Overrides maps part names to content types.
+	Overrides map[string]string
+}
+
+// PartInfo describes a part of a package.
+//
+// The sizes are the ones declared by the package,
+// so they can be used to detect zip bombs without inflating the part.
+type PartInfo struct {
+	Name             string
+	ContentType      string
+	CompressedSize   uint64
+	UncompressedSize uint64
+	// Relationships are the relationships whose source is the part.
+	Relationships []Relationship
+}
+
+// Find returns the part with the given name, ignoring the case.
+func (p *PackageInfo) Find(name string) (*PartInfo, bool) {
+	for i := range p.Parts {
+		if strings.EqualFold(p.Parts[i].Name, name) {
+			return &p.Parts[i], true
+		}
+	}
+	return nil, false
+}
+
+// ContentType returns the content type of the part name
+// as defined by the overrides and the defaults.
+func (p *PackageInfo) ContentType(name string) string {
+	for n, ct := range p.Overrides {
+		if strings.EqualFold(n, name) {
+			return ct
+		}
+	}
+	return p.Defaults[strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))]
+}
+
+// Inspect returns the raw OPC structure of the package
+// without decoding the models nor inflating any part
+// other than [Content_Types].xml and the relationships parts.
+//
+// The parts and their sizes are listed from the zip central directory first.
+// [Content_Types].xml and the relationships parts are then parsed unless
+// their declared sizes exceed the MaxPartSize and MaxCompressionRatio of the Decoder Limits,
+// MaxPartSize defaulting to 16 MiB, in which case they are reported as Skipped.
+func (d *Decoder) Inspect() (*PackageInfo, error) {
+	l := d.Limits
+	if l.MaxPartSize <= 0 {
+		l.MaxPartSize = defaultInspectPartSize
+	}
+	return d.p.Inspect(l)
+}
+
+// Parts returns all the entries of the zip archive, including [Content_Types].xml
+// and the relationships parts, with the sizes declared by its central directory,
+// which is read without inflating any entry.
+func (o *opcReader) Parts() ([]PartInfo, error) {
+	r, err := zip.NewReader(o.ra, o.size)
+	if err != nil {
+		return nil, err
+	}
+	return zipParts(r), nil
+}
+
+func zipParts(r *zip.Reader) []PartInfo {
+	parts := make([]PartInfo, 0, len(r.File))
+	for _, f := range r.File {
+		if !strings.HasSuffix(f.Name, "/") {
+			parts = append(parts, PartInfo{
+				Name:             "/" + f.Name,
+				CompressedSize:   f.CompressedSize64,
+				UncompressedSize: f.UncompressedSize64,
+			})
+		}
+	}
+	return parts
+}
+
+func (o *opcReader) Inspect(limits Limits) (*PackageInfo, error) {
+	r, err := zip.NewReader(o.ra, o.size)
+	if err != nil {
+		return nil, err
+	}
+	info := &PackageInfo{
+		Parts:     zipParts(r),
+		Defaults:  make(map[string]string),
+		Overrides: make(map[string]string),
+	}
+	l := newLimiter(limits)
+	var (
+		hasContentTypes bool
+		rels            = make(map[string][]Relationship) // source part -> relationships
+	)
+	for _, f := range r.File {
+		name := "/" + f.Name
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		isContentTypes := strings.EqualFold(name, "/"+contentTypesName)
+		source, isRels := relationshipsSource(name)
+		if !isContentTypes && !isRels {
+			continue
+		}
+		if f.UncompressedSize64 > uint64(l.MaxPartSize) || l.exceedsRatio(f.UncompressedSize64, f.CompressedSize64) {
+			info.Skipped = append(info.Skipped, name)
+			hasContentTypes = hasContentTypes || isContentTypes
+			continue
+		}
+		if isContentTypes {
+			var tx contentTypesXML
+			if err = decodeZipXML(f, &tx, l.MaxPartSize); err != nil {
+				return nil, err
+			}
+			for _, d := range tx.Defaults {
+				info.Defaults[strings.ToLower(d.Extension)] = d.ContentType
+			}
+			for _, o := range tx.Overrides {
+				info.Overrides[o.PartName] = o.ContentType
+			}
+			hasContentTypes = true
+			continue
+		}
+		var rx relationshipsXML
+		if err = decodeZipXML(f, &rx, l.MaxPartSize); err != nil {
+			return nil, err
+		}
+		for _, r := range rx.Relationships {
+			rels[strings.ToUpper(source)] = append(rels[strings.ToUpper(source)], Relationship{ID: r.ID, Type: r.Type, Path: r.Target})
+		}
+	}
+	if !hasContentTypes {
+		return nil, fmt.Errorf("package does not contain %s", contentTypesName)
+	}
+	info.Relationships = rels["/"]
+	for i := range info.Parts {
+		p := &info.Parts[i]
+		p.ContentType = info.ContentType(p.Name)
+		p.Relationships = rels[strings.ToUpper(p.Name)]
+	}
+	return info, nil
+}
+
+// relationshipsSource returns the source part of the relationships part name,
+// being "/" for the package relationships.
+func relationshipsSource(name string) (string, bool) {
+	dir, file := path.Split(name)
+	if !strings.HasSuffix(strings.ToLower(dir), "/_rels/") || !strings.HasSuffix(strings.ToLower(file), ".rels") {
+		return "", false
+	}
+	return dir[:len(dir)-len("_rels/")] + file[:len(file)-len(".rels")], true
+}
+
+// decodeZipXML decodes the XML content of f, reading at most max bytes.
+func decodeZipXML(f *zip.File, v interface{}, max int64) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err = xml.NewDecoder(io.LimitReader(rc, max)).Decode(v); err != nil {
+		return fmt.Errorf("/%s: %w", f.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"archive/zip"
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func newZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	w.Close()
+	return buff.Bytes()
+}
+
+func TestDecoder_Inspect(t *testing.T) {
+	var buff bytes.Buffer
+	e := NewEncoder(&buff)
+	e.Deterministic = true
+	e.PartCompression = map[string]Compression{"/3D/Metadata/pt.xml": CompressionNone}
+	m := &Model{
+		Attachments:   []Attachment{{ContentType: ContentTypePrintTicket, Path: "/3D/Metadata/pt.xml", Data: []byte("pt")}},
+		Relationships: []Relationship{{Path: "/3D/Metadata/pt.xml", Type: RelTypePrintTicket, ID: "1"}},
+	}
+	if err := e.Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	got, err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Inspect()
+	if err != nil {
+		t.Fatalf("Decoder.Inspect() error = %v", err)
+	}
+	wantDefaults := map[string]string{"rels": contentTypeRelationships, "model": ContentType3DModel, "xml": ContentTypePrintTicket}
+	if diff := deep.Equal(got.Defaults, wantDefaults); diff != nil {
+		t.Errorf("Decoder.Inspect() Defaults = %v", diff)
+	}
+	if len(got.Overrides) != 0 {
+		t.Errorf("Decoder.Inspect() Overrides = %v, want empty", got.Overrides)
+	}
+	if len(got.Relationships) != 1 || got.Relationships[0].Path != DefaultModelPath {
+		t.Errorf("Decoder.Inspect() Relationships = %v", got.Relationships)
+	}
+	pt, ok := got.Find("/3d/metadata/PT.xml")
+	if !ok {
+		t.Fatalf("PackageInfo.Find() not found")
+	}
+	if diff := deep.Equal(*pt, PartInfo{Name: "/3D/Metadata/pt.xml", ContentType: ContentTypePrintTicket, CompressedSize: 2, UncompressedSize: 2}); diff != nil {
+		t.Errorf("Decoder.Inspect() part = %v", diff)
+	}
+	root, ok := got.Find(DefaultModelPath)
+	if !ok {
+		t.Fatalf("PackageInfo.Find() not found")
+	}
+	if root.ContentType != ContentType3DModel || root.UncompressedSize == 0 {
+		t.Errorf("Decoder.Inspect() root = %v", *root)
+	}
+	if diff := deep.Equal(root.Relationships, m.Relationships); diff != nil {
+		t.Errorf("Decoder.Inspect() root relationships = %v", diff)
+	}
+	if _, ok := got.Find("/3D/_rels/3dmodel.model.rels"); !ok {
+		t.Error("Decoder.Inspect() relationships part not listed")
+	}
+}
+
+func TestDecoder_Inspect_Skipped(t *testing.T) {
+	rels := `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + strings.Repeat(" ", 10000) + `</Relationships>`
+	pkg := newZip(t, map[string]string{
+		"[Content_Types].xml":   `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="a/b"/></Types>`,
+		"_rels/.rels":           rels,
+		"3D/_rels/a.model.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="1" Target="/b.png" Type="t"/></Relationships>`,
+	})
+	tests := []struct {
+		name   string
+		limits Limits
+		want   []string
+	}{
+		{"default", Limits{}, nil},
+		{"partSize", Limits{MaxPartSize: 1000}, []string{"/_rels/.rels"}},
+		{"compressionRatio", Limits{MaxCompressionRatio: 10}, []string{"/_rels/.rels"}},
+		{"all", Limits{MaxPartSize: 10}, []string{"/[Content_Types].xml", "/_rels/.rels", "/3D/_rels/a.model.rels"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(bytes.NewReader(pkg), int64(len(pkg)))
+			d.Limits = tt.limits
+			got, err := d.Inspect()
+			if err != nil {
+				t.Fatalf("Decoder.Inspect() error = %v", err)
+			}
+			sort.Strings(got.Skipped)
+			sort.Strings(tt.want)
+			if diff := deep.Equal(got.Skipped, tt.want); diff != nil {
+				t.Errorf("Decoder.Inspect() Skipped = %v", diff)
+			}
+			if len(got.Parts) != 3 {
+				t.Errorf("Decoder.Inspect() Parts = %v, want 3 parts", got.Parts)
+			}
+			if p, _ := got.Find("/_rels/.rels"); p == nil || p.UncompressedSize != uint64(len(rels)) {
+				t.Errorf("Decoder.Inspect() rels part = %v", p)
+			}
+		})
+	}
+}
+
+func TestDecoder_Inspect_Fail(t *testing.T) {
+	tests := []struct {
+		name string
+		pkg  []byte
+	}{
+		{"notZip", []byte("other")},
+		{"noContentTypes", newZip(t, map[string]string{"a.model": ""})},
+		{"malformedContentTypes", newZip(t, map[string]string{"[Content_Types].xml": "<Types"})},
+		{"malformedRels", newZip(t, map[string]string{"[Content_Types].xml": "<Types/>", "_rels/.rels": "<Relationships"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDecoder(bytes.NewReader(tt.pkg), int64(len(tt.pkg))).Inspect(); err == nil {
+				t.Error("Decoder.Inspect() expected error")
+			}
+		})
+	}
+}
+
+func TestPackageInfo_ContentType(t *testing.T) {
+	p := &PackageInfo{
+		Defaults:  map[string]string{"png": "image/png"},
+		Overrides: map[string]string{"/a.PNG": "image/other"},
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"/b.PNG", "image/png"},
+		{"/A.png", "image/other"},
+		{"/c", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.ContentType(tt.name); got != tt.want {
+				t.Errorf("PackageInfo.ContentType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_relationshipsSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOk bool
+	}{
+		{"/_rels/.rels", "/", true},
+		{"/3D/_rels/3dmodel.model.rels", "/3D/3dmodel.model", true},
+		{"/3D/_RELS/a.RELS", "/3D/a", true},
+		{"/3D/3dmodel.model", "", false},
+		{"/_rels/a.xml", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := relationshipsSource(tt.name)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("relationshipsSource() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
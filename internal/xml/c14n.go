@@ -0,0 +1,263 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrC14NNotFound is returned by Canonicalize when no element matches.
+var ErrC14NNotFound = errors.New("xml: element to canonicalize not found")
+
+type c14nFrame struct {
+	ns       map[string]string // prefix -> namespace in scope
+	rendered map[string]string // prefix -> namespace rendered in the output
+}
+
+// Canonicalize returns the Canonical XML 1.0 (without comments)
+// serialization of the first element of r for which match returns true.
+//
+// The namespace declarations in scope from the ancestors
+// of the element are rendered in the element itself.
+//
+// Only documents without a DTD are supported: attributes are normalized
+// as CDATA attributes and there are no default attributes nor declared entities.
+func Canonicalize(r io.Reader, match func(xml.StartElement) bool) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		out   bytes.Buffer
+		stack = []c14nFrame{{ns: map[string]string{}, rendered: map[string]string{}}}
+		apex  = -1
+		d     = xml.NewDecoder(bytes.NewReader(data))
+	)
+	for {
+		offset := d.InputOffset()
+		t, err := d.RawToken()
+		if err == io.EOF {
+			return nil, ErrC14NNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			normalizeAttrs(data[offset:d.InputOffset()], t.Attr)
+			parent := stack[len(stack)-1]
+			f := c14nFrame{ns: make(map[string]string, len(parent.ns)), rendered: parent.rendered}
+			for k, v := range parent.ns {
+				f.ns[k] = v
+			}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" {
+					f.ns[a.Name.Local] = a.Value
+				} else if a.Name.Space == "" && a.Name.Local == "xmlns" {
+					f.ns[""] = a.Value
+				}
+			}
+			if apex == -1 && match(t) {
+				apex = len(stack)
+				f.rendered = map[string]string{}
+			}
+			if apex != -1 {
+				f.rendered = writeC14NStart(&out, t, f)
+			}
+			stack = append(stack, f)
+		case xml.EndElement:
+			if apex != -1 {
+				out.WriteString("</")
+				writeC14NName(&out, t.Name)
+				out.WriteByte('>')
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == apex {
+				return out.Bytes(), nil
+			}
+		case xml.CharData:
+			if apex != -1 {
+				escapeC14N(&out, string(t), false)
+			}
+		case xml.ProcInst:
+			if apex != -1 {
+				out.WriteString("<?")
+				out.WriteString(t.Target)
+				if len(t.Inst) > 0 {
+					out.WriteByte(' ')
+					out.Write(t.Inst)
+				}
+				out.WriteString("?>")
+			}
+		}
+	}
+}
+
+// normalizeAttrs sets the values of attrs to the ones in the raw start tag,
+// normalized as CDATA attributes: literal white space is replaced by a space
+// while the white space written as character references is kept.
+// encoding/xml does not normalize the attribute values.
+func normalizeAttrs(tag []byte, attrs []xml.Attr) {
+	for i := range attrs {
+		q := bytes.IndexAny(tag, `"'`)
+		if q < 0 {
+			return
+		}
+		end := bytes.IndexByte(tag[q+1:], tag[q])
+		if end < 0 {
+			return
+		}
+		attrs[i].Value = normalizeAttrValue(tag[q+1 : q+1+end])
+		tag = tag[q+end+2:]
+	}
+}
+
+func normalizeAttrValue(raw []byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; c {
+		case '\r':
+			if i+1 < len(raw) && raw[i+1] == '\n' {
+				i++
+			}
+			sb.WriteByte(' ')
+		case '\t', '\n':
+			sb.WriteByte(' ')
+		case '&':
+			end := bytes.IndexByte(raw[i:], ';')
+			if end < 0 {
+				sb.Write(raw[i:])
+				return sb.String()
+			}
+			sb.WriteString(unescapeEntity(string(raw[i+1 : i+end])))
+			i += end
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// unescapeEntity returns the text referenced by the entity or character reference name.
+func unescapeEntity(name string) string {
+	switch name {
+	case "lt":
+		return "<"
+	case "gt":
+		return ">"
+	case "amp":
+		return "&"
+	case "quot":
+		return `"`
+	case "apos":
+		return "'"
+	}
+	if strings.HasPrefix(name, "#") {
+		var (
+			n   uint64
+			err error
+		)
+		if strings.HasPrefix(name, "#x") {
+			n, err = strconv.ParseUint(name[2:], 16, 32)
+		} else {
+			n, err = strconv.ParseUint(name[1:], 10, 32)
+		}
+		if err == nil && utf8.ValidRune(rune(n)) {
+			return string(rune(n))
+		}
+	}
+	return "&" + name + ";"
+}
+
+// writeC14NStart writes the start element, with the namespace declarations
+// not already rendered by its ancestors, and returns the rendered namespaces.
+// A default namespace undeclared with xmlns="" is only rendered
+// if an ancestor rendered a non empty default namespace.
+func writeC14NStart(out *bytes.Buffer, t xml.StartElement, f c14nFrame) map[string]string {
+	var prefixes []string
+	for p, ns := range f.ns {
+		if p == "xml" || f.rendered[p] == ns {
+			continue
+		}
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	rendered := f.rendered
+	if len(prefixes) > 0 {
+		rendered = make(map[string]string, len(f.rendered)+len(prefixes))
+		for k, v := range f.rendered {
+			rendered[k] = v
+		}
+	}
+	out.WriteByte('<')
+	writeC14NName(out, t.Name)
+	for _, p := range prefixes {
+		rendered[p] = f.ns[p]
+		out.WriteString(" xmlns")
+		if p != "" {
+			out.WriteByte(':')
+			out.WriteString(p)
+		}
+		out.WriteString(`="`)
+		escapeC14N(out, f.ns[p], true)
+		out.WriteByte('"')
+	}
+	attrs := make([]xml.Attr, 0, len(t.Attr))
+	for _, a := range t.Attr {
+		if a.Name.Space != "xmlns" && (a.Name.Space != "" || a.Name.Local != "xmlns") {
+			attrs = append(attrs, a)
+		}
+	}
+	space := func(a xml.Attr) string {
+		if a.Name.Space == "" {
+			return ""
+		}
+		if a.Name.Space == "xml" {
+			return "http://www.w3.org/XML/1998/namespace"
+		}
+		return f.ns[a.Name.Space]
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		si, sj := space(attrs[i]), space(attrs[j])
+		if si != sj {
+			return si < sj
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+	for _, a := range attrs {
+		out.WriteByte(' ')
+		writeC14NName(out, a.Name)
+		out.WriteString(`="`)
+		escapeC14N(out, a.Value, true)
+		out.WriteByte('"')
+	}
+	out.WriteByte('>')
+	return rendered
+}
+
+func writeC14NName(out *bytes.Buffer, name xml.Name) {
+	if name.Space != "" {
+		out.WriteString(name.Space)
+		out.WriteByte(':')
+	}
+	out.WriteString(name.Local)
+}
+
+var (
+	c14nTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	c14nAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeC14N(out *bytes.Buffer, s string, attr bool) {
+	if attr {
+		c14nAttrEscaper.WriteString(out, s)
+	} else {
+		c14nTextEscaper.WriteString(out, s)
+	}
+}
@@ -0,0 +1,48 @@
+package xml
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	byLocal := func(local string) func(xml.StartElement) bool {
+		return func(s xml.StartElement) bool { return s.Name.Local == local }
+	}
+	tests := []struct {
+		name    string
+		doc     string
+		match   func(xml.StartElement) bool
+		want    string
+		wantErr bool
+	}{
+		{"notFound", `<a/>`, byLocal("b"), "", true},
+		{"malformed", `<a><b`, byLocal("b"), "", true},
+		{"emptyElement", `<?xml version="1.0"?><a/>`, byLocal("a"), `<a></a>`, false},
+		{"attrOrder", `<a z="1" xmlns:p="urn:p" p:b="2" a="3" xmlns="urn:d"/>`, byLocal("a"),
+			`<a xmlns="urn:d" xmlns:p="urn:p" a="3" z="1" p:b="2"></a>`, false},
+		{"inheritedNamespaces", `<r xmlns="urn:r" xmlns:x="urn:x"><!-- c --><s Id="1"><x:t>v</x:t></s></r>`, byLocal("s"),
+			`<s xmlns="urn:r" xmlns:x="urn:x" Id="1"><x:t>v</x:t></s>`, false},
+		{"redeclared", `<s xmlns="urn:a"><t xmlns="urn:a"><u xmlns=""></u></t></s>`, byLocal("s"),
+			`<s xmlns="urn:a"><t><u xmlns=""></u></t></s>`, false},
+		{"escape", "<a b=\"&quot;&#9;&gt;\">&lt;&amp;&gt;&#13;<![CDATA[<]]><?pi data?></a>", byLocal("a"),
+			"<a b=\"&quot;&#x9;>\">&lt;&amp;&gt;&#xD;&lt;<?pi data?></a>", false},
+		{"attrWhitespace", "<a b=\"x\ty\r\nz\n&#10;&#x9;&#13;\" c='&apos;q'/>", byLocal("a"),
+			`<a b="x y z &#xA;&#x9;&#xD;" c="'q"></a>`, false},
+		{"attrQuotes", `<a b='"&gt;' c="'"/>`, byLocal("a"), `<a b="&quot;>" c="'"></a>`, false},
+		{"emptyDefaultNamespace", `<r><s xmlns=""><t/></s></r>`, byLocal("r"), `<r><s><t></t></s></r>`, false},
+		{"emptyDefaultNamespaceApex", `<r xmlns="urn:r"><s xmlns=""><t/></s></r>`, byLocal("s"), `<s><t></t></s>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonicalize(strings.NewReader(tt.doc), tt.match)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Canonicalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Canonicalize() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
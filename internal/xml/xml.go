@@ -101,6 +101,9 @@ type Decoder struct {
 	err       error
 	attrPool  []XMLAttr
 	strPool   []bytes.Buffer
+
+	line, linestart, offset int // current input position
+	tokLine, tokCol         int // position of the current token
 }
 
 // NewDecoder creates a new XML parser reading from r.
@@ -112,6 +115,7 @@ func NewDecoder(r io.Reader) *Decoder {
 		names:    make(map[[nameCacheSize]byte]string),
 		attrPool: make([]XMLAttr, 10),
 		strPool:  make([]bytes.Buffer, 10),
+		line:     1,
 		r: &bufioReader{
 			buf:      make([]byte, defaultBufSize),
 			rd:       r,
@@ -276,7 +280,19 @@ func (d *Decoder) pushNs(local string, url string, ok bool) {
 
 // Creates a SyntaxError.
 func (d *Decoder) syntaxError(msg string) error {
-	return &goxml.SyntaxError{Msg: msg}
+	return &goxml.SyntaxError{Msg: msg, Line: d.line}
+}
+
+// TokenPos returns the 1-based line and column, in bytes,
+// where the last token returned by RawToken starts.
+func (d *Decoder) TokenPos() (line, column int) {
+	return d.tokLine, d.tokCol
+}
+
+// InputPos returns the 1-based line and column, in bytes,
+// of the current decoder position.
+func (d *Decoder) InputPos() (line, column int) {
+	return d.line, d.offset - d.linestart + 1
 }
 
 // Record that we are ending an element with the given name.
@@ -336,6 +352,7 @@ func (d *Decoder) RawToken() error {
 		return nil
 	}
 
+	d.tokLine, d.tokCol = d.InputPos()
 	b, ok := d.getc()
 	if !ok {
 		if d.err == io.EOF && d.stk != nil && d.stk.kind != stkEOF {
@@ -524,6 +541,13 @@ func (d *Decoder) space() {
 func (d *Decoder) getc() (b byte, ok bool) {
 	b, d.err = d.r.ReadByte()
 	ok = d.err == nil
+	if ok {
+		d.offset++
+		if b == '\n' {
+			d.line++
+			d.linestart = d.offset
+		}
+	}
 	return
 }
 
@@ -540,6 +564,10 @@ func (d *Decoder) mustNotEOF() {
 
 // Unread a single byte.
 func (d *Decoder) ungetc(b byte) {
+	if b == '\n' {
+		d.line--
+	}
+	d.offset--
 	d.r.nextByte = int(b)
 }
 
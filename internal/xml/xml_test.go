@@ -0,0 +1,43 @@
+package xml
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecoder_TokenPos(t *testing.T) {
+	doc := "<a>\n\t<b x=\"1\"\n\t\ty=\"2\"/><c>\n text</c>\n</a>"
+	want := map[string][2]int{"a": {1, 1}, "b": {2, 2}, "c": {3, 10}}
+	d := NewDecoder(strings.NewReader(doc))
+	got := make(map[string][2]int)
+	d.OnStart = func(s StartElement) {
+		line, column := d.TokenPos()
+		got[s.Name.Local] = [2]int{line, column}
+	}
+	var err error
+	for err == nil {
+		err = d.RawToken()
+	}
+	for name, pos := range want {
+		if got[name] != pos {
+			t.Errorf("Decoder.TokenPos() <%s> = %v, want %v", name, got[name], pos)
+		}
+	}
+	if line, column := d.InputPos(); line != 5 || column != 5 {
+		t.Errorf("Decoder.InputPos() = %d, %d, want 5, 5", line, column)
+	}
+}
+
+func TestDecoder_RawToken_SyntaxErrorLine(t *testing.T) {
+	d := NewDecoder(strings.NewReader("<a>\n<b>\n</c>"))
+	var err error
+	for err == nil {
+		err = d.RawToken()
+	}
+	var serr *xml.SyntaxError
+	if !errors.As(err, &serr) || serr.Line != 3 {
+		t.Errorf("Decoder.RawToken() = %v, want syntax error at line 3", err)
+	}
+}
@@ -0,0 +1,279 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	xml3mf "github.com/hpinc/go3mf/internal/xml"
+)
+
+// Limits bound the resources used by a Decoder,
+// so packages from untrusted sources can be decoded safely.
+// The zero value of a field means no limit.
+//
+// The sizes are checked against the ones declared by the package before
+// inflating any part, and against the bytes actually read while decoding.
+// Attachments opened lazily are only checked against MaxPartSize
+// and MaxCompressionRatio, with a new budget each time they are opened.
+type Limits struct {
+	// MaxPartSize is the maximum uncompressed size of a part, in bytes.
+	MaxPartSize int64
+	// MaxTotalSize is the maximum uncompressed size of all the parts, in bytes.
+	MaxTotalSize int64
+	// MaxCompressionRatio is the maximum ratio between the uncompressed
+	// and the compressed size of a part.
+	MaxCompressionRatio int64
+	// MaxChildModels is the maximum number of child model parts.
+	MaxChildModels int
+	// MaxObjects is the maximum number of objects of all the model parts.
+	MaxObjects int
+	// MaxVertices is the maximum number of vertices of a mesh.
+	MaxVertices int
+	// MaxTriangles is the maximum number of triangles of a mesh.
+	MaxTriangles int
+	// MaxDepth is the maximum nesting depth of the XML elements of a model part.
+	MaxDepth int
+	// MaxAttrLength is the maximum length of an XML attribute value, in bytes.
+	MaxAttrLength int
+}
+
+// A LimitError is returned when decoding exceeds one of the Limits.
+type LimitError struct {
+	// Path is the part exceeding the limit, empty if the limit applies to the whole package.
+	Path string
+	// Limit is the name of the exceeded Limits field.
+	Limit string
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("go3mf: %s of %d exceeded", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("go3mf: %s: %s of %d exceeded", e.Path, e.Limit, e.Max)
+}
+
+// Code returns the rule code used in validation reports.
+func (e *LimitError) Code() string {
+	return "limit"
+}
+
+// limiter tracks the resources used while decoding a package.
+// It is safe for concurrent use and a nil limiter does not limit anything.
+type limiter struct {
+	Limits
+	compressed map[string]uint64 // upper case part name -> compressed size
+	total      int64             // accessed atomically
+	objects    int64             // accessed atomically
+	mu         sync.Mutex
+	err        error
+}
+
+func newLimiter(l Limits) *limiter {
+	return &limiter{Limits: l, compressed: make(map[string]uint64)}
+}
+
+// needsPackageInfo reports whether the limits require the sizes declared by the package.
+func (l *limiter) needsPackageInfo() bool {
+	return l.MaxPartSize > 0 || l.MaxTotalSize > 0 || l.MaxCompressionRatio > 0
+}
+
+// Err returns the first limit exceeded, if any.
+func (l *limiter) Err() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+func (l *limiter) fail(path, limit string, max int64) error {
+	err := &LimitError{Path: path, Limit: limit, Max: max}
+	l.mu.Lock()
+	if l.err == nil {
+		l.err = err
+	}
+	l.mu.Unlock()
+	return err
+}
+
+// checkPackage checks the sizes declared by the package parts.
+func (l *limiter) checkPackage(parts []PartInfo) error {
+	var total uint64
+	for _, p := range parts {
+		l.compressed[strings.ToUpper(p.Name)] = p.CompressedSize
+		if l.MaxPartSize > 0 && p.UncompressedSize > uint64(l.MaxPartSize) {
+			return l.fail(p.Name, "MaxPartSize", l.MaxPartSize)
+		}
+		if l.exceedsRatio(p.UncompressedSize, p.CompressedSize) {
+			return l.fail(p.Name, "MaxCompressionRatio", l.MaxCompressionRatio)
+		}
+		total += p.UncompressedSize
+	}
+	if l.MaxTotalSize > 0 && total > uint64(l.MaxTotalSize) {
+		return l.fail("", "MaxTotalSize", l.MaxTotalSize)
+	}
+	return nil
+}
+
+func (l *limiter) exceedsRatio(uncompressed, compressed uint64) bool {
+	if l.MaxCompressionRatio <= 0 {
+		return false
+	}
+	if compressed == 0 {
+		compressed = 1
+	}
+	return uncompressed > compressed*uint64(l.MaxCompressionRatio)
+}
+
+// read accounts n bytes read from the part name, which has read bytes so far.
+func (l *limiter) read(name string, read, n int64) error {
+	if l.MaxPartSize > 0 && read > l.MaxPartSize {
+		return l.fail(name, "MaxPartSize", l.MaxPartSize)
+	}
+	if compressed, ok := l.compressed[strings.ToUpper(name)]; ok && l.exceedsRatio(uint64(read), compressed) {
+		return l.fail(name, "MaxCompressionRatio", l.MaxCompressionRatio)
+	}
+	if l.MaxTotalSize > 0 && atomic.AddInt64(&l.total, n) > l.MaxTotalSize {
+		return l.fail("", "MaxTotalSize", l.MaxTotalSize)
+	}
+	return nil
+}
+
+func (l *limiter) checkChildModels(n int) error {
+	if l != nil && l.MaxChildModels > 0 && n > l.MaxChildModels {
+		return l.fail("", "MaxChildModels", int64(l.MaxChildModels))
+	}
+	return nil
+}
+
+type limitedPackage struct {
+	packageReader
+	l *limiter
+}
+
+func (p *limitedPackage) FindFileFromName(name string) (packageFile, bool) {
+	f, ok := p.packageReader.FindFileFromName(name)
+	if !ok {
+		return nil, false
+	}
+	return &limitedFile{packageFile: f, l: p.l}, true
+}
+
+type limitedFile struct {
+	packageFile
+	l *limiter
+}
+
+func (f *limitedFile) FindFileFromName(name string) (packageFile, bool) {
+	file, ok := f.packageFile.FindFileFromName(name)
+	if !ok {
+		return nil, false
+	}
+	return &limitedFile{packageFile: file, l: f.l}, true
+}
+
+func (f *limitedFile) Open() (io.ReadCloser, error) {
+	r, err := f.packageFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &limitedReader{ReadCloser: r, name: f.Name(), l: f.l}, nil
+}
+
+// attachmentFile is a lazy attachment of a limited package.
+// Each Open checks the per-part limits with a new budget,
+// as the attachment can be opened any number of times once decoded.
+type attachmentFile struct {
+	packageFile
+	l *limiter
+}
+
+func (f *attachmentFile) Open() (io.ReadCloser, error) {
+	r, err := f.packageFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	l := &limiter{
+		Limits:     Limits{MaxPartSize: f.l.MaxPartSize, MaxCompressionRatio: f.l.MaxCompressionRatio},
+		compressed: f.l.compressed,
+	}
+	return &limitedReader{ReadCloser: r, name: f.Name(), l: l}, nil
+}
+
+type limitedReader struct {
+	io.ReadCloser
+	name string
+	l    *limiter
+	read int64
+	err  error
+}
+
+func (r *limitedReader) Read(b []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.ReadCloser.Read(b)
+	r.read += int64(n)
+	if r.err = r.l.read(r.name, r.read, int64(n)); r.err != nil {
+		return n, r.err
+	}
+	return n, err
+}
+
+// modelLimiter checks the limits of the XML content of a model part.
+type modelLimiter struct {
+	l                          *limiter
+	path                       string
+	depth, vertices, triangles int
+}
+
+func (m *modelLimiter) start(tp xml3mf.StartElement) error {
+	if m.l == nil {
+		return nil
+	}
+	m.depth++
+	if m.l.MaxDepth > 0 && m.depth > m.l.MaxDepth {
+		return m.l.fail(m.path, "MaxDepth", int64(m.l.MaxDepth))
+	}
+	if m.l.MaxAttrLength > 0 {
+		for _, a := range tp.Attr {
+			if len(a.Value) > m.l.MaxAttrLength {
+				return m.l.fail(m.path, "MaxAttrLength", int64(m.l.MaxAttrLength))
+			}
+		}
+	}
+	if tp.Name.Space != Namespace {
+		return nil
+	}
+	switch tp.Name.Local {
+	case attrMesh:
+		m.vertices, m.triangles = 0, 0
+	case attrVertex:
+		if m.vertices++; m.l.MaxVertices > 0 && m.vertices > m.l.MaxVertices {
+			return m.l.fail(m.path, "MaxVertices", int64(m.l.MaxVertices))
+		}
+	case attrTriangle:
+		if m.triangles++; m.l.MaxTriangles > 0 && m.triangles > m.l.MaxTriangles {
+			return m.l.fail(m.path, "MaxTriangles", int64(m.l.MaxTriangles))
+		}
+	case attrObject:
+		if n := atomic.AddInt64(&m.l.objects, 1); m.l.MaxObjects > 0 && n > int64(m.l.MaxObjects) {
+			return m.l.fail(m.path, "MaxObjects", int64(m.l.MaxObjects))
+		}
+	}
+	return nil
+}
+
+func (m *modelLimiter) end() {
+	if m.l != nil {
+		m.depth--
+	}
+}
@@ -0,0 +1,177 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func newLimitsPackage(t *testing.T) []byte {
+	t.Helper()
+	mesh := &Mesh{
+		Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+		Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 2, V3: 1}}},
+	}
+	m := &Model{
+		Resources: Resources{Objects: []*Object{{ID: 1, Mesh: mesh}, {ID: 2, Mesh: mesh}}},
+		Attachments: []Attachment{
+			{ContentType: "text/plain", Path: "/big.txt", Data: []byte(strings.Repeat("a", 10000))},
+		},
+		RootRelationships: []Relationship{{Path: "/big.txt", Type: "big"}},
+		Childs: map[string]*ChildModel{
+			"/3D/a.model": {Resources: Resources{Objects: []*Object{{ID: 1, Mesh: mesh}}}},
+			"/3D/b.model": {Resources: Resources{Objects: []*Object{{ID: 1, Mesh: mesh}}}},
+		},
+	}
+	var buff bytes.Buffer
+	e := NewEncoder(&buff)
+	e.PartCompression = map[string]Compression{"/3D/3dmodel.model": CompressionNone}
+	if err := e.Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	return buff.Bytes()
+}
+
+func TestDecoder_Decode_Limits(t *testing.T) {
+	pkg := newLimitsPackage(t)
+	tests := []struct {
+		name      string
+		limits    Limits
+		buffer    bool
+		wantLimit string
+	}{
+		{"none", Limits{}, true, ""},
+		{"within", Limits{
+			MaxPartSize: 20000, MaxTotalSize: 100000, MaxCompressionRatio: 1000, MaxChildModels: 2,
+			MaxObjects: 4, MaxVertices: 3, MaxTriangles: 2, MaxDepth: 6, MaxAttrLength: 100,
+		}, true, ""},
+		{"partSize", Limits{MaxPartSize: 5000}, false, "MaxPartSize"},
+		{"totalSize", Limits{MaxTotalSize: 10000}, false, "MaxTotalSize"},
+		{"compressionRatio", Limits{MaxCompressionRatio: 20}, false, "MaxCompressionRatio"},
+		{"childModels", Limits{MaxChildModels: 1}, false, "MaxChildModels"},
+		{"objects", Limits{MaxObjects: 3}, false, "MaxObjects"},
+		{"vertices", Limits{MaxVertices: 2}, false, "MaxVertices"},
+		{"triangles", Limits{MaxTriangles: 1}, false, "MaxTriangles"},
+		{"depth", Limits{MaxDepth: 4}, false, "MaxDepth"},
+		{"attrLength", Limits{MaxAttrLength: 3}, false, "MaxAttrLength"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(bytes.NewReader(pkg), int64(len(pkg)))
+			d.Limits = tt.limits
+			d.BufferAttachments = tt.buffer
+			err := d.Decode(new(Model))
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Errorf("Decoder.Decode() error = %v", err)
+				}
+				return
+			}
+			var lerr *LimitError
+			if !errors.As(err, &lerr) || lerr.Limit != tt.wantLimit {
+				t.Errorf("Decoder.Decode() error = %v, want %s", err, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_LimitsLazyAttachments(t *testing.T) {
+	pkg := newLimitsPackage(t)
+	d := NewDecoder(bytes.NewReader(pkg), int64(len(pkg)))
+	// Enough for a single decoding, but not for reading the attachment a few more times.
+	d.Limits = Limits{MaxTotalSize: 30000, MaxPartSize: 20000}
+	m := new(Model)
+	if err := d.Decode(m); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := NewEncoder(ioutil.Discard).Encode(m); err != nil {
+			t.Fatalf("Encoder.Encode() #%d error = %v", i, err)
+		}
+	}
+}
+
+func TestDecoder_Decode_LimitsBeforeOpen(t *testing.T) {
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	fw, _ := w.Create(contentTypesName)
+	// Not even valid XML, the declared size must be rejected before parsing it.
+	fw.Write(bytes.Repeat([]byte{' '}, 100000))
+	w.Close()
+	pkg := buff.Bytes()
+	d := NewDecoder(bytes.NewReader(pkg), int64(len(pkg)))
+	d.Limits = Limits{MaxPartSize: 1000}
+	var lerr *LimitError
+	if err := d.Decode(new(Model)); !errors.As(err, &lerr) || lerr.Path != "/"+contentTypesName {
+		t.Errorf("Decoder.Decode() error = %v, want MaxPartSize of %s", err, contentTypesName)
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     Limits
+		compressed map[string]uint64
+		wantLimit  string
+	}{
+		{"partSize", Limits{MaxPartSize: 9}, nil, "MaxPartSize"},
+		{"totalSize", Limits{MaxTotalSize: 9}, nil, "MaxTotalSize"},
+		{"compressionRatio", Limits{MaxCompressionRatio: 2}, map[string]uint64{"/A.TXT": 4}, "MaxCompressionRatio"},
+		{"unknownCompressedSize", Limits{MaxCompressionRatio: 2}, nil, ""},
+		{"within", Limits{MaxPartSize: 10, MaxTotalSize: 10, MaxCompressionRatio: 5}, map[string]uint64{"/A.TXT": 2}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLimiter(tt.limits)
+			if tt.compressed != nil {
+				l.compressed = tt.compressed
+			}
+			f := &limitedFile{packageFile: &fakePackageFile{data: []byte("0123456789")}, l: l}
+			// fakePackageFile is always named as the root model.
+			f.packageFile = &namedFile{packageFile: f.packageFile, name: "/a.txt"}
+			r, _ := f.Open()
+			_, err := ioutil.ReadAll(r)
+			var lerr *LimitError
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Errorf("limitedReader.Read() error = %v", err)
+				}
+			} else if !errors.As(err, &lerr) || lerr.Limit != tt.wantLimit {
+				t.Errorf("limitedReader.Read() error = %v, want %s", err, tt.wantLimit)
+			}
+			if got := l.Err(); (got != nil) != (tt.wantLimit != "") {
+				t.Errorf("limiter.Err() = %v", got)
+			}
+		})
+	}
+}
+
+type namedFile struct {
+	packageFile
+	name string
+}
+
+func (f *namedFile) Name() string { return f.name }
+
+func TestLimitError_Error(t *testing.T) {
+	tests := []struct {
+		err  *LimitError
+		want string
+	}{
+		{&LimitError{Limit: "MaxTotalSize", Max: 10}, "go3mf: MaxTotalSize of 10 exceeded"},
+		{&LimitError{Path: "/3D/3dmodel.model", Limit: "MaxDepth", Max: 2}, "go3mf: /3D/3dmodel.model: MaxDepth of 2 exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("LimitError.Error() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,10 +5,10 @@ package materials
 
 import (
 	"encoding/xml"
-	"errors"
 	"image/color"
 
 	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/errors"
 	"github.com/hpinc/go3mf/spec"
 )
 
@@ -32,13 +32,13 @@ func init() {
 type Spec struct{}
 
 var (
-	ErrMultiBlend         = errors.New("there MUST NOT be more blendmethods than layers – 1")
-	ErrMaterialMulti      = errors.New("a material, if included, MUST be positioned as the first layer")
-	ErrMultiRefMulti      = errors.New("the pids list MUST NOT contain any references to a multiproperties")
-	ErrMultiColors        = errors.New("the pids list MUST NOT contain more than one reference to a colorgroup")
-	ErrTextureReference   = errors.New("MUST reference to a texture resource")
-	ErrCompositeBase      = errors.New("MUST reference to a basematerials group")
-	ErrMissingTexturePart = errors.New("texture part MUST be added as an attachment")
+	ErrMultiBlend         = errors.NewRule("materials-6-blendmethods", errors.SeverityError, "there MUST NOT be more blendmethods than layers – 1")
+	ErrMaterialMulti      = errors.NewRule("materials-6-material-layer", errors.SeverityError, "a material, if included, MUST be positioned as the first layer")
+	ErrMultiRefMulti      = errors.NewRule("materials-6-nested", errors.SeverityError, "the pids list MUST NOT contain any references to a multiproperties")
+	ErrMultiColors        = errors.NewRule("materials-6-colorgroups", errors.SeverityError, "the pids list MUST NOT contain more than one reference to a colorgroup")
+	ErrTextureReference   = errors.NewRule("materials-4-texture", errors.SeverityError, "MUST reference to a texture resource")
+	ErrCompositeBase      = errors.NewRule("materials-5-basematerials", errors.SeverityError, "MUST reference to a basematerials group")
+	ErrMissingTexturePart = errors.NewRule("materials-3-texture-part", errors.SeverityError, "texture part MUST be added as an attachment")
 )
 
 // Texture2DType defines the allowed texture 2D types.
@@ -0,0 +1,26 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package materials
+
+func (Spec) Resolve(parent interface{}, name string, index int) (interface{}, bool) {
+	switch p := parent.(type) {
+	case *ColorGroup:
+		if name == attrColor && index >= 0 && index < len(p.Colors) {
+			return &p.Colors[index], true
+		}
+	case *Texture2DGroup:
+		if name == attrTex2DCoord && index >= 0 && index < len(p.Coords) {
+			return &p.Coords[index], true
+		}
+	case *CompositeMaterials:
+		if name == attrComposite && index >= 0 && index < len(p.Composites) {
+			return &p.Composites[index], true
+		}
+	case *MultiProperties:
+		if name == attrMulti && index >= 0 && index < len(p.Multis) {
+			return &p.Multis[index], true
+		}
+	}
+	return nil, false
+}
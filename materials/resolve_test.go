@@ -0,0 +1,39 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package materials
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/hpinc/go3mf"
+)
+
+func TestSpec_Resolve(t *testing.T) {
+	colors := &ColorGroup{ID: 1, Colors: make([]color.RGBA, 2)}
+	coords := &Texture2DGroup{ID: 2, Coords: make([]TextureCoord, 2)}
+	composites := &CompositeMaterials{ID: 3, Composites: make([]Composite, 2)}
+	multis := &MultiProperties{ID: 4, Multis: make([]Multi, 2)}
+	m := &go3mf.Model{Resources: go3mf.Resources{Assets: []go3mf.Asset{colors, coords, composites, multis}}}
+	tests := []struct {
+		xpath  string
+		want   interface{}
+		wantOk bool
+	}{
+		{"/model/resources/colorgroup[0]/color[1]", &colors.Colors[1], true},
+		{"/model/resources/texture2dgroup[1]/tex2coord[1]", &coords.Coords[1], true},
+		{"/model/resources/compositematerials[2]/composite[1]", &composites.Composites[1], true},
+		{"/model/resources/multiproperties[3]/multi[1]", &multis.Multis[1], true},
+		{"/model/resources/colorgroup[0]/color[2]", nil, false},
+		{"/model/resources/colorgroup[0]/multi[0]", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.xpath, func(t *testing.T) {
+			got, ok := m.Resolve("", tt.xpath)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("Spec.Resolve() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
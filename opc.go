@@ -4,63 +4,409 @@
 package go3mf
 
 import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"mime"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/qmuntal/opc"
 )
 
+const (
+	contentTypesName         = "[Content_Types].xml"
+	contentTypeRelationships = "application/vnd.openxmlformats-package.relationships+xml"
+	nsContentTypes           = "http://schemas.openxmlformats.org/package/2006/content-types"
+	nsRelationships          = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+// deterministicTime is the modification time of the zip entries in deterministic mode,
+// which is the earliest time that can be represented in the zip format.
+var deterministicTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 type opcPart struct {
 	io.Writer
-	Part *opc.Part
+	name string
+	rels []Relationship
 }
 
 func (o *opcPart) AddRelationship(r Relationship) {
-	for _, ro := range o.Part.Relationships {
-		if ro.Type == r.Type && ro.TargetURI == r.Path {
-			return
-		}
-	}
-	o.Part.Relationships = append(o.Part.Relationships, &opc.Relationship{
-		ID:        r.ID,
-		Type:      r.Type,
-		TargetURI: r.Path,
-	})
+	o.rels = appendRelationship(o.rels, r)
 }
 
+// opcWriter writes an OPC package directly to a zip archive.
+// The qmuntal/opc writer is not used as it always deflates the parts,
+// even with CompressionNone, stamps the entries with the current time,
+// does not accept a custom compressor and does not expose the
+// relationships parts, which are needed to sign the package.
 type opcWriter struct {
-	w *opc.Writer
+	w             *zip.Writer
+	compressor    func(w io.Writer, level int) (io.WriteCloser, error)
+	level         int  // compression level of the part being created
+	registered    bool // compress is registered in w
+	deterministic bool
+	parts         map[string]struct{} // upper case part names
+	dirs          map[string]struct{} // upper case part name prefixes ending at a segment
+	defaults      map[string]string   // extension:content type
+	overrides     map[string]string   // part name:content type
+	rels          []Relationship
+	written       []relationshipsPart
+	last          *opcPart
+}
+
+// relationshipsPart holds the relationships stored in the relationships part name.
+type relationshipsPart struct {
+	name string
+	rels []Relationship
 }
 
 func newOpcWriter(w io.Writer) *opcWriter {
-	return &opcWriter{opc.NewWriter(w)}
+	return &opcWriter{
+		w:         zip.NewWriter(w),
+		parts:     make(map[string]struct{}),
+		dirs:      make(map[string]struct{}),
+		defaults:  map[string]string{"rels": contentTypeRelationships},
+		overrides: make(map[string]string),
+	}
 }
 
-func (o *opcWriter) Create(name, contentType string) (packagePart, error) {
-	p := &opc.Part{Name: opc.NormalizePartName(name), ContentType: contentType}
-	w, err := o.w.CreatePart(p, opc.CompressionNormal)
+// compress deflates a zip entry with the level of the part being created,
+// as the zip writer calls it from CreateHeader.
+func (o *opcWriter) compress(w io.Writer) (io.WriteCloser, error) {
+	if o.compressor != nil {
+		return o.compressor(w, o.level)
+	}
+	return flate.NewWriter(w, o.level)
+}
+
+func (o *opcWriter) Create(name, contentType string, c Compression) (packagePart, error) {
+	if err := o.closeLastPart(); err != nil {
+		return nil, err
+	}
+	p := &opcPart{name: opc.NormalizePartName(name)}
+	w, err := o.create(p.name, contentType, c)
 	if err != nil {
 		return nil, err
 	}
-	return &opcPart{Writer: w, Part: p}, nil
+	p.Writer = w
+	o.last = p
+	return p, nil
 }
 
 func (o *opcWriter) AddRelationship(r Relationship) {
-	for _, ro := range o.w.Relationships {
-		if ro.Type == r.Type && ro.TargetURI == r.Path {
-			return
-		}
-	}
-	o.w.Relationships = append(o.w.Relationships, &opc.Relationship{
-		ID:        r.ID,
-		Type:      r.Type,
-		TargetURI: r.Path,
-	})
+	o.rels = appendRelationship(o.rels, r)
 }
 
 func (o *opcWriter) Close() error {
+	err := o.closeLastPart()
+	if err == nil {
+		err = o.writeRelationships(relationshipsPartName("/"), o.rels)
+	}
+	if err == nil {
+		err = o.writeContentTypes()
+	}
+	if err != nil {
+		o.w.Close()
+		return err
+	}
 	return o.w.Close()
 }
 
+func (o *opcWriter) create(name, contentType string, c Compression) (io.Writer, error) {
+	if err := validatePartName(name); err != nil {
+		return nil, err
+	}
+	if t, _, err := mime.ParseMediaType(contentType); err != nil || !strings.Contains(t, "/") {
+		return nil, fmt.Errorf("%s: invalid content type %q", name, contentType)
+	}
+	key := strings.ToUpper(name)
+	if _, ok := o.parts[key]; ok {
+		return nil, fmt.Errorf("%s: duplicated part name", name)
+	}
+	if _, ok := o.dirs[key]; ok {
+		return nil, fmt.Errorf("%s: part name is a prefix of another part name", name)
+	}
+	for i := strings.IndexByte(key[1:], '/') + 1; i > 0; i = nextSegment(key, i) {
+		if _, ok := o.parts[key[:i]]; ok {
+			return nil, fmt.Errorf("%s: part name derived from the part name %s", name, key[:i])
+		}
+	}
+	w, err := o.createHeader(name[1:], c)
+	if err != nil {
+		return nil, err
+	}
+	o.parts[key] = struct{}{}
+	for i := strings.IndexByte(key[1:], '/') + 1; i > 0; i = nextSegment(key, i) {
+		o.dirs[key[:i]] = struct{}{}
+	}
+	o.addContentType(name, contentType)
+	return w, nil
+}
+
+// nextSegment returns the index of the slash ending the segment after i, or 0 if it is the last one.
+func nextSegment(name string, i int) int {
+	if j := strings.IndexByte(name[i+1:], '/'); j != -1 {
+		return i + 1 + j
+	}
+	return 0
+}
+
+// validatePartName checks the part name syntax defined in ISO/IEC 29500-2 §9.1.1.1.
+func validatePartName(name string) error {
+	if name == "" {
+		return errors.New("a part name shall not be empty")
+	}
+	if name[0] != '/' {
+		return fmt.Errorf("%s: a part name shall start with a forward slash character", name)
+	}
+	if strings.HasSuffix(name, "/") {
+		return fmt.Errorf("%s: a part name shall not have a forward slash as the last character", name)
+	}
+	for _, seg := range strings.Split(name[1:], "/") {
+		if seg == "" {
+			return fmt.Errorf("%s: a part name shall not have empty segments", name)
+		}
+		if strings.HasSuffix(seg, ".") {
+			return fmt.Errorf("%s: a part name segment shall not end with a dot character", name)
+		}
+	}
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c == '%':
+			if i+2 >= len(name) || !isHex(name[i+1]) || !isHex(name[i+2]) {
+				return fmt.Errorf("%s: a part name shall only hold valid percent-encoded characters", name)
+			}
+			switch d := unhex(name[i+1])<<4 | unhex(name[i+2]); {
+			case d == '/' || d == '\\':
+				return fmt.Errorf("%s: a part name segment shall not contain percent-encoded forward slash or backward slash characters", name)
+			case isUnreserved(d):
+				return fmt.Errorf("%s: a part name segment shall not contain percent-encoded unreserved characters", name)
+			}
+			i += 2
+		case c < 0x80 && c != '/' && !isUnreserved(c) && !strings.ContainsRune("!$&'()*+,;=:@", rune(c)):
+			return fmt.Errorf("%s: a part name segment shall not hold any characters other than pchar characters", name)
+		}
+	}
+	return nil
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) != -1
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+func (o *opcWriter) createHeader(name string, c Compression) (io.Writer, error) {
+	fh := &zip.FileHeader{Name: name, Modified: time.Now()}
+	if o.deterministic {
+		fh.Modified = deterministicTime
+	}
+	if c == CompressionNone {
+		fh.Method = zip.Store
+		return o.w.CreateHeader(fh)
+	}
+	fh.Method = zip.Deflate
+	fh.Flags |= c.flags()
+	if !o.registered {
+		o.w.RegisterCompressor(zip.Deflate, o.compress)
+		o.registered = true
+	}
+	o.level = c.level()
+	return o.w.CreateHeader(fh)
+}
+
+func (o *opcWriter) addContentType(name, contentType string) {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		o.overrides[name] = contentType
+		return
+	}
+	ext = ext[1:]
+	if ct, ok := o.defaults[ext]; !ok {
+		o.defaults[ext] = contentType
+	} else if ct != contentType {
+		o.overrides[name] = contentType
+	}
+}
+
+func (o *opcWriter) closeLastPart() error {
+	if o.last == nil {
+		return nil
+	}
+	p := o.last
+	o.last = nil
+	return o.writeRelationships(relationshipsPartName(p.name), p.rels)
+}
+
+// relationshipsParts closes the last part and returns the relationships parts
+// written so far followed by the package relationships, whose IDs are assigned
+// so they do not change when the package relationships part is written.
+func (o *opcWriter) relationshipsParts() ([]relationshipsPart, error) {
+	if err := o.closeLastPart(); err != nil {
+		return nil, err
+	}
+	parts := make([]relationshipsPart, len(o.written), len(o.written)+1)
+	copy(parts, o.written)
+	if len(o.rels) > 0 {
+		o.assignIDs(o.rels)
+		rels := make([]Relationship, len(o.rels))
+		copy(rels, o.rels)
+		parts = append(parts, relationshipsPart{name: relationshipsPartName("/"), rels: rels})
+	}
+	return parts, nil
+}
+
+func (o *opcWriter) writeRelationships(name string, rels []Relationship) error {
+	if len(rels) == 0 {
+		return nil
+	}
+	o.assignIDs(rels)
+	rx := relationshipsXML{XMLNS: nsRelationships, Relationships: make([]relationshipXML, len(rels))}
+	for i, r := range rels {
+		rx.Relationships[i] = relationshipXML{ID: r.ID, Type: r.Type, Target: r.Path}
+	}
+	w, err := o.create(name, contentTypeRelationships, CompressionNormal)
+	if err != nil {
+		return err
+	}
+	if err = encodeXMLPart(w, rx); err != nil {
+		return err
+	}
+	o.written = append(o.written, relationshipsPart{name: name, rels: rels})
+	return nil
+}
+
+// assignIDs sets the ID of the relationships without one.
+func (o *opcWriter) assignIDs(rels []Relationship) {
+	ids := make(map[string]struct{}, len(rels))
+	for _, r := range rels {
+		if r.ID != "" {
+			ids[r.ID] = struct{}{}
+		}
+	}
+	var n int
+	for i := range rels {
+		r := &rels[i]
+		for k := 0; r.ID == ""; k++ {
+			id := "rId" + strconv.Itoa(n)
+			if o.deterministic {
+				id = contentRelationshipID(*r, k)
+			} else {
+				n++
+			}
+			if _, ok := ids[id]; !ok {
+				r.ID = id
+				ids[id] = struct{}{}
+			}
+		}
+	}
+}
+
+func (o *opcWriter) writeContentTypes() error {
+	tx := contentTypesXML{XMLNS: nsContentTypes}
+	for ext, ct := range o.defaults {
+		tx.Defaults = append(tx.Defaults, defaultXML{Extension: ext, ContentType: ct})
+	}
+	for name, ct := range o.overrides {
+		tx.Overrides = append(tx.Overrides, overrideXML{PartName: name, ContentType: ct})
+	}
+	sort.Slice(tx.Defaults, func(i, j int) bool { return tx.Defaults[i].Extension < tx.Defaults[j].Extension })
+	sort.Slice(tx.Overrides, func(i, j int) bool { return tx.Overrides[i].PartName < tx.Overrides[j].PartName })
+	w, err := o.createHeader(contentTypesName, CompressionNormal)
+	if err != nil {
+		return err
+	}
+	return encodeXMLPart(w, tx)
+}
+
+// contentRelationshipID returns an ID derived from the type and the target of r,
+// so it does not depend on the rest of relationships.
+// k is only used to resolve collisions.
+func contentRelationshipID(r Relationship, k int) string {
+	h := sha1.New()
+	io.WriteString(h, r.Type)
+	h.Write([]byte{0})
+	io.WriteString(h, r.Path)
+	if k > 0 {
+		h.Write([]byte{0})
+		io.WriteString(h, strconv.Itoa(k))
+	}
+	return "R" + hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// relationshipsPartName returns the name of the relationships part of source,
+// being "/" for the package relationships.
+func relationshipsPartName(source string) string {
+	dir, file := path.Split(source)
+	return dir + "_rels/" + file + ".rels"
+}
+
+func appendRelationship(rels []Relationship, r Relationship) []Relationship {
+	for _, ro := range rels {
+		if ro.Type == r.Type && ro.Path == r.Path {
+			return rels
+		}
+	}
+	return append(rels, r)
+}
+
+func encodeXMLPart(w io.Writer, v interface{}) error {
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+type relationshipsXML struct {
+	XMLName       xml.Name          `xml:"Relationships"`
+	XMLNS         string            `xml:"xmlns,attr"`
+	Relationships []relationshipXML `xml:"Relationship"`
+}
+
+type relationshipXML struct {
+	ID     string `xml:"Id,attr"`
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+type contentTypesXML struct {
+	XMLName   xml.Name      `xml:"Types"`
+	XMLNS     string        `xml:"xmlns,attr"`
+	Defaults  []defaultXML  `xml:"Default"`
+	Overrides []overrideXML `xml:"Override"`
+}
+
+type defaultXML struct {
+	Extension   string `xml:"Extension,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
+type overrideXML struct {
+	PartName    string `xml:"PartName,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
 func newRelationships(rels []*opc.Relationship) []Relationship {
 	pr := make([]Relationship, len(rels))
 	for i, r := range rels {
@@ -118,6 +464,57 @@ func (o *opcReader) FindFileFromName(name string) (packageFile, bool) {
 	return findOPCFileFromName(name, o.r)
 }
 
+// CoreProperties returns the core properties of the package.
+// The returned error reports the first invalid date, which is left unset.
+func (o *opcReader) CoreProperties() (*CoreProperties, error) {
+	p := o.r.Properties
+	props := &CoreProperties{
+		Category:       p.Category,
+		ContentStatus:  p.ContentStatus,
+		Creator:        p.Creator,
+		Description:    p.Description,
+		Identifier:     p.Identifier,
+		Keywords:       p.Keywords,
+		Language:       p.Language,
+		LastModifiedBy: p.LastModifiedBy,
+		Revision:       p.Revision,
+		Subject:        p.Subject,
+		Title:          p.Title,
+		Version:        p.Version,
+	}
+	var errs [3]error
+	props.Created, errs[0] = parseW3CDTF("created", p.Created)
+	props.LastPrinted, errs[1] = parseW3CDTF("lastPrinted", p.LastPrinted)
+	props.Modified, errs[2] = parseW3CDTF("modified", p.Modified)
+	for _, err := range errs {
+		if err != nil {
+			return props, err
+		}
+	}
+	return props, nil
+}
+
+// relationshipsOf returns the relationships stored in the relationships part name.
+func (o *opcReader) relationshipsOf(name string) ([]*opc.Relationship, bool) {
+	source, ok := relationshipsSource(name)
+	if !ok {
+		return nil, false
+	}
+	if source == "/" {
+		return o.r.Relationships, true
+	}
+	for _, f := range o.r.Files {
+		if strings.EqualFold(f.Name, source) {
+			return f.Relationships, true
+		}
+	}
+	return nil, false
+}
+
+func normalizePartName(name string) string {
+	return opc.NormalizePartName(name)
+}
+
 func resolveRelationship(source, rel string) string {
 	return opc.ResolveRelationship(source, rel)
 }
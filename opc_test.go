@@ -4,6 +4,10 @@
 package go3mf
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"io"
 	"reflect"
 	"testing"
 
@@ -121,18 +125,209 @@ func Test_opcWriter_AddRelationship(t *testing.T) {
 		name string
 		o    *opcWriter
 		args args
-		want []*opc.Relationship
+		want []Relationship
 	}{
-		{"base", newOpcWriter(nil), args{Relationship{ID: "id_1", Path: "fake_uri", Type: "fake_type"}}, []*opc.Relationship{
-			{ID: "id_1", TargetURI: "fake_uri", Type: "fake_type"},
+		{"base", newOpcWriter(nil), args{Relationship{ID: "id_1", Path: "fake_uri", Type: "fake_type"}}, []Relationship{
+			{ID: "id_1", Path: "fake_uri", Type: "fake_type"},
+		}},
+		{"duplicated", &opcWriter{rels: []Relationship{{Path: "fake_uri", Type: "fake_type"}}}, args{Relationship{ID: "id_1", Path: "fake_uri", Type: "fake_type"}}, []Relationship{
+			{Path: "fake_uri", Type: "fake_type"},
 		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.o.AddRelationship(tt.args.r)
-			if !reflect.DeepEqual(tt.o.w.Relationships, tt.want) {
-				t.Errorf("opcWriter.AddRelationship() = %v, want %v", tt.o.w.Relationships, tt.want)
+			if !reflect.DeepEqual(tt.o.rels, tt.want) {
+				t.Errorf("opcWriter.AddRelationship() = %v, want %v", tt.o.rels, tt.want)
 			}
 		})
 	}
 }
+
+func Test_opcWriter_AddRelationship_Written(t *testing.T) {
+	var buf bytes.Buffer
+	o := newOpcWriter(&buf)
+	o.AddRelationship(Relationship{ID: "id_1", Path: "/a.model", Type: RelType3DModel})
+	p, err := o.Create("/a.model", ContentType3DModel, CompressionNormal)
+	if err != nil {
+		t.Fatalf("opcWriter.Create() error = %v", err)
+	}
+	p.AddRelationship(Relationship{ID: "id_2", Path: "/b.png", Type: RelTypeThumbnail})
+	p.AddRelationship(Relationship{ID: "id_3", Path: "/b.png", Type: RelTypeThumbnail})
+	if _, err = o.Create("/b.png", "image/png", CompressionNone); err != nil {
+		t.Fatalf("opcWriter.Create() error = %v", err)
+	}
+	if err = o.Close(); err != nil {
+		t.Fatalf("opcWriter.Close() error = %v", err)
+	}
+	r, err := opc.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("opc.NewReader() error = %v", err)
+	}
+	want := []*opc.Relationship{{ID: "id_1", TargetURI: "/a.model", Type: RelType3DModel}}
+	if !reflect.DeepEqual(r.Relationships, want) {
+		t.Errorf("opcWriter.AddRelationship() package = %v, want %v", r.Relationships, want)
+	}
+	want = []*opc.Relationship{{ID: "id_2", TargetURI: "/b.png", Type: RelTypeThumbnail}}
+	if !reflect.DeepEqual(r.Files[0].Relationships, want) {
+		t.Errorf("opcPart.AddRelationship() = %v, want %v", r.Files[0].Relationships, want)
+	}
+}
+
+func Test_validatePartName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"/3D/3dmodel.model", false},
+		{"/a%20b/c%C3%B1.png", false},
+		{"/ñ.png", false},
+		{"", true},
+		{"a.png", true},
+		{"/a/", true},
+		{"//a.png", true},
+		{"/a./b.png", true},
+		{"/a/../b.png", true},
+		{"/a/%2F.png", true},
+		{"/a/%5c.png", true},
+		{"/a%41.png", true},
+		{"/a%2.png", true},
+		{"/a b.png", true},
+		{"/a#b.png", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePartName(tt.name); (err != nil) != tt.wantErr {
+				t.Errorf("validatePartName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_opcWriter_Create(t *testing.T) {
+	type args struct {
+		name        string
+		contentType string
+		c           Compression
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantMethod uint16
+		wantFlags  uint16
+		wantErr    bool
+	}{
+		{"emptyName", args{"", "text/plain", CompressionNormal}, 0, 0, true},
+		{"invalidContentType", args{"/a.txt", "text", CompressionNormal}, 0, 0, true},
+		{"duplicated", args{"/A.TXT", "text/plain", CompressionNormal}, 0, 0, true},
+		{"equivalent", args{"/a%2Etxt", "text/plain", CompressionNormal}, 0, 0, true},
+		{"encodedSlash", args{"/a/%2F.png", "image/png", CompressionNormal}, 0, 0, true},
+		{"dotSegment", args{"/a/b.", "image/png", CompressionNormal}, 0, 0, true},
+		{"derived", args{"/a.txt/b.png", "image/png", CompressionNormal}, 0, 0, true},
+		{"prefix", args{"/c", "image/png", CompressionNormal}, 0, 0, true},
+		{"normal", args{"/b.txt", "text/plain", CompressionNormal}, zip.Deflate, 0, false},
+		{"none", args{"/b.png", "image/png", CompressionNone}, zip.Store, 0, false},
+		{"maximum", args{"/b.txt", "text/plain", CompressionMaximum}, zip.Deflate, 0x2, false},
+		{"fast", args{"/b.txt", "text/plain", CompressionFast}, zip.Deflate, 0x4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			o := newOpcWriter(&buf)
+			if _, err := o.Create("/a.txt", "text/plain", CompressionNormal); err != nil {
+				t.Fatalf("opcWriter.Create() error = %v", err)
+			}
+			if _, err := o.Create("/c/d.txt", "text/plain", CompressionNormal); err != nil {
+				t.Fatalf("opcWriter.Create() error = %v", err)
+			}
+			w, err := o.Create(tt.args.name, tt.args.contentType, tt.args.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("opcWriter.Create() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if _, err = w.Write([]byte("content")); err != nil {
+				t.Fatalf("opcPart.Write() error = %v", err)
+			}
+			if err = o.Close(); err != nil {
+				t.Fatalf("opcWriter.Close() error = %v", err)
+			}
+			r, err := opc.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("opc.NewReader() error = %v", err)
+			}
+			zr, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			for _, f := range zr.File {
+				if "/"+f.Name != tt.args.name {
+					continue
+				}
+				if f.Method != tt.wantMethod || f.Flags&0x6 != tt.wantFlags {
+					t.Errorf("opcWriter.Create() method = %v, flags = %v, want %v, %v", f.Method, f.Flags&0x6, tt.wantMethod, tt.wantFlags)
+				}
+			}
+			if len(r.Files) != 3 || r.Files[2].ContentType != tt.args.contentType {
+				t.Errorf("opcWriter.Create() files = %v", r.Files)
+			}
+		})
+	}
+}
+
+func Test_opcWriter_Close(t *testing.T) {
+	var buf bytes.Buffer
+	o := newOpcWriter(&buf)
+	var levels []int
+	o.compressor = func(w io.Writer, level int) (io.WriteCloser, error) {
+		levels = append(levels, level)
+		return flate.NewWriter(w, level)
+	}
+	p, err := o.Create("/3D/a.model", ContentType3DModel, CompressionFast)
+	if err != nil {
+		t.Fatalf("opcWriter.Create() error = %v", err)
+	}
+	p.AddRelationship(Relationship{Path: "/3D/b.model", Type: RelType3DModel})
+	if _, err = o.Create("/3D/b.model", ContentType3DModel, CompressionNone); err != nil {
+		t.Fatalf("opcWriter.Create() error = %v", err)
+	}
+	o.AddRelationship(Relationship{ID: "rId0", Path: "/3D/a.model", Type: RelType3DModel})
+	o.AddRelationship(Relationship{Path: "/3D/c.model", Type: RelType3DModel})
+	if err = o.Close(); err != nil {
+		t.Fatalf("opcWriter.Close() error = %v", err)
+	}
+	if want := []int{flate.BestSpeed, flate.DefaultCompression, flate.DefaultCompression, flate.DefaultCompression}; !reflect.DeepEqual(levels, want) {
+		t.Errorf("opcWriter.Close() compressor levels = %v, want %v", levels, want)
+	}
+	r, err := opc.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("opc.NewReader() error = %v", err)
+	}
+	want := []*opc.Relationship{
+		{ID: "rId0", TargetURI: "/3D/a.model", Type: RelType3DModel},
+		{ID: "rId1", TargetURI: "/3D/c.model", Type: RelType3DModel},
+	}
+	if !reflect.DeepEqual(r.Relationships, want) {
+		t.Errorf("opcWriter.Close() relationships = %v, want %v", r.Relationships, want)
+	}
+	wantPart := []*opc.Relationship{{ID: "rId0", TargetURI: "/3D/b.model", Type: RelType3DModel}}
+	if !reflect.DeepEqual(r.Files[0].Relationships, wantPart) {
+		t.Errorf("opcWriter.Close() part relationships = %v, want %v", r.Files[0].Relationships, wantPart)
+	}
+}
+
+func Test_contentRelationshipID(t *testing.T) {
+	r := Relationship{Path: "/3D/a.model", Type: RelType3DModel}
+	got := contentRelationshipID(r, 0)
+	if got != contentRelationshipID(Relationship{Path: "/3D/a.model", Type: RelType3DModel, ID: "a"}, 0) {
+		t.Error("contentRelationshipID() depends on the ID")
+	}
+	if got == contentRelationshipID(r, 1) {
+		t.Error("contentRelationshipID() does not resolve collisions")
+	}
+	if got == contentRelationshipID(Relationship{Path: "/3D/b.model", Type: RelType3DModel}, 0) {
+		t.Error("contentRelationshipID() does not depend on the target")
+	}
+	if len(got) != 17 || got[0] != 'R' {
+		t.Errorf("contentRelationshipID() = %v, want an xsd:ID", got)
+	}
+}
@@ -0,0 +1,62 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+type parallelismKey struct{}
+
+// WithParallelism returns a copy of ctx that bounds to n the goroutines used by
+// the model-wide operations called with it, such as Model.BoundingBoxContext,
+// Model.ValidateContext, Model.ValidateCoherencyContext and Decoder.DecodeContext.
+// Zero or negative means runtime.GOMAXPROCS(0), which is also the default.
+func WithParallelism(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, parallelismKey{}, n)
+}
+
+// parallelism returns the number of workers to use
+// when n is the requested parallelism.
+func parallelism(ctx context.Context, n int) int {
+	if n < 1 {
+		n, _ = ctx.Value(parallelismKey{}).(int)
+	}
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return n
+}
+
+// forEach calls fn for each index in [0, n) from at most workers goroutines,
+// zero meaning the parallelism set in ctx with WithParallelism.
+// It stops calling fn once ctx is done, in which case it returns ctx.Err().
+func forEach(ctx context.Context, workers, n int, fn func(i int)) error {
+	workers = parallelism(ctx, workers)
+	if workers > n {
+		workers = n
+	}
+	var (
+		wg   sync.WaitGroup
+		next int64 = -1
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n || ctx.Err() != nil {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
@@ -0,0 +1,78 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_parallelism(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		n    int
+		want int
+	}{
+		{"requested", WithParallelism(context.Background(), 3), 2, 2},
+		{"context", WithParallelism(context.Background(), 3), 0, 3},
+		{"default", context.Background(), 0, runtime.GOMAXPROCS(0)},
+		{"negative", WithParallelism(context.Background(), -1), -1, runtime.GOMAXPROCS(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parallelism(tt.ctx, tt.n); got != tt.want {
+				t.Errorf("parallelism() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_forEach(t *testing.T) {
+	const n = 100
+	var (
+		calls         [n]int32
+		running, peak int32
+	)
+	err := forEach(context.Background(), 4, n, func(i int) {
+		r := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if r <= p || atomic.CompareAndSwapInt32(&peak, p, r) {
+				break
+			}
+		}
+		atomic.AddInt32(&calls[i], 1)
+		atomic.AddInt32(&running, -1)
+	})
+	if err != nil {
+		t.Fatalf("forEach() error = %v", err)
+	}
+	for i, c := range calls {
+		if c != 1 {
+			t.Errorf("forEach() called %d %d times, want 1", i, c)
+		}
+	}
+	if peak > 4 {
+		t.Errorf("forEach() ran %d goroutines, want at most 4", peak)
+	}
+}
+
+func Test_forEach_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	err := forEach(ctx, 1, 100, func(i int) {
+		if atomic.AddInt32(&calls, 1) == 10 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("forEach() error = %v, want %v", err, context.Canceled)
+	}
+	if calls != 10 {
+		t.Errorf("forEach() calls = %d, want 10", calls)
+	}
+}
@@ -0,0 +1,101 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hpinc/go3mf/errors"
+)
+
+// position locates an element in a model part.
+type position struct {
+	line, column int
+}
+
+// positionIndex records where the decoded elements are located,
+// so validation errors can point at them.
+//
+// The keys are the part name followed by the indexed levels of the element XPath
+// and its own name when not indexed, which makes them independent
+// of the grouping elements, such as vertices or triangles,
+// that the decoder sees but the validation XPaths omit.
+type positionIndex struct {
+	mu        sync.Mutex
+	positions map[string]position
+}
+
+func newPositionIndex() *positionIndex {
+	return &positionIndex{positions: make(map[string]position)}
+}
+
+func (p *positionIndex) add(key string, line, column int) {
+	p.mu.Lock()
+	p.positions[key] = position{line, column}
+	p.mu.Unlock()
+}
+
+// find returns the position of the element at levels,
+// or of its closest known ancestor other than the root element,
+// as errors about content outside the model part, such as relationships,
+// are reported on the root.
+func (p *positionIndex) find(path string, levels []errors.Level) (position, bool) {
+	for n := len(levels); n > 0; n-- {
+		if n == 1 && len(levels) > 1 {
+			break
+		}
+		if pos, ok := p.positions[positionKey(path, levels[:n])]; ok {
+			return pos, true
+		}
+	}
+	return position{}, false
+}
+
+// annotate sets the position of the errors in err whose position is unknown.
+// rootPath is used for the errors without path.
+func (p *positionIndex) annotate(err error, rootPath string) {
+	switch e := err.(type) {
+	case *errors.Error:
+		if e.Line != 0 {
+			return
+		}
+		path := e.Path
+		if path == "" {
+			path = rootPath
+		}
+		levels := make([]errors.Level, len(e.Target))
+		for i, l := range e.Target {
+			levels[len(e.Target)-i-1] = l
+		}
+		if pos, ok := p.find(path, levels); ok {
+			errors.WithPosition(e, path, pos.line, pos.column)
+		}
+	case *errors.List:
+		for _, e1 := range e.Errors {
+			p.annotate(e1, rootPath)
+		}
+	}
+}
+
+// positionKey returns the key of the element at levels, from the root.
+func positionKey(path string, levels []errors.Level) string {
+	var sb strings.Builder
+	sb.WriteString(path)
+	last := len(levels) - 1
+	for i, l := range levels {
+		if l.Index == -1 && i != last && i != 0 {
+			continue
+		}
+		sb.WriteByte('/')
+		sb.WriteString(l.Name)
+		if l.Index != -1 {
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Itoa(l.Index))
+			sb.WriteByte(']')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,114 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	specerr "github.com/hpinc/go3mf/errors"
+)
+
+func TestModel_Validate_Positions(t *testing.T) {
+	rootFile := `
+		<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+		<resources>
+			<object id="1">
+				<mesh>
+					<vertices>
+						<vertex x="0" y="0" z="0" />
+						<vertex x="1" y="0" z="0" />
+						<vertex x="0" y="1" z="0" />
+					</vertices>
+					<triangles>
+						<triangle v1="0" v2="1" v3="2" />
+						<triangle v1="0" v2="0" v3="2" />
+					</triangles>
+				</mesh>
+			</object>
+		</resources>
+		<build>
+			<item objectid="5" />
+		</build>
+		</model>`
+	m := &Model{positions: newPositionIndex(), Relationships: []Relationship{{Path: "/a.png"}}}
+	if err := UnmarshalModel([]byte(rootFile), m); err != nil {
+		t.Fatalf("UnmarshalModel() error = %v", err)
+	}
+	err := m.Validate()
+	want := map[string][2]int{
+		"/model/relationship[0]":                      {0, 0},
+		"/model/resources/object[0]/mesh":             {5, 5},
+		"/model/resources/object[0]/mesh/triangle[1]": {13, 7},
+		"/model/build/item[0]":                        {19, 4},
+	}
+	var list *specerr.List
+	if !errors.As(err, &list) {
+		t.Fatalf("Model.Validate() = %v", err)
+	}
+	for _, err := range list.Errors {
+		e := err.(*specerr.Error)
+		pos, ok := want[e.XPath()]
+		if !ok {
+			continue
+		}
+		delete(want, e.XPath())
+		if e.Line != pos[0] || e.Column != pos[1] {
+			t.Errorf("Model.Validate() %s = %d, %d, want %d, %d", e.XPath(), e.Line, e.Column, pos[0], pos[1])
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("Model.Validate() missing errors %v", want)
+	}
+}
+
+func TestDecoder_Decode_TrackPositions(t *testing.T) {
+	invalid := &Object{ID: 1, Mesh: &Mesh{
+		Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+		Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 0, V3: 2}}},
+	}}
+	m := &Model{Childs: map[string]*ChildModel{"/3D/other.model": {Resources: Resources{Objects: []*Object{invalid}}}}}
+	var buff bytes.Buffer
+	if err := NewEncoder(&buff).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	for _, track := range []bool{false, true} {
+		d := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+		d.TrackPositions = track
+		got := new(Model)
+		if err := d.Decode(got); err != nil {
+			t.Fatalf("Decoder.Decode() error = %v", err)
+		}
+		var e *specerr.Error
+		if !errors.As(got.Validate(), &e) {
+			t.Fatal("Model.Validate() expected error")
+		}
+		if e.Path != "/3D/other.model" || (e.Line > 0) != track {
+			t.Errorf("Model.Validate() TrackPositions=%v = %v", track, e)
+		}
+	}
+}
+
+func Test_positionKey(t *testing.T) {
+	l := func(name string, index int) specerr.Level {
+		return specerr.Level{Name: name, Index: index}
+	}
+	tests := []struct {
+		levels []specerr.Level
+		want   string
+	}{
+		{[]specerr.Level{l("model", -1)}, "/a.model/model"},
+		{[]specerr.Level{l("model", -1), l("build", -1)}, "/a.model/model/build"},
+		{[]specerr.Level{l("model", -1), l("resources", -1), l("object", 2), l("mesh", -1), l("triangles", -1), l("triangle", 3)}, "/a.model/model/object[2]/triangle[3]"},
+		{[]specerr.Level{l("model", -1), l("resources", -1), l("object", 2), l("mesh", -1), l("triangle", 3)}, "/a.model/model/object[2]/triangle[3]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := positionKey("/a.model", tt.levels); got != tt.want {
+				t.Errorf("positionKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,9 +4,8 @@
 package production
 
 import (
-	"errors"
-
 	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/errors"
 	"github.com/hpinc/go3mf/spec"
 	"github.com/hpinc/go3mf/uuid"
 )
@@ -21,8 +20,8 @@ var DefaultExtension = go3mf.Extension{
 }
 
 var (
-	ErrUUID             = errors.New("UUID MUST be any of the four UUID variants described in IETF RFC 4122")
-	ErrProdRefInNonRoot = errors.New("non-root model file components MUST only reference objects in the same model file")
+	ErrUUID             = errors.NewRule("production-3-uuid", errors.SeverityError, "UUID MUST be any of the four UUID variants described in IETF RFC 4122")
+	ErrProdRefInNonRoot = errors.NewRule("production-3.4-component-path", errors.SeverityError, "non-root model file components MUST only reference objects in the same model file")
 )
 
 const (
@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -34,6 +35,9 @@ type packageReader interface {
 	Open(func(r io.Reader) io.ReadCloser) error
 	FindFileFromName(string) (packageFile, bool)
 	Relationships() []Relationship
+	CoreProperties() (*CoreProperties, error)
+	Inspect(Limits) (*PackageInfo, error)
+	Parts() ([]PartInfo, error)
 }
 
 // ReadCloser wrapps a Decoder than can be closed.
@@ -57,11 +61,12 @@ func OpenReader(name string) (*ReadCloser, error) {
 }
 
 // Close closes the 3MF file, rendering it unusable for I/O.
+// Attachments not buffered while decoding can no longer be opened.
 func (r *ReadCloser) Close() error {
 	return r.f.Close()
 }
 
-func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string, isRoot, strict bool) error {
+func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string, isRoot, strict bool, h *ModelHandler, l *limiter) error {
 	x := xml3mf.NewDecoder(r)
 	type stackElement struct {
 		decoder spec.ElementDecoder
@@ -74,23 +79,41 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 		currentDecoder spec.ElementDecoder
 		currentName    xml.Name
 		errs           specerr.List
+		limits         = modelLimiter{l: l, path: path}
+		limitErr       error
 	)
-	currentDecoder = &topLevelDecoder{isRoot: isRoot, model: model, path: path}
+	var handler *decodeHandler
+	if h != nil {
+		handler = &decodeHandler{h: h, path: path}
+	}
+	currentDecoder = &topLevelDecoder{isRoot: isRoot, model: model, path: path, handler: handler}
 	var err error
 	x.OnStart = func(tp xml3mf.StartElement) {
+		if limitErr = limits.start(tp); limitErr != nil {
+			return
+		}
 		if childDecoder, ok := currentDecoder.(spec.ChildElementDecoder); ok {
 			i, tmpDecoder := childDecoder.Child(tp.Name)
 			if tmpDecoder != nil {
 				stack = append(stack, stackElement{tmpDecoder, tp.Name, i})
 				currentName = tp.Name
 				currentDecoder = tmpDecoder
+				if model.positions != nil {
+					levels := make([]specerr.Level, len(stack))
+					for j, element := range stack {
+						levels[j] = specerr.Level{Name: element.name.Local, Index: element.i}
+					}
+					line, column := x.TokenPos()
+					model.positions.add(positionKey(path, levels), line, column)
+				}
 				err := currentDecoder.Start(*(*[]spec.XMLAttr)(unsafe.Pointer(&tp.Attr)))
 				if err != nil {
 					for j := len(stack) - 1; j >= 0; j-- {
 						element := stack[j]
 						err = specerr.WrapIndex(err, element.name.Local, element.i)
 					}
-					specerr.Append(&errs, err)
+					line, column := x.TokenPos()
+					specerr.Append(&errs, specerr.WithPosition(err, path, line, column))
 				}
 			}
 		} else if appendDecoder, ok := currentDecoder.(spec.AppendTokenElementDecoder); ok {
@@ -108,6 +131,7 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 		}
 	}
 	x.OnEnd = func(tp xml.EndElement) {
+		limits.end()
 		if currentName == tp.Name {
 			currentDecoder.End()
 			stack = stack[:len(stack)-1]
@@ -130,7 +154,7 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 	var i int
 	for {
 		err = x.RawToken()
-		if err != nil || (strict && errs.Len() != 0) {
+		if err != nil || limitErr != nil || (strict && errs.Len() != 0) || (handler != nil && handler.err != nil) {
 			break
 		}
 		if i%checkEveryTokens == 0 {
@@ -148,6 +172,12 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 	if err == io.EOF {
 		err = nil
 	}
+	if err == nil && limitErr != nil {
+		err = limitErr
+	}
+	if err == nil && handler != nil && handler.err != nil {
+		err = handler.err
+	}
 	if err == nil && errs.Len() != 0 {
 		if strict || errs.Len() == 1 {
 			err = errs.Unwrap()
@@ -158,12 +188,69 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 	return err
 }
 
+// A PartDecrypter decrypts the encrypted parts of a package while decoding.
+type PartDecrypter interface {
+	// Init is called once the package is opened, before reading any part.
+	// rels are the package relationships and open returns
+	// the raw content of the part with the given name.
+	Init(rels []Relationship, open func(name string) (io.ReadCloser, error)) error
+	// Decrypt returns the content of the part name, being r its raw content.
+	// Parts that are not encrypted must be returned unchanged.
+	Decrypt(name string, r io.ReadCloser) (io.ReadCloser, error)
+}
+
 // Decoder implements a 3mf file decoder.
+//
+// If Strict is true decoding stops at the first error,
+// otherwise the errors of all the model parts are returned in an errors.List,
+// the ones of the child models wrapped with the path of their part.
+//
+// If BufferAttachments is true the attachments are read into memory while decoding,
+// otherwise they are opened lazily by Attachment.Open and must not be
+// accessed once the underlying reader is closed.
+//
+// If Decrypter is not nil, the models and the attachments are read through it.
+//
+// Limits bound the resources used while decoding, exceeding them produces a *LimitError.
+//
+// If TrackPositions is true the decoded model remembers the line and column
+// of each element, so Model.Validate errors can point at them.
+// It requires memory proportional to the number of elements.
 type Decoder struct {
-	Strict        bool
-	p             packageReader
-	flate         func(r io.Reader) io.ReadCloser
-	nonRootModels []packageFile
+	Strict            bool
+	BufferAttachments bool
+	Decrypter         PartDecrypter
+	Limits            Limits
+	TrackPositions    bool
+	p                 packageReader
+	limiter           *limiter
+	flate             func(r io.Reader) io.ReadCloser
+	nonRootModels     []packageFile
+}
+
+// A ModelHandler receives the content of the model parts as it is decoded.
+// Content whose callback is nil is discarded.
+//
+// path is the name of the model part the content belongs to.
+// The slices passed to the callbacks are reused once the callback returns.
+// Decoding stops at the first callback error, which is returned by the decoder.
+type ModelHandler struct {
+	// Metadata is called for each metadata of the root model.
+	Metadata func(Metadata) error
+	// Asset is called for each asset once decoded.
+	Asset func(path string, a Asset) error
+	// Object is called for each object once decoded.
+	// Mesh objects are passed without vertices and triangles,
+	// which are previously passed in chunks to Vertices and Triangles.
+	Object func(path string, o *Object) error
+	// Vertices is called with consecutive chunks of the vertices of o.
+	// o only contains the attributes decoded so far.
+	Vertices func(path string, o *Object, v []Point3D) error
+	// Triangles is called with consecutive chunks of the triangles of o.
+	// o only contains the attributes decoded so far.
+	Triangles func(path string, o *Object, t []Triangle) error
+	// Item is called for each build item of the root model.
+	Item func(*Item) error
 }
 
 // NewDecoder returns a new Decoder reading a 3mf file from r.
@@ -174,6 +261,14 @@ func NewDecoder(r io.ReaderAt, size int64) *Decoder {
 	}
 }
 
+// SetDecompressor sets the function used to inflate the deflated parts,
+// which defaults to compress/flate.
+//
+// It is intended to plug faster inflate implementations.
+func (d *Decoder) SetDecompressor(dcomp func(r io.Reader) io.ReadCloser) {
+	d.flate = dcomp
+}
+
 // Decode reads the 3mf file and unmarshall its content into the model.
 func (d *Decoder) Decode(model *Model) error {
 	return d.DecodeContext(context.Background(), model)
@@ -181,14 +276,55 @@ func (d *Decoder) Decode(model *Model) error {
 
 // DecodeContext reads the 3mf file and unmarshall its content into the model.
 func (d *Decoder) DecodeContext(ctx context.Context, model *Model) error {
+	return d.decode(ctx, model, nil)
+}
+
+// DecodeHandler reads the 3mf file and passes the metadata, resources and build items
+// to the callbacks of h as they are decoded, instead of adding them to the model.
+// The rest of the package, such as units, extensions and attachments,
+// is still unmarshalled into the model.
+//
+// Child models are decoded sequentially, before the root model,
+// so the callbacks are never called concurrently.
+func (d *Decoder) DecodeHandler(model *Model, h *ModelHandler) error {
+	return d.DecodeHandlerContext(context.Background(), model, h)
+}
+
+// DecodeHandlerContext is like DecodeHandler but it stops early if ctx is done,
+// returning ctx.Err().
+func (d *Decoder) DecodeHandlerContext(ctx context.Context, model *Model, h *ModelHandler) error {
+	return d.decode(ctx, model, h)
+}
+
+func (d *Decoder) decode(ctx context.Context, model *Model, h *ModelHandler) error {
 	rootFile, err := d.processOPC(model)
 	if err != nil {
 		return err
 	}
-	if err := d.processNonRootModels(ctx, model); err != nil {
+	model.positions = nil
+	if d.TrackPositions {
+		model.positions = newPositionIndex()
+	}
+	if h != nil {
+		for i := range d.nonRootModels {
+			if err := d.readChildModel(ctx, i, model, h); err != nil {
+				return err
+			}
+		}
+		return d.processRootModel(ctx, rootFile, model, h)
+	}
+	childErrs := d.processNonRootModels(ctx, model)
+	if childErrs != nil && (d.Strict || ctx.Err() != nil || d.limiter.Err() != nil) {
+		return childErrs
+	}
+	err = d.processRootModel(ctx, rootFile, model, h)
+	if childErrs == nil {
 		return err
 	}
-	return d.processRootModel(ctx, rootFile, model)
+	// Not strict, report the errors of the root model along with the child ones.
+	var errs specerr.List
+	specerr.Append(&errs, childErrs, err)
+	return &errs
 }
 
 // UnmarshalModel fills a model with the data of a root model file
@@ -196,56 +332,101 @@ func (d *Decoder) DecodeContext(ctx context.Context, model *Model) error {
 func UnmarshalModel(data []byte, model *Model) error {
 	d := NewDecoder(nil, 0)
 	d.Strict = false
-	return d.processRootModel(context.Background(), &fakePackageFile{data: data}, model)
+	return d.processRootModel(context.Background(), &fakePackageFile{data: data}, model, nil)
 }
 
-func (d *Decoder) processRootModel(ctx context.Context, rootFile packageFile, model *Model) error {
+func (d *Decoder) processRootModel(ctx context.Context, rootFile packageFile, model *Model, h *ModelHandler) error {
 	f, err := rootFile.Open()
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	err = decodeModelFile(ctx, f, model, rootFile.Name(), true, d.Strict)
+	err = decodeModelFile(ctx, f, model, rootFile.Name(), true, d.Strict, h, d.limiter)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *Decoder) processNonRootModels(ctx context.Context, model *Model) (errs error) {
+// processNonRootModels decodes the child models concurrently,
+// using at most the goroutines set in ctx with WithParallelism.
+// In strict mode it stops at the first error, otherwise it returns
+// the errors of all the child models, wrapped with the path of their part.
+func (d *Decoder) processNonRootModels(ctx context.Context, model *Model) error {
 	var (
-		wg                 sync.WaitGroup
+		once               sync.Once
+		firstErr           error
 		nonRootModelsCount = len(d.nonRootModels)
+		partErrs           = make([]error, nonRootModelsCount)
 	)
-	wg.Add(nonRootModelsCount)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	for i := 0; i < nonRootModelsCount; i++ {
-		go func(i int) {
-			defer wg.Done()
-			err := d.readChildModel(ctx, i, model)
-			if err != nil {
-				errs = err
+	err := forEach(ctx, 0, nonRootModelsCount, func(i int) {
+		err := d.readChildModel(ctx, i, model, nil)
+		if err == nil {
+			return
+		}
+		if d.Strict {
+			// Keep the first error, the rest are caused by the cancellation.
+			once.Do(func() {
+				firstErr = err
 				cancel()
-			}
-		}(i)
+			})
+			return
+		}
+		partErrs[i] = wrapPartError(err, d.nonRootModels[i].Name())
+	})
+	if firstErr != nil {
+		return firstErr
 	}
-	wg.Wait()
-	if errs != nil {
-		return errs
+	if err != nil {
+		return err
 	}
-	return nil
+	var errs specerr.List
+	specerr.Append(&errs, partErrs...)
+	switch errs.Len() {
+	case 0:
+		return nil
+	case 1:
+		return errs.Errors[0]
+	}
+	return &errs
+}
+
+// wrapPartError sets the part path of err, an error decoding the model part path.
+func wrapPartError(err error, path string) error {
+	switch err.(type) {
+	case *specerr.Error, *specerr.List:
+		// The decoding errors already have their XPath.
+		return specerr.WithPosition(err, path, 0, 0)
+	}
+	return specerr.WrapPath(err, attrModel, path)
 }
 
 func (d *Decoder) processOPC(model *Model) (packageFile, error) {
+	// The limiter is initialized first, as opening the package
+	// already inflates [Content_Types].xml and the relationships parts.
+	if err := d.initLimiter(); err != nil {
+		return nil, err
+	}
 	if err := d.p.Open(d.flate); err != nil {
 		return nil, err
 	}
+	p := d.p
+	if d.Decrypter != nil {
+		if err := d.Decrypter.Init(d.p.Relationships(), d.openRaw); err != nil {
+			return nil, err
+		}
+		p = &decryptedPackage{packageReader: d.p, dec: d.Decrypter}
+	}
+	if d.limiter != nil {
+		p = &limitedPackage{packageReader: p, l: d.limiter}
+	}
 	var rootFile packageFile
-	for _, r := range d.p.Relationships() {
+	for _, r := range p.Relationships() {
 		if r.Type == RelType3DModel {
 			var ok bool
-			rootFile, ok = d.p.FindFileFromName(r.Path)
+			rootFile, ok = p.FindFileFromName(r.Path)
 			if !ok {
 				return nil, errors.New("package root model points to an unexisting file")
 			}
@@ -254,7 +435,23 @@ func (d *Decoder) processOPC(model *Model) (packageFile, error) {
 			for _, file := range d.nonRootModels {
 				d.extractCoreAttachments(file, model, false)
 			}
-		} else if att, ok := d.p.FindFileFromName(r.Path); ok {
+		} else if r.Type == RelTypeCoreProperties {
+			props, err := p.CoreProperties()
+			if err != nil && d.Strict {
+				return nil, err
+			}
+			model.CoreProperties = props
+		} else if r.Type == RelTypeDigitalSignatureOrigin {
+			// Signatures are verified with Signatures and not preserved,
+			// as re-encoding the model invalidates them.
+			continue
+		} else if att, ok := p.FindFileFromName(r.Path); ok {
+			if att.ContentType() == ContentType3DModel {
+				// Model parts are decoded from the 3dmodel relationships,
+				// other relationships targeting them, such as the Secure Content
+				// encryptedfile ones, are not preserved.
+				continue
+			}
 			model.RootRelationships = append(model.RootRelationships, r)
 			model.Attachments = d.addAttachment(model.Attachments, att)
 		}
@@ -262,9 +459,42 @@ func (d *Decoder) processOPC(model *Model) (packageFile, error) {
 	if rootFile == nil {
 		return nil, errors.New("package does not have root model")
 	}
+	if err := d.limiter.checkChildModels(len(d.nonRootModels)); err != nil {
+		return nil, err
+	}
+	if err := d.limiter.Err(); err != nil {
+		return nil, err
+	}
 	return rootFile, nil
 }
 
+func (d *Decoder) initLimiter() error {
+	d.limiter = nil
+	if d.Limits == (Limits{}) {
+		return nil
+	}
+	l := newLimiter(d.Limits)
+	if l.needsPackageInfo() {
+		parts, err := d.p.Parts()
+		if err != nil {
+			return err
+		}
+		if err = l.checkPackage(parts); err != nil {
+			return err
+		}
+	}
+	d.limiter = l
+	return nil
+}
+
+func (d *Decoder) openRaw(name string) (io.ReadCloser, error) {
+	f, ok := d.p.FindFileFromName(name)
+	if !ok {
+		return nil, fmt.Errorf("package does not contain %s", name)
+	}
+	return f.Open()
+}
+
 func (d *Decoder) extractCoreAttachments(modelFile packageFile, model *Model, isRoot bool) {
 	for _, rel := range modelFile.Relationships() {
 		if file, ok := modelFile.FindFileFromName(rel.Path); ok {
@@ -295,24 +525,34 @@ func (d *Decoder) addAttachment(attachments []Attachment, file packageFile) []At
 			return attachments
 		}
 	}
-	if buff, err := copyFile(file); err == nil {
+	if !d.BufferAttachments {
+		if lf, ok := file.(*limitedFile); ok {
+			file = &attachmentFile{packageFile: lf.packageFile, l: lf.l}
+		}
+		return append(attachments, Attachment{
+			Path:        file.Name(),
+			ContentType: file.ContentType(),
+			file:        file,
+		})
+	}
+	if data, err := readFile(file); err == nil {
 		return append(attachments, Attachment{
 			Path:        file.Name(),
-			Stream:      buff,
+			Data:        data,
 			ContentType: file.ContentType(),
 		})
 	}
 	return attachments
 }
 
-func (d *Decoder) readChildModel(ctx context.Context, i int, model *Model) error {
+func (d *Decoder) readChildModel(ctx context.Context, i int, model *Model, h *ModelHandler) error {
 	attachment := d.nonRootModels[i]
 	file, err := attachment.Open()
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	err = decodeModelFile(ctx, file, model, attachment.Name(), false, d.Strict)
+	err = decodeModelFile(ctx, file, model, attachment.Name(), false, d.Strict, h, d.limiter)
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
@@ -321,15 +561,13 @@ func (d *Decoder) readChildModel(ctx context.Context, i int, model *Model) error
 	return err
 }
 
-func copyFile(file packageFile) (io.Reader, error) {
+func readFile(file packageFile) ([]byte, error) {
 	stream, err := file.Open()
 	if err != nil {
 		return nil, err
 	}
-	buff := new(bytes.Buffer)
-	_, err = io.Copy(buff, stream)
-	stream.Close()
-	return buff, err
+	defer stream.Close()
+	return ioutil.ReadAll(stream)
 }
 
 type fakePackageFile struct {
@@ -343,3 +581,37 @@ func (f *fakePackageFile) Relationships() []Relationship               { return
 func (f *fakePackageFile) Open() (io.ReadCloser, error) {
 	return ioutil.NopCloser(bytes.NewBuffer(f.data)), nil
 }
+
+type decryptedPackage struct {
+	packageReader
+	dec PartDecrypter
+}
+
+func (p *decryptedPackage) FindFileFromName(name string) (packageFile, bool) {
+	f, ok := p.packageReader.FindFileFromName(name)
+	if !ok {
+		return nil, false
+	}
+	return &decryptedFile{packageFile: f, dec: p.dec}, true
+}
+
+type decryptedFile struct {
+	packageFile
+	dec PartDecrypter
+}
+
+func (f *decryptedFile) FindFileFromName(name string) (packageFile, bool) {
+	file, ok := f.packageFile.FindFileFromName(name)
+	if !ok {
+		return nil, false
+	}
+	return &decryptedFile{packageFile: file, dec: f.dec}, true
+}
+
+func (f *decryptedFile) Open() (io.ReadCloser, error) {
+	r, err := f.packageFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	return f.dec.Decrypt(f.Name(), r)
+}
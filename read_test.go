@@ -5,6 +5,7 @@ package go3mf
 
 import (
 	"bytes"
+	"compress/flate"
 	"context"
 	"encoding/xml"
 	"errors"
@@ -216,12 +217,24 @@ type mockPackage struct {
 func newMockPackage(other *mockFile) *mockPackage {
 	m := new(mockPackage)
 	m.On("Open", mock.Anything).Return(nil).Maybe()
-	m.On("Create", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	m.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
 	m.On("Relationships").Return([]Relationship{{Path: DefaultModelPath, Type: RelType3DModel}}).Maybe()
 	m.On("FindFileFromName", mock.Anything).Return(other, other != nil).Maybe()
 	return m
 }
 
+func newMockPackageCoreProperties(props *CoreProperties, err error) *mockPackage {
+	m := new(mockPackage)
+	m.On("Open", mock.Anything).Return(nil).Maybe()
+	m.On("Relationships").Return([]Relationship{
+		{Path: DefaultCorePropertiesPath, Type: RelTypeCoreProperties},
+		{Path: "/a.model", Type: RelType3DModel},
+	}).Maybe()
+	m.On("CoreProperties").Return(props, err)
+	m.On("FindFileFromName", "/a.model").Return(newMockFile("/a.model", nil, nil, false), true).Maybe()
+	return m
+}
+
 func (m *mockPackage) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -236,16 +249,36 @@ func (m *mockPackage) AddRelationship(args0 Relationship) {
 	m.Called(args0)
 }
 
-func (m *mockPackage) Create(args0, args1 string) (packagePart, error) {
-	args := m.Called(args0, args1)
+func (m *mockPackage) Create(args0, args1 string, args2 Compression) (packagePart, error) {
+	args := m.Called(args0, args1, args2)
 	return args.Get(0).(packagePart), args.Error(1)
 }
 
+func (m *mockPackage) relationshipsParts() ([]relationshipsPart, error) {
+	args := m.Called()
+	return args.Get(0).([]relationshipsPart), args.Error(1)
+}
+
 func (m *mockPackage) Open(f func(r io.Reader) io.ReadCloser) error {
 	args := m.Called(f)
 	return args.Error(0)
 }
 
+func (m *mockPackage) CoreProperties() (*CoreProperties, error) {
+	args := m.Called()
+	return args.Get(0).(*CoreProperties), args.Error(1)
+}
+
+func (m *mockPackage) Inspect(l Limits) (*PackageInfo, error) {
+	args := m.Called(l)
+	return args.Get(0).(*PackageInfo), args.Error(1)
+}
+
+func (m *mockPackage) Parts() ([]PartInfo, error) {
+	args := m.Called()
+	return args.Get(0).([]PartInfo), args.Error(1)
+}
+
 func (m *mockPackage) FindFileFromName(args0 string) (packageFile, bool) {
 	args := m.Called(args0)
 	return args.Get(0).(packageFile), args.Bool(1)
@@ -263,29 +296,39 @@ func TestDecoder_processOPC(t *testing.T) {
 		{"noRoot", &Decoder{p: newMockPackage(nil)}, &Model{}, true},
 		{"noRels", &Decoder{p: newMockPackage(newMockFile("/a.model", nil, nil, false))}, &Model{Path: "/a.model"}, false},
 		{"withThumb", &Decoder{
-			p: newMockPackage(newMockFile("/a.model", []Relationship{{Type: RelTypeThumbnail, Path: "/a.png"}}, newMockFile("/a.png", nil, nil, false), false)),
+			BufferAttachments: true,
+			p:                 newMockPackage(newMockFile("/a.model", []Relationship{{Type: RelTypeThumbnail, Path: "/a.png"}}, newMockFile("/a.png", nil, nil, false), false)),
 		}, &Model{
 			Path:          "/a.model",
 			Relationships: []Relationship{{Path: "/a.png", Type: RelTypeThumbnail}},
-			Attachments:   []Attachment{{Path: "/a.png", Stream: new(bytes.Buffer)}},
+			Attachments:   []Attachment{{Path: "/a.png", Data: []byte{}}},
 		}, false},
 		{"withPrintTicket", &Decoder{
-			p: newMockPackage(newMockFile("/a.model", []Relationship{{Type: RelTypePrintTicket, Path: "/pc.png"}}, newMockFile("/pc.png", nil, nil, false), false)),
+			BufferAttachments: true,
+			p:                 newMockPackage(newMockFile("/a.model", []Relationship{{Type: RelTypePrintTicket, Path: "/pc.png"}}, newMockFile("/pc.png", nil, nil, false), false)),
 		}, &Model{
 			Path:          "/a.model",
 			Relationships: []Relationship{{Path: "/pc.png", Type: RelTypePrintTicket}},
-			Attachments:   []Attachment{{Path: "/pc.png", Stream: new(bytes.Buffer)}},
+			Attachments:   []Attachment{{Path: "/pc.png", Data: []byte{}}},
 		}, false},
 		{"withExtRel", &Decoder{
-			p: newMockPackage(newMockFile("/a.model", []Relationship{{Type: extType, Path: "/other.png"}}, newMockFile("/other.png", nil, nil, false), false)),
+			BufferAttachments: true,
+			p:                 newMockPackage(newMockFile("/a.model", []Relationship{{Type: extType, Path: "/other.png"}}, newMockFile("/other.png", nil, nil, false), false)),
 		}, &Model{
 			Path:          "/a.model",
 			Relationships: []Relationship{{Path: "/other.png", Type: extType}},
-			Attachments:   []Attachment{{Path: "/other.png", Stream: new(bytes.Buffer)}},
+			Attachments:   []Attachment{{Path: "/other.png", Data: []byte{}}},
 		}, false},
 		{"withOtherRel", &Decoder{
 			p: newMockPackage(newMockFile("/a.model", []Relationship{{Type: "other", Path: "/a.png"}}, nil, false)),
 		}, &Model{Path: "/a.model"}, false},
+		{"withCoreProperties", &Decoder{p: newMockPackageCoreProperties(&CoreProperties{Title: "cube"}, nil)}, &Model{
+			Path: "/a.model", CoreProperties: &CoreProperties{Title: "cube"},
+		}, false},
+		{"invalidCoreProperties", &Decoder{Strict: true, p: newMockPackageCoreProperties(new(CoreProperties), errors.New(""))}, &Model{}, true},
+		{"invalidCorePropertiesNotStrict", &Decoder{p: newMockPackageCoreProperties(new(CoreProperties), errors.New(""))}, &Model{
+			Path: "/a.model", CoreProperties: new(CoreProperties),
+		}, false},
 		{"withModelAttachment", &Decoder{
 			p: newMockPackage(newMockFile("/a.model", []Relationship{{Type: RelType3DModel, Path: "/other.model"}}, otherModel, false)),
 		}, &Model{Path: "/a.model", Childs: map[string]*ChildModel{"/other.model": new(ChildModel)}}, false},
@@ -317,7 +360,7 @@ func TestDecoder_processRootModel_Fail(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := new(Decoder).processRootModel(context.Background(), tt.f, new(Model)); (err != nil) != tt.wantErr {
+			if err := new(Decoder).processRootModel(context.Background(), tt.f, new(Model), nil); (err != nil) != tt.wantErr {
 				t.Errorf("Decoder.processRootModel() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
@@ -509,7 +552,7 @@ func TestDecoder_processRootModel(t *testing.T) {
 
 	d := new(Decoder)
 	d.Strict = true
-	if err := d.processRootModel(context.Background(), rootFile, got); err != nil {
+	if err := d.processRootModel(context.Background(), rootFile, got, nil); err != nil {
 		t.Errorf("Decoder.processRootModel() unexpected error = %v", err)
 		return
 	}
@@ -568,6 +611,51 @@ func TestDecoder_processNonRootModels(t *testing.T) {
 	}
 }
 
+func TestDecoder_processNonRootModels_errors(t *testing.T) {
+	newDecoder := func(strict bool) *Decoder {
+		return &Decoder{Strict: strict, nonRootModels: []packageFile{
+			new(modelBuilder).withDefaultModel().withElement(`
+				<resources>
+					<basematerials id="a" />
+					<basematerials id="b" />
+				</resources>
+			`).build("/3D/a.model"),
+			new(modelBuilder).withDefaultModel().withElement(`<resources>`).build("/3D/b.model"),
+			new(modelBuilder).withDefaultModel().withElement(`
+				<resources>
+					<basematerials id="6" />
+				</resources>
+			`).build("/3D/c.model"),
+		}}
+	}
+	want := []string{
+		fmt.Sprintf("go3mf: Path: /3D/a.model Line: 4 Column: 6 XPath: /model/resources/basematerials[0]: %v", specerr.NewParseAttrError("id", true)),
+		fmt.Sprintf("go3mf: Path: /3D/a.model Line: 5 Column: 6 XPath: /model/resources/basematerials[1]: %v", specerr.NewParseAttrError("id", true)),
+		"go3mf: Path: /3D/b.model XPath: /model: XML syntax error on line 3: element <resources> closed by </model>",
+	}
+	model := &Model{Childs: map[string]*ChildModel{"/3D/a.model": new(ChildModel), "/3D/b.model": new(ChildModel), "/3D/c.model": new(ChildModel)}}
+	err := newDecoder(false).processNonRootModels(context.Background(), model)
+	var list *specerr.List
+	if !errors.As(err, &list) {
+		t.Fatalf("Decoder.processNonRootModels() error = %v", err)
+	}
+	var errs []string
+	for _, err := range list.Errors {
+		errs = append(errs, err.Error())
+	}
+	if diff := deep.Equal(errs, want); diff != nil {
+		t.Errorf("Decoder.processNonRootModels() = %v", diff)
+	}
+	if len(model.Childs["/3D/c.model"].Resources.Assets) != 1 {
+		t.Error("Decoder.processNonRootModels() valid child model not decoded")
+	}
+	if err = newDecoder(true).processNonRootModels(context.Background(), model); err == nil {
+		t.Error("Decoder.processNonRootModels() strict expected error")
+	} else if _, ok := err.(*specerr.List); ok {
+		t.Errorf("Decoder.processNonRootModels() strict = %v, want a single error", err)
+	}
+}
+
 func TestDecoder_Decode(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -616,7 +704,7 @@ func Test_modelFile_Decode(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := decodeModelFile(tt.args.ctx, tt.args.r, new(Model), "", true, false); (err != nil) != tt.wantErr {
+			if err := decodeModelFile(tt.args.ctx, tt.args.r, new(Model), "", true, false, nil, nil); (err != nil) != tt.wantErr {
 				t.Errorf("modelFile.Decode() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -650,17 +738,17 @@ func TestNewDecoder(t *testing.T) {
 func TestDecoder_processRootModel_warns(t *testing.T) {
 	spec.Register(fakeSpec.Namespace, new(qmExtension))
 	want := []string{
-		fmt.Sprintf("go3mf: XPath: /model/resources/basematerials[0]/base[0]: %v", specerr.NewParseAttrError("displaycolor", true)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/basematerials[1]: %v", specerr.NewParseAttrError("id", true)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/vertices/vertex[8]: %v", specerr.NewParseAttrError("x", true)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/triangles/triangle[13]: %v", specerr.NewParseAttrError("v1", true)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[1]: %v", specerr.NewParseAttrError("pid", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[1]: %v", specerr.NewParseAttrError("pindex", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[1]: %v", specerr.NewParseAttrError("type", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[2]/components/component[0]: %v", specerr.NewParseAttrError("transform", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[2]/components/component[1]: %v", specerr.NewParseAttrError("objectid", true)),
-		fmt.Sprintf("go3mf: XPath: /model/build/item[0]: %v", specerr.NewParseAttrError("transform", false)),
-		fmt.Sprintf("go3mf: XPath: /model/build/item[3]: %v", specerr.NewParseAttrError("objectid", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 6 Column: 5 XPath: /model/resources/basematerials[0]/base[0]: %v", specerr.NewParseAttrError("displaycolor", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 9 Column: 4 XPath: /model/resources/basematerials[1]: %v", specerr.NewParseAttrError("id", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 25 Column: 7 XPath: /model/resources/object[0]/mesh/vertices/vertex[8]: %v", specerr.NewParseAttrError("x", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 41 Column: 7 XPath: /model/resources/object[0]/mesh/triangles/triangle[13]: %v", specerr.NewParseAttrError("v1", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 45 Column: 4 XPath: /model/resources/object[1]: %v", specerr.NewParseAttrError("pid", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 45 Column: 4 XPath: /model/resources/object[1]: %v", specerr.NewParseAttrError("pindex", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 45 Column: 4 XPath: /model/resources/object[1]: %v", specerr.NewParseAttrError("type", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 48 Column: 6 XPath: /model/resources/object[2]/components/component[0]: %v", specerr.NewParseAttrError("transform", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 49 Column: 6 XPath: /model/resources/object[2]/components/component[1]: %v", specerr.NewParseAttrError("objectid", true)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 54 Column: 4 XPath: /model/build/item[0]: %v", specerr.NewParseAttrError("transform", false)),
+		fmt.Sprintf("go3mf: Path: /3D/3dmodel.model Line: 57 Column: 4 XPath: /model/build/item[3]: %v", specerr.NewParseAttrError("objectid", true)),
 	}
 	got := new(Model)
 	got.Extensions = append(got.Extensions, fakeSpec)
@@ -732,7 +820,7 @@ func TestDecoder_processRootModel_warns(t *testing.T) {
 
 	d := new(Decoder)
 	d.Strict = false
-	err := d.processRootModel(context.Background(), rootFile, got)
+	err := d.processRootModel(context.Background(), rootFile, got, nil)
 	if err == nil {
 		t.Fatal("error expected")
 	}
@@ -789,3 +877,141 @@ func TestOpenReader(t *testing.T) {
 		return
 	}
 }
+
+func TestDecoder_DecodeHandler(t *testing.T) {
+	defer func(n int) { handlerChunkSize = n }(handlerChunkSize)
+	handlerChunkSize = 2
+	m := &Model{
+		Metadata: []Metadata{{Name: xml.Name{Local: "Title"}, Value: "t"}, {Name: xml.Name{Local: "Designer"}, Value: "d"}},
+		Childs: map[string]*ChildModel{
+			"/3D/other.model": {Resources: Resources{Objects: []*Object{
+				{ID: 1, Mesh: &Mesh{
+					Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+					Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}},
+				}},
+			}}},
+		},
+		Resources: Resources{
+			Assets: []Asset{&BaseMaterials{ID: 1, Materials: []Base{{Name: "a", Color: color.RGBA{R: 255, A: 255}}}}},
+			Objects: []*Object{
+				{ID: 2, PID: 1, Mesh: &Mesh{
+					Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {1, 1, 1}}},
+					Triangles: Triangles{Triangle: []Triangle{
+						{V1: 0, V2: 1, V3: 2, PID: 1}, {V1: 0, V2: 1, V3: 3, PID: 1}, {V1: 0, V2: 2, V3: 3, PID: 1},
+					}},
+				}},
+				{ID: 3, Components: &Components{Component: []*Component{{ObjectID: 2}}}},
+			},
+		},
+		Build: Build{Items: []*Item{{ObjectID: 3}, {ObjectID: 2}}},
+	}
+	var buff bytes.Buffer
+	if err := NewEncoder(&buff).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	want := new(Model)
+	if err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(want); err != nil {
+		t.Fatal(err)
+	}
+
+	got := new(Model)
+	var (
+		vertices  []Point3D
+		triangles []Triangle
+		chunks    int
+	)
+	h := &ModelHandler{
+		Metadata: func(md Metadata) error {
+			got.Metadata = append(got.Metadata, md)
+			return nil
+		},
+		Asset: func(path string, a Asset) error {
+			rs, _ := got.FindResources(path)
+			rs.Assets = append(rs.Assets, a)
+			return nil
+		},
+		Vertices: func(_ string, _ *Object, v []Point3D) error {
+			chunks++
+			vertices = append(vertices, v...)
+			return nil
+		},
+		Triangles: func(_ string, _ *Object, tr []Triangle) error {
+			chunks++
+			triangles = append(triangles, tr...)
+			return nil
+		},
+		Object: func(path string, o *Object) error {
+			if o.Mesh != nil {
+				o.Mesh.Vertices.Vertex, o.Mesh.Triangles.Triangle = vertices, triangles
+				vertices, triangles = nil, nil
+			}
+			rs, _ := got.FindResources(path)
+			rs.Objects = append(rs.Objects, o)
+			return nil
+		},
+		Item: func(item *Item) error {
+			got.Build.Items = append(got.Build.Items, item)
+			return nil
+		},
+	}
+	if err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).DecodeHandler(got, h); err != nil {
+		t.Fatalf("Decoder.DecodeHandler() error = %v", err)
+	}
+	if chunks != 8 {
+		t.Errorf("Decoder.DecodeHandler() chunks = %d, want 8", chunks)
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Decoder.DecodeHandler() = %v", diff)
+	}
+
+	errFake := errors.New("fake")
+	h = &ModelHandler{Triangles: func(string, *Object, []Triangle) error { return errFake }}
+	if err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).DecodeHandler(new(Model), h); err != errFake {
+		t.Errorf("Decoder.DecodeHandler() error = %v, want %v", err, errFake)
+	}
+}
+
+func TestDecoder_Decode_LazyAttachments(t *testing.T) {
+	var buff bytes.Buffer
+	m := &Model{
+		Thumbnail:   "/Metadata/thumbnail.png",
+		Attachments: []Attachment{{Path: "/Metadata/thumbnail.png", ContentType: "image/png", Stream: bytes.NewBufferString("fake")}},
+	}
+	if err := NewEncoder(&buff).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	got := new(Model)
+	if err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if len(got.Attachments) != 1 || got.Attachments[0].Stream != nil {
+		t.Fatalf("Decoder.Decode() attachments = %v, want one lazy attachment", got.Attachments)
+	}
+	r, err := got.Attachments[0].Open()
+	if err != nil {
+		t.Fatalf("Attachment.Open() error = %v", err)
+	}
+	defer r.Close()
+	if b, _ := ioutil.ReadAll(r); string(b) != "fake" {
+		t.Errorf("Attachment.Open() = %s, want fake", b)
+	}
+}
+
+func TestDecoder_SetDecompressor(t *testing.T) {
+	var buff bytes.Buffer
+	if err := NewEncoder(&buff).Encode(&Model{Resources: Resources{Objects: []*Object{{ID: 1, Mesh: new(Mesh)}}}}); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	var calls int
+	d := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	d.SetDecompressor(func(r io.Reader) io.ReadCloser {
+		calls++
+		return flate.NewReader(r)
+	})
+	if err := d.Decode(new(Model)); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if calls == 0 {
+		t.Error("Decoder.SetDecompressor() decompressor not called")
+	}
+}
@@ -0,0 +1,186 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hpinc/go3mf/errors"
+	"github.com/hpinc/go3mf/spec"
+)
+
+// Resolve returns the element referenced by an error path and XPath,
+// as reported by errors.Error, such as the *Triangle
+// at /model/resources/object[3]/mesh/triangle[17].
+//
+// The core elements resolve to pointers to the model values, such as
+// *Model, *ChildModel, *Resources, *Object, *Mesh, *Triangle, *Point3D,
+// *Components, *Component, *Build, *Item, *Metadata, *Relationship and Asset.
+// The elements of other specs are resolved by the specs implementing spec.ResolveSpec.
+//
+// path selects the model part, being the root model if empty.
+// The relationships of a root model XPath with an empty path
+// are the RootRelationships, as reported by Validate.
+func (m *Model) Resolve(path, xpath string) (interface{}, bool) {
+	levels, ok := parseXPath(xpath)
+	if !ok || len(levels) == 0 || levels[0] != (errors.Level{Name: attrModel, Index: -1}) {
+		return nil, false
+	}
+	var el interface{} = m
+	if path != "" && path != m.PathOrDefault() {
+		child, ok := m.Childs[path]
+		if !ok {
+			return nil, false
+		}
+		el = child
+	}
+	var ns string
+	for _, l := range levels[1:] {
+		next, nextNs, ok := m.resolveLevel(path, el, ns, l)
+		if !ok {
+			return nil, false
+		}
+		el, ns = next, nextNs
+	}
+	return el, true
+}
+
+// parseXPath returns the levels of xpath, from the root.
+func parseXPath(xpath string) ([]errors.Level, bool) {
+	if !strings.HasPrefix(xpath, "/") {
+		return nil, false
+	}
+	parts := strings.Split(xpath[1:], "/")
+	levels := make([]errors.Level, len(parts))
+	for i, p := range parts {
+		l := errors.Level{Name: p, Index: -1}
+		if n := strings.IndexByte(p, '['); n != -1 {
+			if !strings.HasSuffix(p, "]") {
+				return nil, false
+			}
+			index, err := strconv.Atoi(p[n+1 : len(p)-1])
+			if err != nil || index < 0 {
+				return nil, false
+			}
+			l = errors.Level{Name: p[:n], Index: index}
+		}
+		if l.Name == "" {
+			return nil, false
+		}
+		levels[i] = l
+	}
+	return levels, true
+}
+
+// resolveLevel returns the child of parent at l and the namespace of the spec resolving it.
+// The spec that resolved parent, whose namespace is ns, is asked first,
+// as the elements of a spec are usually nested into another element of the same spec.
+func (m *Model) resolveLevel(path string, parent interface{}, ns string, l errors.Level) (interface{}, string, bool) {
+	if el, ok := resolveCore(path, parent, l); ok {
+		if a, ok := el.(Asset); ok {
+			ns = a.XMLName().Space
+		}
+		return el, ns, true
+	}
+	namespaces := make([]string, 0, len(m.Extensions)+1)
+	if ns != "" {
+		namespaces = append(namespaces, ns)
+	}
+	for _, ext := range m.Extensions {
+		namespaces = append(namespaces, ext.Namespace)
+	}
+	for _, ns := range namespaces {
+		if r, ok := spec.LoadResolver(ns); ok {
+			if el, ok := r.Resolve(parent, l.Name, l.Index); ok {
+				return el, ns, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+func resolveCore(path string, parent interface{}, l errors.Level) (interface{}, bool) {
+	switch p := parent.(type) {
+	case *Model:
+		switch l.Name {
+		case attrResources:
+			return &p.Resources, l.Index == -1
+		case attrBuild:
+			return &p.Build, l.Index == -1
+		case attrMetadata:
+			if inRange(l.Index, len(p.Metadata)) {
+				return &p.Metadata[l.Index], true
+			}
+		case attrRelationship:
+			rels := p.Relationships
+			if path == "" {
+				rels = p.RootRelationships
+			}
+			if inRange(l.Index, len(rels)) {
+				return &rels[l.Index], true
+			}
+		}
+	case *ChildModel:
+		switch l.Name {
+		case attrResources:
+			return &p.Resources, l.Index == -1
+		case attrRelationship:
+			if inRange(l.Index, len(p.Relationships)) {
+				return &p.Relationships[l.Index], true
+			}
+		}
+	case *Resources:
+		if l.Name == attrObject {
+			if inRange(l.Index, len(p.Objects)) {
+				return p.Objects[l.Index], true
+			}
+		} else if inRange(l.Index, len(p.Assets)) && p.Assets[l.Index].XMLName().Local == l.Name {
+			return p.Assets[l.Index], true
+		}
+	case *Build:
+		if l.Name == attrItem && inRange(l.Index, len(p.Items)) {
+			return p.Items[l.Index], true
+		}
+	case *Item:
+		if l.Name == attrMetadata && inRange(l.Index, len(p.Metadata.Metadata)) {
+			return &p.Metadata.Metadata[l.Index], true
+		}
+	case *Object:
+		switch l.Name {
+		case attrMesh:
+			return p.Mesh, p.Mesh != nil && l.Index == -1
+		case attrComponents:
+			return p.Components, p.Components != nil && l.Index == -1
+		case attrMetadata:
+			if inRange(l.Index, len(p.Metadata.Metadata)) {
+				return &p.Metadata.Metadata[l.Index], true
+			}
+		}
+	case *Mesh:
+		switch l.Name {
+		case attrTriangle:
+			if inRange(l.Index, len(p.Triangles.Triangle)) {
+				return &p.Triangles.Triangle[l.Index], true
+			}
+		case attrVertex:
+			if inRange(l.Index, len(p.Vertices.Vertex)) {
+				return &p.Vertices.Vertex[l.Index], true
+			}
+		}
+	case *Components:
+		if l.Name == attrComponent && inRange(l.Index, len(p.Component)) {
+			return p.Component[l.Index], true
+		}
+	case *BaseMaterials:
+		if l.Name == attrBase && inRange(l.Index, len(p.Materials)) {
+			return &p.Materials[l.Index], true
+		}
+	}
+	return nil, false
+}
+
+func inRange(index, n int) bool {
+	return index >= 0 && index < n
+}
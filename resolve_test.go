@@ -0,0 +1,102 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"testing"
+
+	"github.com/hpinc/go3mf/errors"
+)
+
+func TestModel_Resolve(t *testing.T) {
+	mesh := &Mesh{Vertices: Vertices{Vertex: []Point3D{{}, {1, 0, 0}}}, Triangles: Triangles{Triangle: []Triangle{{V1: 0}, {V1: 1}}}}
+	components := &Components{Component: []*Component{{ObjectID: 1}}}
+	base := &BaseMaterials{ID: 5, Materials: []Base{{Name: "a"}, {Name: "b"}}}
+	item := &Item{ObjectID: 1, Metadata: MetadataGroup{Metadata: []Metadata{{Value: "b"}}}}
+	childObj := &Object{ID: 8}
+	child := &ChildModel{Resources: Resources{Objects: []*Object{childObj}}, Relationships: []Relationship{{Path: "/c"}}}
+	m := &Model{
+		Path:              "/3D/model.model",
+		Metadata:          []Metadata{{Value: "a"}},
+		RootRelationships: []Relationship{{Path: "/a"}},
+		Relationships:     []Relationship{{Path: "/b"}},
+		Resources: Resources{
+			Assets:  []Asset{base},
+			Objects: []*Object{{ID: 1, Mesh: mesh, Metadata: MetadataGroup{Metadata: []Metadata{{Value: "c"}}}}, {ID: 2, Components: components}},
+		},
+		Build:  Build{Items: []*Item{item}},
+		Childs: map[string]*ChildModel{"/other.model": child},
+	}
+	tests := []struct {
+		name   string
+		path   string
+		xpath  string
+		want   interface{}
+		wantOk bool
+	}{
+		{"model", "", "/model", m, true},
+		{"rootPath", "/3D/model.model", "/model", m, true},
+		{"metadata", "", "/model/metadata[0]", &m.Metadata[0], true},
+		{"rootRels", "", "/model/relationship[0]", &m.RootRelationships[0], true},
+		{"rels", "/3D/model.model", "/model/relationship[0]", &m.Relationships[0], true},
+		{"resources", "", "/model/resources", &m.Resources, true},
+		{"asset", "", "/model/resources/basematerials[0]", base, true},
+		{"base", "", "/model/resources/basematerials[0]/base[1]", &base.Materials[1], true},
+		{"object", "", "/model/resources/object[1]", m.Resources.Objects[1], true},
+		{"objectMetadata", "", "/model/resources/object[0]/metadata[0]", &m.Resources.Objects[0].Metadata.Metadata[0], true},
+		{"mesh", "", "/model/resources/object[0]/mesh", mesh, true},
+		{"triangle", "", "/model/resources/object[0]/mesh/triangle[1]", &mesh.Triangles.Triangle[1], true},
+		{"vertex", "", "/model/resources/object[0]/mesh/vertex[1]", &mesh.Vertices.Vertex[1], true},
+		{"components", "", "/model/resources/object[1]/components", components, true},
+		{"component", "", "/model/resources/object[1]/components/component[0]", components.Component[0], true},
+		{"build", "", "/model/build", &m.Build, true},
+		{"item", "", "/model/build/item[0]", item, true},
+		{"itemMetadata", "", "/model/build/item[0]/metadata[0]", &item.Metadata.Metadata[0], true},
+		{"child", "/other.model", "/model", child, true},
+		{"childObject", "/other.model", "/model/resources/object[0]", childObj, true},
+		{"childRels", "/other.model", "/model/relationship[0]", &child.Relationships[0], true},
+		{"noChild", "/missing.model", "/model", nil, false},
+		{"noRoot", "", "/resources", nil, false},
+		{"empty", "", "", nil, false},
+		{"relative", "", "model", nil, false},
+		{"emptyLevel", "", "/model//build", nil, false},
+		{"badIndex", "", "/model/metadata[a]", nil, false},
+		{"unclosedIndex", "", "/model/metadata[0", nil, false},
+		{"outOfRange", "", "/model/resources/object[0]/mesh/triangle[2]", nil, false},
+		{"negative", "", "/model/metadata[-1]", nil, false},
+		{"notIndexed", "", "/model/build/item", nil, false},
+		{"indexed", "", "/model/build[0]", nil, false},
+		{"assetName", "", "/model/resources/colorgroup[0]", nil, false},
+		{"noMesh", "", "/model/resources/object[1]/mesh", nil, false},
+		{"unknown", "", "/model/resources/object[0]/mesh/other[0]", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.Resolve(tt.path, tt.xpath)
+			if ok != tt.wantOk {
+				t.Errorf("Model.Resolve() ok = %v, want %v", ok, tt.wantOk)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Model.Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_Resolve_validationErrors(t *testing.T) {
+	m := &Model{Resources: Resources{Objects: []*Object{{ID: 1, Mesh: &Mesh{
+		Vertices:  Vertices{Vertex: []Point3D{{}, {}, {}}},
+		Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 1, V3: 1}, {V1: 0, V2: 1, V3: 2}}},
+	}}}}}
+	err := m.Validate()
+	if err == nil {
+		t.Fatal("Model.Validate() = nil, want error")
+	}
+	e := err.(*errors.List).Errors[0].(*errors.Error)
+	got, ok := m.Resolve(e.Path, e.XPath())
+	if !ok || got != &m.Resources.Objects[0].Mesh.Triangles.Triangle[2] {
+		t.Errorf("Model.Resolve(%s) = %v, %v", e.XPath(), got, ok)
+	}
+}
@@ -0,0 +1,81 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package securecontent
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/sha1"
+	"io"
+)
+
+const (
+	cekSize         = 32 // AES-256
+	ivSize          = 12
+	algRSAOAEPMGF1P = "http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p"
+)
+
+func defaultKEKParams() KEKParams {
+	return KEKParams{
+		WrappingAlgorithm: AlgorithmRSAOAEP,
+		MGFAlgorithm:      AlgorithmMGF1SHA1,
+		DigestMethod:      AlgorithmSHA1,
+	}
+}
+
+// wrapKey wraps cek with RSA-OAEP using SHA-1 as digest and MGF1 function.
+func wrapKey(rand io.Reader, pub *rsa.PublicKey, cek []byte) ([]byte, error) {
+	return rsa.EncryptOAEP(sha1.New(), rand, pub, cek, nil)
+}
+
+func unwrapKey(priv *rsa.PrivateKey, params KEKParams, wrapped []byte) ([]byte, error) {
+	if params.WrappingAlgorithm != AlgorithmRSAOAEP && params.WrappingAlgorithm != algRSAOAEPMGF1P {
+		return nil, ErrAlgorithm
+	}
+	if params.MGFAlgorithm != "" && params.MGFAlgorithm != AlgorithmMGF1SHA1 {
+		return nil, ErrAlgorithm
+	}
+	if params.DigestMethod != "" && params.DigestMethod != AlgorithmSHA1 {
+		return nil, ErrAlgorithm
+	}
+	return rsa.DecryptOAEP(sha1.New(), nil, priv, wrapped, nil)
+}
+
+func newGCM(cek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plain with AES-256-GCM and fills the IV and the Tag of p.
+func seal(rand io.Reader, cek []byte, p *CEKParams, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	p.IV = make([]byte, ivSize)
+	if _, err = io.ReadFull(rand, p.IV); err != nil {
+		return nil, err
+	}
+	out := gcm.Seal(nil, p.IV, plain, p.AAD)
+	n := len(out) - gcm.Overhead()
+	p.Tag = out[n:]
+	return out[:n], nil
+}
+
+// open decrypts data, which has been encrypted as defined by p.
+func open(cek []byte, p *CEKParams, data []byte) ([]byte, error) {
+	if p.EncryptionAlgorithm != AlgorithmAES256GCM {
+		return nil, ErrAlgorithm
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, p.Tag...)
+	return gcm.Open(data[:0], p.IV, data, p.AAD)
+}
@@ -0,0 +1,151 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package securecontent
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hpinc/go3mf"
+)
+
+// A Decrypter implements go3mf.PartDecrypter for packages
+// encrypted following the Secure Content specification.
+//
+// The content encryption keys are unwrapped with PrivateKey,
+// or with Unwrap if not nil, i.e. to delegate it to a hardware security module.
+// Parts of groups the consumer has no access to fail to be read with ErrAccessRight.
+type Decrypter struct {
+	// ConsumerID identifies the consumer decrypting the package.
+	// If empty, the first consumer of the KeyStore is used.
+	ConsumerID string
+	PrivateKey *rsa.PrivateKey
+	Unwrap     func(c Consumer, params KEKParams, wrapped []byte) ([]byte, error)
+	// KeyStore is set by Init, it is nil if the package is not encrypted.
+	KeyStore *KeyStore
+	keys     map[string][]byte // key uuid -> cek
+}
+
+// Init decodes the KeyStore part and unwraps the content encryption keys
+// of the groups the consumer has access to.
+func (d *Decrypter) Init(rels []go3mf.Relationship, open func(name string) (io.ReadCloser, error)) error {
+	d.KeyStore, d.keys = nil, nil
+	for _, r := range rels {
+		if r.Type != RelTypeKeyStore {
+			continue
+		}
+		rc, err := open(r.Path)
+		if err != nil {
+			return err
+		}
+		d.KeyStore, err = DecodeKeyStore(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		return d.unwrapKeys()
+	}
+	return nil
+}
+
+// Decrypt returns the decrypted content of the part name,
+// or r if the part is not encrypted.
+func (d *Decrypter) Decrypt(name string, r io.ReadCloser) (io.ReadCloser, error) {
+	if d.KeyStore == nil {
+		return r, nil
+	}
+	g, rd, ok := d.KeyStore.FindResourceData(name)
+	if !ok {
+		return r, nil
+	}
+	defer r.Close()
+	cek, ok := d.keys[g.KeyUUID]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrAccessRight)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = open(cek, &rd.CEKParams, data); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if rd.CEKParams.Compression == CompressionDeflate {
+		return flate.NewReader(bytes.NewReader(data)), nil
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (d *Decrypter) unwrapKeys() error {
+	consumer := -1
+	for i, c := range d.KeyStore.Consumers {
+		if d.ConsumerID == "" || c.ConsumerID == d.ConsumerID {
+			consumer = i
+			break
+		}
+	}
+	if consumer == -1 {
+		return ErrConsumer
+	}
+	d.keys = make(map[string][]byte)
+	for _, g := range d.KeyStore.ResourceDataGroups {
+		for _, a := range g.AccessRights {
+			if a.ConsumerIndex != consumer {
+				continue
+			}
+			cek, err := d.unwrap(d.KeyStore.Consumers[consumer], a)
+			if err != nil {
+				return err
+			}
+			d.keys[g.KeyUUID] = cek
+		}
+	}
+	return nil
+}
+
+func (d *Decrypter) unwrap(c Consumer, a AccessRight) ([]byte, error) {
+	if d.Unwrap != nil {
+		return d.Unwrap(c, a.KEKParams, a.CipherValue)
+	}
+	if d.PrivateKey == nil {
+		return nil, ErrKey
+	}
+	return unwrapKey(d.PrivateKey, a.KEKParams, a.CipherValue)
+}
+
+// RemoveKeyStore removes the KeyStore attachment and its relationship,
+// and the encryptedfile relationships, from m.
+// It must be called before encoding a model decoded with a Decrypter,
+// as the model parts are no longer encrypted.
+func RemoveKeyStore(m *go3mf.Model) {
+	rels := m.RootRelationships[:0]
+	for _, r := range m.RootRelationships {
+		if r.Type == RelTypeEncryptedFile {
+			continue
+		}
+		if r.Type != RelTypeKeyStore {
+			rels = append(rels, r)
+			continue
+		}
+		atts := m.Attachments[:0]
+		for _, a := range m.Attachments {
+			if !strings.EqualFold(a.Path, r.Path) {
+				atts = append(atts, a)
+			}
+		}
+		if len(atts) == 0 {
+			atts = nil
+		}
+		m.Attachments = atts
+	}
+	if len(rels) == 0 {
+		rels = nil
+	}
+	m.RootRelationships = rels
+}
@@ -0,0 +1,152 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package securecontent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/hpinc/go3mf"
+)
+
+type keyStoreFixture struct {
+	ks      *KeyStore
+	content []byte // encrypted content of /3D/a.model
+}
+
+func newKeyStoreFixture(t *testing.T) *keyStoreFixture {
+	t.Helper()
+	k := testKeys(t)
+	cek := make([]byte, cekSize)
+	rand.Read(cek)
+	wrapped, err := wrapKey(rand.Reader, &k[0].PublicKey, cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := CEKParams{EncryptionAlgorithm: AlgorithmAES256GCM, AAD: []byte("aad")}
+	content, err := seal(rand.Reader, cek, &params, []byte("model"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &keyStoreFixture{content: content, ks: &KeyStore{
+		UUID:      "ks",
+		Consumers: []Consumer{{ConsumerID: "a"}, {ConsumerID: "b"}},
+		ResourceDataGroups: []ResourceDataGroup{{
+			KeyUUID:      "group",
+			AccessRights: []AccessRight{{ConsumerIndex: 0, KEKParams: defaultKEKParams(), CipherValue: wrapped}},
+			ResourceData: []ResourceData{{Path: "/3D/a.model", CEKParams: params}},
+		}},
+	}}
+}
+
+func (f *keyStoreFixture) open(name string) (io.ReadCloser, error) {
+	if name != "/Secure/keystore.xml" {
+		return nil, errors.New("not found")
+	}
+	var buf bytes.Buffer
+	if err := f.ks.Encode(&buf); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(&buf), nil
+}
+
+var keyStoreRels = []go3mf.Relationship{
+	{Path: "/3D/3dmodel.model", Type: go3mf.RelType3DModel},
+	{Path: "/Secure/keystore.xml", Type: RelTypeKeyStore},
+}
+
+func TestDecrypter_Decrypt(t *testing.T) {
+	k := testKeys(t)
+	f := newKeyStoreFixture(t)
+	tests := []struct {
+		name    string
+		d       *Decrypter
+		part    string
+		content []byte
+		want    string
+		wantErr error
+	}{
+		{"plain", &Decrypter{PrivateKey: k[0]}, "/3D/b.model", []byte("other"), "other", nil},
+		{"firstConsumer", &Decrypter{PrivateKey: k[0]}, "/3D/a.model", f.content, "model", nil},
+		{"caseInsensitive", &Decrypter{ConsumerID: "a", PrivateKey: k[0]}, "/3D/A.model", f.content, "model", nil},
+		{"unwrap", &Decrypter{ConsumerID: "a", Unwrap: func(c Consumer, params KEKParams, wrapped []byte) ([]byte, error) {
+			return unwrapKey(k[0], params, wrapped)
+		}}, "/3D/a.model", f.content, "model", nil},
+		{"noAccess", &Decrypter{ConsumerID: "b", PrivateKey: k[1]}, "/3D/a.model", f.content, "", ErrAccessRight},
+		{"tampered", &Decrypter{PrivateKey: k[0]}, "/3D/a.model", append([]byte{0}, f.content[1:]...), "", errors.New("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.d.Init(keyStoreRels, f.open); err != nil {
+				t.Fatalf("Decrypter.Init() error = %v", err)
+			}
+			rc, err := tt.d.Decrypt(tt.part, ioutil.NopCloser(bytes.NewReader(tt.content)))
+			if tt.wantErr != nil {
+				if err == nil || (tt.wantErr.Error() != "" && !errors.Is(err, tt.wantErr)) {
+					t.Errorf("Decrypter.Decrypt() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decrypter.Decrypt() error = %v", err)
+			}
+			got, _ := ioutil.ReadAll(rc)
+			if string(got) != tt.want {
+				t.Errorf("Decrypter.Decrypt() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecrypter_Init(t *testing.T) {
+	k := testKeys(t)
+	f := newKeyStoreFixture(t)
+	badAlg := newKeyStoreFixture(t)
+	badAlg.ks.ResourceDataGroups[0].AccessRights[0].KEKParams.DigestMethod = "sha512"
+	tests := []struct {
+		name    string
+		d       *Decrypter
+		rels    []go3mf.Relationship
+		open    func(string) (io.ReadCloser, error)
+		wantErr error
+	}{
+		{"notEncrypted", new(Decrypter), keyStoreRels[:1], f.open, nil},
+		{"noKeyStorePart", new(Decrypter), []go3mf.Relationship{{Path: "/other.xml", Type: RelTypeKeyStore}}, f.open, errors.New("")},
+		{"consumer", &Decrypter{ConsumerID: "c", PrivateKey: k[0]}, keyStoreRels, f.open, ErrConsumer},
+		{"noKey", &Decrypter{ConsumerID: "a"}, keyStoreRels, f.open, ErrKey},
+		{"algorithm", &Decrypter{ConsumerID: "a", PrivateKey: k[0]}, keyStoreRels, badAlg.open, ErrAlgorithm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.d.Init(tt.rels, tt.open)
+			if tt.wantErr == nil {
+				if err != nil || tt.d.KeyStore != nil {
+					t.Errorf("Decrypter.Init() = %v, %v, want nil", tt.d.KeyStore, err)
+				}
+				return
+			}
+			if err == nil || (tt.wantErr.Error() != "" && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("Decrypter.Init() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRemoveKeyStore(t *testing.T) {
+	m := &go3mf.Model{
+		RootRelationships: []go3mf.Relationship{{Path: "/thumb.png", Type: go3mf.RelTypeThumbnail}, {Path: "/Secure/keystore.xml", Type: RelTypeKeyStore}},
+		Attachments:       []go3mf.Attachment{{Path: "/thumb.png"}, {Path: "/Secure/KeyStore.xml"}},
+	}
+	RemoveKeyStore(m)
+	if len(m.RootRelationships) != 1 || m.RootRelationships[0].Path != "/thumb.png" {
+		t.Errorf("RemoveKeyStore() relationships = %v", m.RootRelationships)
+	}
+	if len(m.Attachments) != 1 || m.Attachments[0].Path != "/thumb.png" {
+		t.Errorf("RemoveKeyStore() attachments = %v", m.Attachments)
+	}
+}
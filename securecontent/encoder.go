@@ -0,0 +1,160 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package securecontent
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/uuid"
+)
+
+// A Recipient is a consumer that can decrypt the encrypted parts.
+//
+// The content encryption key is wrapped with PublicKey,
+// or with Wrap if not nil, i.e. to delegate it to a hardware security module.
+type Recipient struct {
+	Consumer
+	PublicKey *rsa.PublicKey
+	Wrap      func(c Consumer, cek []byte) ([]byte, error)
+}
+
+// An Encrypter implements go3mf.PartEncrypter following the Secure Content specification.
+//
+// All the encrypted parts belong to the same resource data group,
+// so they share a content encryption key that is wrapped for every recipient.
+// The KeyStore part is written once the package is closed,
+// after that the Encrypter can be reused but it must not be shared by concurrent encoders.
+type Encrypter struct {
+	Recipients []Recipient
+	// Filter reports whether a part must be encrypted.
+	// If nil all the parts are encrypted.
+	Filter func(name, contentType string) bool
+	// Compress deflates the parts before being encrypted.
+	Compress bool
+	// KeyStorePath is the name of the KeyStore part, DefaultKeyStorePath if empty.
+	KeyStorePath string
+	// Rand is the source of entropy, crypto/rand.Reader if nil.
+	Rand  io.Reader
+	cek   []byte
+	group *ResourceDataGroup
+}
+
+// Encrypt returns a writer that encrypts the part content into w
+// once it is closed, or nil if Filter discards the part.
+func (e *Encrypter) Encrypt(name, contentType string, w io.Writer) (io.WriteCloser, error) {
+	if e.Filter != nil && !e.Filter(name, contentType) {
+		return nil, nil
+	}
+	if len(e.Recipients) == 0 {
+		return nil, ErrConsumer
+	}
+	if e.group == nil {
+		e.cek = make([]byte, cekSize)
+		if _, err := io.ReadFull(e.rand(), e.cek); err != nil {
+			return nil, err
+		}
+		e.group = &ResourceDataGroup{KeyUUID: uuid.New()}
+	}
+	return &partWriter{e: e, name: name, w: w}, nil
+}
+
+// Close writes the KeyStore part, if any part has been encrypted,
+// and the encryptedfile relationships targeting the encrypted parts.
+func (e *Encrypter) Close(create func(name, contentType string) (io.Writer, error), addRelationship func(go3mf.Relationship)) error {
+	if e.group == nil {
+		return nil
+	}
+	defer func() {
+		e.cek, e.group = nil, nil
+	}()
+	ks := &KeyStore{UUID: uuid.New(), Consumers: make([]Consumer, len(e.Recipients))}
+	for i, r := range e.Recipients {
+		ks.Consumers[i] = r.Consumer
+		var (
+			wrapped []byte
+			err     error
+		)
+		switch {
+		case r.Wrap != nil:
+			wrapped, err = r.Wrap(r.Consumer, e.cek)
+		case r.PublicKey != nil:
+			wrapped, err = wrapKey(e.rand(), r.PublicKey, e.cek)
+		default:
+			err = ErrKey
+		}
+		if err != nil {
+			return err
+		}
+		e.group.AccessRights = append(e.group.AccessRights, AccessRight{
+			ConsumerIndex: i,
+			KEKParams:     defaultKEKParams(),
+			CipherValue:   wrapped,
+		})
+	}
+	ks.ResourceDataGroups = []ResourceDataGroup{*e.group}
+	path := e.KeyStorePath
+	if path == "" {
+		path = DefaultKeyStorePath
+	}
+	w, err := create(path, ContentTypeKeyStore)
+	if err != nil {
+		return err
+	}
+	addRelationship(go3mf.Relationship{Path: path, Type: RelTypeKeyStore})
+	for _, rd := range e.group.ResourceData {
+		addRelationship(go3mf.Relationship{Path: rd.Path, Type: RelTypeEncryptedFile})
+	}
+	return ks.Encode(w)
+}
+
+func (e *Encrypter) rand() io.Reader {
+	if e.Rand != nil {
+		return e.Rand
+	}
+	return rand.Reader
+}
+
+// partWriter buffers the part content, as AES-GCM
+// can only encrypt the whole content at once.
+type partWriter struct {
+	e    *Encrypter
+	name string
+	w    io.Writer
+	buf  bytes.Buffer
+}
+
+func (p *partWriter) Write(b []byte) (int, error) {
+	return p.buf.Write(b)
+}
+
+func (p *partWriter) Close() error {
+	params := CEKParams{EncryptionAlgorithm: AlgorithmAES256GCM, Compression: CompressionNone}
+	data := p.buf.Bytes()
+	if p.e.Compress {
+		params.Compression = CompressionDeflate
+		var buf bytes.Buffer
+		fw, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+		if err := fw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	data, err := seal(p.e.rand(), p.e.cek, &params, data)
+	if err != nil {
+		return err
+	}
+	if _, err = p.w.Write(data); err != nil {
+		return err
+	}
+	p.e.group.ResourceData = append(p.e.group.ResourceData, ResourceData{Path: p.name, CEKParams: params})
+	return nil
+}
@@ -0,0 +1,154 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package securecontent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hpinc/go3mf"
+)
+
+var (
+	keysOnce sync.Once
+	keys     [2]*rsa.PrivateKey
+)
+
+func testKeys(t *testing.T) [2]*rsa.PrivateKey {
+	t.Helper()
+	keysOnce.Do(func() {
+		for i := range keys {
+			var err error
+			if keys[i], err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	return keys
+}
+
+func testModel() *go3mf.Model {
+	return &go3mf.Model{
+		Path: go3mf.DefaultModelPath,
+		Attachments: []go3mf.Attachment{
+			{ContentType: "image/png", Path: "/3D/Textures/a.png", Data: []byte("texture")},
+		},
+		Relationships: []go3mf.Relationship{{Path: "/3D/Textures/a.png", Type: go3mf.RelTypeThumbnail, ID: "1"}},
+		Resources:     go3mf.Resources{Objects: []*go3mf.Object{{ID: 1, Mesh: new(go3mf.Mesh)}}},
+		Childs: map[string]*go3mf.ChildModel{
+			"/3D/other.model": {Resources: go3mf.Resources{Objects: []*go3mf.Object{{ID: 2, Mesh: new(go3mf.Mesh)}}}},
+		},
+	}
+}
+
+func TestEncrypter_Roundtrip(t *testing.T) {
+	k := testKeys(t)
+	tests := []struct {
+		name     string
+		compress bool
+		filter   func(string, string) bool
+		want     []string
+		wantRels []string
+	}{
+		{"all", false, nil, []string{"/3D/Textures/a.png", "/3D/other.model"}, []string{"/3D/Textures/a.png"}},
+		{"compress", true, nil, []string{"/3D/Textures/a.png", "/3D/other.model"}, []string{"/3D/Textures/a.png"}},
+		{"filter", false, func(_, ct string) bool { return ct == go3mf.ContentType3DModel }, []string{"/3D/other.model"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wrapped bool
+			e := &Encrypter{
+				Compress: tt.compress,
+				Filter:   tt.filter,
+				Recipients: []Recipient{
+					{Consumer: Consumer{ConsumerID: "a", KeyID: "ka"}, PublicKey: &k[0].PublicKey},
+					{Consumer: Consumer{ConsumerID: "b"}, Wrap: func(c Consumer, cek []byte) ([]byte, error) {
+						wrapped = true
+						return wrapKey(rand.Reader, &k[1].PublicKey, cek)
+					}},
+				},
+			}
+			var buff bytes.Buffer
+			enc := go3mf.NewEncoder(&buff)
+			enc.Encrypter = e
+			if err := enc.Encode(testModel()); err != nil {
+				t.Fatalf("Encoder.Encode() error = %v", err)
+			}
+			if !wrapped {
+				t.Error("Recipient.Wrap not called")
+			}
+			if e.group != nil || e.cek != nil {
+				t.Error("Encrypter.Close() did not reset the encrypter")
+			}
+			for i, id := range []string{"a", "b"} {
+				d := &Decrypter{ConsumerID: id, PrivateKey: k[i]}
+				dec := go3mf.NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+				dec.Decrypter = d
+				got := new(go3mf.Model)
+				if err := dec.Decode(got); err != nil {
+					t.Fatalf("Decoder.Decode() error = %v", err)
+				}
+				var paths []string
+				for _, rd := range d.KeyStore.ResourceDataGroups[0].ResourceData {
+					paths = append(paths, rd.Path)
+				}
+				if diff := deep.Equal(paths, tt.want); diff != nil {
+					t.Errorf("Encrypter encrypted parts = %v", diff)
+				}
+				// The encryptedfile relationships targeting model parts are not preserved.
+				var rels []string
+				for _, r := range got.RootRelationships {
+					if r.Type == RelTypeEncryptedFile {
+						rels = append(rels, r.Path)
+					}
+				}
+				if diff := deep.Equal(rels, tt.wantRels); diff != nil {
+					t.Errorf("Encrypter encryptedfile relationships = %v", diff)
+				}
+				if err := (Spec{}).Validate(got, got.Path, got); err != nil {
+					t.Errorf("Spec.Validate() error = %v", err)
+				}
+				RemoveKeyStore(got)
+				for j := range got.Attachments {
+					rc, err := got.Attachments[j].Open()
+					if err != nil {
+						t.Fatalf("Attachment.Open() error = %v", err)
+					}
+					got.Attachments[j].Data, _ = ioutil.ReadAll(rc)
+					got.Attachments[j].Stream = nil
+					rc.Close()
+				}
+				if diff := deep.Equal(got, testModel()); diff != nil {
+					t.Errorf("Decoder.Decode() = %v", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestEncrypter_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		e    *Encrypter
+		want error
+	}{
+		{"noRecipients", new(Encrypter), ErrConsumer},
+		{"noKey", &Encrypter{Recipients: []Recipient{{Consumer: Consumer{ConsumerID: "a"}}}}, ErrKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := go3mf.NewEncoder(new(bytes.Buffer))
+			enc.Encrypter = tt.e
+			if err := enc.Encode(testModel()); !errors.Is(err, tt.want) {
+				t.Errorf("Encoder.Encode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
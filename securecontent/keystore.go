@@ -0,0 +1,139 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package securecontent
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+)
+
+type keyStoreXML struct {
+	XMLName   xml.Name               `xml:"keystore"`
+	XMLNS     string                 `xml:"xmlns,attr"`
+	UUID      string                 `xml:"UUID,attr"`
+	Consumers []consumerXML          `xml:"consumer"`
+	Groups    []resourceDataGroupXML `xml:"resourcedatagroup"`
+}
+
+type consumerXML struct {
+	ConsumerID string `xml:"consumerid,attr"`
+	KeyID      string `xml:"keyid,attr,omitempty"`
+	KeyValue   string `xml:"keyvalue,omitempty"`
+}
+
+type resourceDataGroupXML struct {
+	KeyUUID      string            `xml:"keyuuid,attr"`
+	AccessRights []accessRightXML  `xml:"accessright"`
+	ResourceData []resourceDataXML `xml:"resourcedata"`
+}
+
+type accessRightXML struct {
+	ConsumerIndex int           `xml:"consumerindex,attr"`
+	KEKParams     kekParamsXML  `xml:"kekparams"`
+	CipherData    cipherDataXML `xml:"cipherdata"`
+}
+
+type cipherDataXML struct {
+	CipherValue string `xml:"http://www.w3.org/2001/04/xmlenc# CipherValue"`
+}
+
+type kekParamsXML struct {
+	WrappingAlgorithm string `xml:"wrappingalgorithm,attr"`
+	MGFAlgorithm      string `xml:"mgfalgorithm,attr,omitempty"`
+	DigestMethod      string `xml:"digestmethod,attr,omitempty"`
+}
+
+type resourceDataXML struct {
+	Path      string       `xml:"path,attr"`
+	CEKParams cekParamsXML `xml:"cekparams"`
+}
+
+type cekParamsXML struct {
+	EncryptionAlgorithm string `xml:"encryptionalgorithm,attr"`
+	Compression         string `xml:"compression,attr,omitempty"`
+	IV                  string `xml:"iv"`
+	Tag                 string `xml:"tag"`
+	AAD                 string `xml:"aad,omitempty"`
+}
+
+// DecodeKeyStore decodes the content of a KeyStore part.
+func DecodeKeyStore(r io.Reader) (*KeyStore, error) {
+	var kx keyStoreXML
+	if err := xml.NewDecoder(r).Decode(&kx); err != nil {
+		return nil, err
+	}
+	k := &KeyStore{UUID: kx.UUID, Consumers: make([]Consumer, len(kx.Consumers))}
+	for i, c := range kx.Consumers {
+		k.Consumers[i] = Consumer{ConsumerID: c.ConsumerID, KeyID: c.KeyID, KeyValue: c.KeyValue}
+	}
+	var err error
+	decode := func(s string) []byte {
+		if err != nil || s == "" {
+			return nil
+		}
+		var b []byte
+		b, err = base64.StdEncoding.DecodeString(s)
+		return b
+	}
+	for _, gx := range kx.Groups {
+		g := ResourceDataGroup{KeyUUID: gx.KeyUUID}
+		for _, ax := range gx.AccessRights {
+			g.AccessRights = append(g.AccessRights, AccessRight{
+				ConsumerIndex: ax.ConsumerIndex,
+				KEKParams:     KEKParams(ax.KEKParams),
+				CipherValue:   decode(ax.CipherData.CipherValue),
+			})
+		}
+		for _, rx := range gx.ResourceData {
+			p := rx.CEKParams
+			g.ResourceData = append(g.ResourceData, ResourceData{Path: rx.Path, CEKParams: CEKParams{
+				EncryptionAlgorithm: p.EncryptionAlgorithm,
+				Compression:         p.Compression,
+				IV:                  decode(p.IV),
+				Tag:                 decode(p.Tag),
+				AAD:                 decode(p.AAD),
+			}})
+		}
+		k.ResourceDataGroups = append(k.ResourceDataGroups, g)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Encode writes the XML encoding of k.
+func (k *KeyStore) Encode(w io.Writer) error {
+	encode := base64.StdEncoding.EncodeToString
+	kx := keyStoreXML{XMLNS: Namespace, UUID: k.UUID, Consumers: make([]consumerXML, len(k.Consumers))}
+	for i, c := range k.Consumers {
+		kx.Consumers[i] = consumerXML{ConsumerID: c.ConsumerID, KeyID: c.KeyID, KeyValue: c.KeyValue}
+	}
+	for _, g := range k.ResourceDataGroups {
+		gx := resourceDataGroupXML{KeyUUID: g.KeyUUID}
+		for _, a := range g.AccessRights {
+			gx.AccessRights = append(gx.AccessRights, accessRightXML{
+				ConsumerIndex: a.ConsumerIndex,
+				KEKParams:     kekParamsXML(a.KEKParams),
+				CipherData:    cipherDataXML{CipherValue: encode(a.CipherValue)},
+			})
+		}
+		for _, r := range g.ResourceData {
+			p := r.CEKParams
+			gx.ResourceData = append(gx.ResourceData, resourceDataXML{Path: r.Path, CEKParams: cekParamsXML{
+				EncryptionAlgorithm: p.EncryptionAlgorithm,
+				Compression:         p.Compression,
+				IV:                  encode(p.IV),
+				Tag:                 encode(p.Tag),
+				AAD:                 encode(p.AAD),
+			}})
+		}
+		kx.Groups = append(kx.Groups, gx)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(&kx)
+}
@@ -0,0 +1,91 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package securecontent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestKeyStore_Encode(t *testing.T) {
+	want := &KeyStore{
+		UUID:      "b7ac2ab6-29c6-4ae5-a8e4-2a1e7b5e0d1c",
+		Consumers: []Consumer{{ConsumerID: "a", KeyID: "ka", KeyValue: "pem"}, {ConsumerID: "b"}},
+		ResourceDataGroups: []ResourceDataGroup{{
+			KeyUUID: "4a3c3f0e-2d6a-4f8b-a1b1-5e7a9c2d0b3e",
+			AccessRights: []AccessRight{
+				{ConsumerIndex: 0, KEKParams: defaultKEKParams(), CipherValue: []byte{1, 2, 3}},
+				{ConsumerIndex: 1, KEKParams: KEKParams{WrappingAlgorithm: AlgorithmRSAOAEP}, CipherValue: []byte{4}},
+			},
+			ResourceData: []ResourceData{
+				{Path: "/3D/a.model", CEKParams: CEKParams{EncryptionAlgorithm: AlgorithmAES256GCM, Compression: CompressionDeflate, IV: []byte{5}, Tag: []byte{6}, AAD: []byte{7}}},
+				{Path: "/3D/b.model", CEKParams: CEKParams{EncryptionAlgorithm: AlgorithmAES256GCM, Compression: CompressionNone, IV: []byte{8}, Tag: []byte{9}}},
+			},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := want.Encode(&buf); err != nil {
+		t.Fatalf("KeyStore.Encode() error = %v", err)
+	}
+	got, err := DecodeKeyStore(&buf)
+	if err != nil {
+		t.Fatalf("DecodeKeyStore() error = %v", err)
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("KeyStore.Encode() = %v", diff)
+	}
+}
+
+func TestDecodeKeyStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *KeyStore
+		wantErr bool
+	}{
+		{"malformed", `<keystore`, nil, true},
+		{"base64", `<keystore><resourcedatagroup><accessright><cipherdata>
+			<xenc:CipherValue xmlns:xenc="http://www.w3.org/2001/04/xmlenc#">%%%</xenc:CipherValue>
+		</cipherdata></accessright></resourcedatagroup></keystore>`, nil, true},
+		{"prefixed", `<?xml version="1.0" encoding="UTF-8"?>
+		<keystore xmlns="http://schemas.microsoft.com/3dmanufacturing/securecontent/2019/07" xmlns:xenc="http://www.w3.org/2001/04/xmlenc#" UUID="ks">
+			<consumer consumerid="HP#1" keyid="k1"><keyvalue>pem</keyvalue></consumer>
+			<resourcedatagroup keyuuid="g1">
+				<accessright consumerindex="0">
+					<kekparams wrappingalgorithm="http://www.w3.org/2009/xmlenc11#rsa-oaep" mgfalgorithm="http://www.w3.org/2009/xmlenc11#mgf1sha1" digestmethod="http://www.w3.org/2000/09/xmldsig#sha1"/>
+					<cipherdata><xenc:CipherValue>AQID</xenc:CipherValue></cipherdata>
+				</accessright>
+				<resourcedata path="/3D/a.model">
+					<cekparams encryptionalgorithm="http://www.w3.org/2009/xmlenc11#aes256-gcm" compression="deflate">
+						<iv>BA==</iv><tag>BQ==</tag><aad>Bg==</aad>
+					</cekparams>
+				</resourcedata>
+			</resourcedatagroup>
+		</keystore>`, &KeyStore{
+			UUID:      "ks",
+			Consumers: []Consumer{{ConsumerID: "HP#1", KeyID: "k1", KeyValue: "pem"}},
+			ResourceDataGroups: []ResourceDataGroup{{
+				KeyUUID:      "g1",
+				AccessRights: []AccessRight{{ConsumerIndex: 0, KEKParams: defaultKEKParams(), CipherValue: []byte{1, 2, 3}}},
+				ResourceData: []ResourceData{{Path: "/3D/a.model", CEKParams: CEKParams{
+					EncryptionAlgorithm: AlgorithmAES256GCM, Compression: CompressionDeflate, IV: []byte{4}, Tag: []byte{5}, AAD: []byte{6},
+				}}},
+			}},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeKeyStore(strings.NewReader(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeKeyStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Errorf("DecodeKeyStore() = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+// Package securecontent implements the 3MF Secure Content extension,
+// which encrypts package parts so they can only be read by specific consumers.
+//
+// Parts are encrypted with AES-256-GCM using a content encryption key (CEK)
+// that is wrapped, for each consumer, with RSA-OAEP. The information required
+// to decrypt the parts is stored in the KeyStore part.
+//
+// Encrypter and Decrypter plug into go3mf.Encoder and go3mf.Decoder:
+//
+//	enc := go3mf.NewEncoder(w)
+//	enc.Encrypter = &securecontent.Encrypter{Recipients: recipients}
+//
+//	dec := go3mf.NewDecoder(r, size)
+//	dec.Decrypter = &securecontent.Decrypter{ConsumerID: "printer", PrivateKey: key}
+//
+// Importing the package also registers the extension spec,
+// which validates the KeyStore part of the models declaring it.
+package securecontent
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+
+	"github.com/hpinc/go3mf"
+	specerr "github.com/hpinc/go3mf/errors"
+	"github.com/hpinc/go3mf/spec"
+)
+
+const (
+	// Namespace is the canonical name of this extension.
+	Namespace = "http://schemas.microsoft.com/3dmanufacturing/securecontent/2019/07"
+	// RelTypeKeyStore is the type of the package relationship targeting the KeyStore part.
+	RelTypeKeyStore = "http://schemas.microsoft.com/3dmanufacturing/2019/07/keystore"
+	// RelTypeEncryptedFile is the type of the package relationships targeting the encrypted parts.
+	RelTypeEncryptedFile = "http://schemas.microsoft.com/3dmanufacturing/2019/07/encryptedfile"
+	// ContentTypeKeyStore is the content type of the KeyStore part.
+	ContentTypeKeyStore = "application/vnd.ms-package.3dmanufacturing-keystore+xml"
+	// DefaultKeyStorePath is the default name of the KeyStore part.
+	DefaultKeyStorePath = "/Secure/keystore.xml"
+)
+
+// Supported algorithms.
+const (
+	AlgorithmAES256GCM = "http://www.w3.org/2009/xmlenc11#aes256-gcm"
+	AlgorithmRSAOAEP   = "http://www.w3.org/2009/xmlenc11#rsa-oaep"
+	AlgorithmMGF1SHA1  = "http://www.w3.org/2009/xmlenc11#mgf1sha1"
+	AlgorithmSHA1      = "http://www.w3.org/2000/09/xmldsig#sha1"
+)
+
+// Compression applied to the content before being encrypted.
+const (
+	CompressionNone    = "none"
+	CompressionDeflate = "deflate"
+)
+
+var DefaultExtension = go3mf.Extension{
+	Namespace:  Namespace,
+	LocalName:  "sc",
+	IsRequired: false,
+}
+
+func init() {
+	spec.Register(Namespace, Spec{})
+}
+
+// Spec validates the KeyStore part of the models
+// that declare the extension or that are validated with it enabled.
+// The model XML does not contain elements nor attributes of this extension.
+type Spec struct{}
+
+func (Spec) NewElementDecoder(xml.Name) spec.GetterElementDecoder {
+	return nil
+}
+
+func (Spec) NewAttrGroup(xml.Name) spec.AttrGroup {
+	return nil
+}
+
+var (
+	ErrKeyStoreUUID  = specerr.NewRule("securecontent-2-uuid", specerr.SeverityError, "keystore UUID and resourcedatagroup keyuuid MUST be any of the four UUID variants described in IETF RFC 4122")
+	ErrConsumerIndex = specerr.NewRule("securecontent-2.2.1-consumerindex", specerr.SeverityError, "accessright consumerindex MUST reference a consumer of the keystore")
+	ErrEncryptedFile = specerr.NewRule("securecontent-3-encryptedfile", specerr.SeverityError, "encryptedfile relationships MUST target a part described by a resourcedata element")
+)
+
+var (
+	ErrConsumer    = errors.New("securecontent: consumer is not defined in the keystore")
+	ErrAccessRight = errors.New("securecontent: consumer does not have access to the part")
+	ErrAlgorithm   = errors.New("securecontent: unsupported algorithm")
+	ErrKey         = errors.New("securecontent: no key to wrap or unwrap the content encryption key")
+)
+
+// KeyStore contains the consumers and the information
+// required to decrypt the encrypted parts.
+type KeyStore struct {
+	UUID               string
+	Consumers          []Consumer
+	ResourceDataGroups []ResourceDataGroup
+}
+
+// FindResourceData returns the group and the resource data of the part path.
+// Part names are compared case-insensitively.
+func (k *KeyStore) FindResourceData(path string) (*ResourceDataGroup, *ResourceData, bool) {
+	for i := range k.ResourceDataGroups {
+		g := &k.ResourceDataGroups[i]
+		for j := range g.ResourceData {
+			if strings.EqualFold(g.ResourceData[j].Path, path) {
+				return g, &g.ResourceData[j], true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// Consumer is an entity allowed to decrypt some of the encrypted parts.
+type Consumer struct {
+	ConsumerID string
+	KeyID      string
+	KeyValue   string // PEM encoded public key, optional.
+}
+
+// ResourceDataGroup is a set of parts encrypted with the same CEK.
+type ResourceDataGroup struct {
+	KeyUUID      string
+	AccessRights []AccessRight
+	ResourceData []ResourceData
+}
+
+// AccessRight contains the CEK of a group wrapped for a consumer.
+type AccessRight struct {
+	ConsumerIndex int
+	KEKParams     KEKParams
+	CipherValue   []byte
+}
+
+// KEKParams defines how the CEK is wrapped.
+type KEKParams struct {
+	WrappingAlgorithm string
+	MGFAlgorithm      string
+	DigestMethod      string
+}
+
+// ResourceData describes an encrypted part.
+type ResourceData struct {
+	Path      string
+	CEKParams CEKParams
+}
+
+// CEKParams defines how a part is encrypted.
+type CEKParams struct {
+	EncryptionAlgorithm string
+	Compression         string
+	IV                  []byte
+	Tag                 []byte
+	AAD                 []byte
+}
@@ -0,0 +1,73 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package securecontent
+
+import (
+	"strings"
+
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/errors"
+	"github.com/hpinc/go3mf/uuid"
+)
+
+func (Spec) Validate(model interface{}, path string, e interface{}) error {
+	if m, ok := e.(*go3mf.Model); ok {
+		return validateKeyStore(m)
+	}
+	return nil
+}
+
+// validateKeyStore checks the KeyStore part targeted by the root relationships of m.
+// Models without a KeyStore attachment, such as the ones not encoded yet, are not checked.
+func validateKeyStore(m *go3mf.Model) error {
+	var ksPath string
+	for _, r := range m.RootRelationships {
+		if r.Type == RelTypeKeyStore {
+			ksPath = r.Path
+			break
+		}
+	}
+	var att *go3mf.Attachment
+	for i := range m.Attachments {
+		if ksPath != "" && strings.EqualFold(m.Attachments[i].Path, ksPath) {
+			att = &m.Attachments[i]
+			break
+		}
+	}
+	if att == nil {
+		return nil
+	}
+	rc, err := att.Open()
+	if err != nil {
+		return &errors.Error{Err: err, Path: ksPath}
+	}
+	ks, err := DecodeKeyStore(rc)
+	rc.Close()
+	if err != nil {
+		return &errors.Error{Err: err, Path: ksPath}
+	}
+	var errs error
+	if uuid.Validate(ks.UUID) != nil {
+		errs = errors.Append(errs, &errors.Error{Err: ErrKeyStoreUUID, Path: ksPath})
+	}
+	for _, g := range ks.ResourceDataGroups {
+		if uuid.Validate(g.KeyUUID) != nil {
+			errs = errors.Append(errs, &errors.Error{Err: ErrKeyStoreUUID, Path: ksPath})
+		}
+		for _, a := range g.AccessRights {
+			if a.ConsumerIndex < 0 || a.ConsumerIndex >= len(ks.Consumers) {
+				errs = errors.Append(errs, &errors.Error{Err: ErrConsumerIndex, Path: ksPath})
+			}
+		}
+	}
+	for _, r := range m.RootRelationships {
+		if r.Type != RelTypeEncryptedFile {
+			continue
+		}
+		if _, _, ok := ks.FindResourceData(r.Path); !ok {
+			errs = errors.Append(errs, &errors.Error{Err: ErrEncryptedFile, Path: r.Path})
+		}
+	}
+	return errs
+}
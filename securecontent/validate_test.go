@@ -0,0 +1,87 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package securecontent
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/errors"
+	"github.com/hpinc/go3mf/spec"
+)
+
+func TestValidate(t *testing.T) {
+	const (
+		ksUUID    = "f47ac10b-58cc-0372-8567-0e02b2c3d479"
+		groupUUID = "f47ac10b-58cc-0372-8567-0e02b2c3d480"
+	)
+	model := func(ks *KeyStore, encrypted ...string) *go3mf.Model {
+		var buf bytes.Buffer
+		if ks != nil {
+			if err := ks.Encode(&buf); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			buf.WriteString("<keystore")
+		}
+		m := &go3mf.Model{
+			Extensions: []go3mf.Extension{DefaultExtension},
+			Attachments: []go3mf.Attachment{
+				{Path: DefaultKeyStorePath, ContentType: ContentTypeKeyStore, Data: buf.Bytes()},
+				{Path: "/3D/Textures/a.png", ContentType: "image/png", Data: []byte("texture")},
+			},
+			RootRelationships: []go3mf.Relationship{{Path: DefaultKeyStorePath, Type: RelTypeKeyStore}},
+		}
+		for _, p := range encrypted {
+			m.RootRelationships = append(m.RootRelationships, go3mf.Relationship{Path: p, Type: RelTypeEncryptedFile})
+		}
+		return m
+	}
+	tests := []struct {
+		name  string
+		model *go3mf.Model
+		want  []string
+	}{
+		{"noKeyStore", &go3mf.Model{Extensions: []go3mf.Extension{DefaultExtension}}, nil},
+		{"valid", model(&KeyStore{UUID: ksUUID, Consumers: []Consumer{{ConsumerID: "a"}}, ResourceDataGroups: []ResourceDataGroup{{
+			KeyUUID:      groupUUID,
+			AccessRights: []AccessRight{{ConsumerIndex: 0}},
+			ResourceData: []ResourceData{{Path: "/3D/Textures/a.png"}},
+		}}}, "/3D/Textures/a.png"), nil},
+		{"malformed", model(nil), []string{
+			"go3mf: Path: /Secure/keystore.xml XPath: /model: XML syntax error on line 1: unexpected EOF",
+		}},
+		{"invalid", model(&KeyStore{UUID: "a-b-c-d", Consumers: []Consumer{{ConsumerID: "a"}}, ResourceDataGroups: []ResourceDataGroup{{
+			KeyUUID:      "group",
+			AccessRights: []AccessRight{{ConsumerIndex: 0}, {ConsumerIndex: 1}},
+		}}}, "/3D/Textures/a.png"), []string{
+			fmt.Sprintf("go3mf: Path: /Secure/keystore.xml XPath: /model: %v", ErrKeyStoreUUID),
+			fmt.Sprintf("go3mf: Path: /Secure/keystore.xml XPath: /model: %v", ErrKeyStoreUUID),
+			fmt.Sprintf("go3mf: Path: /Secure/keystore.xml XPath: /model: %v", ErrConsumerIndex),
+			fmt.Sprintf("go3mf: Path: /3D/Textures/a.png XPath: /model: %v", ErrEncryptedFile),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []string
+			if err := tt.model.Validate(); err != nil {
+				for _, err := range err.(*errors.List).Errors {
+					errs = append(errs, err.Error())
+				}
+			}
+			if diff := deep.Equal(errs, tt.want); diff != nil {
+				t.Errorf("Validate() = %v", diff)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if _, ok := spec.LoadValidator(Namespace); !ok {
+		t.Error("securecontent spec is not registered as a validator")
+	}
+}
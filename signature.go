@@ -0,0 +1,843 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1" // register the digest algorithms supported by the verifier.
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	xml3mf "github.com/hpinc/go3mf/internal/xml"
+	"github.com/qmuntal/opc"
+)
+
+const (
+	// RelTypeDigitalSignatureOrigin is the package relationship type of the digital signature origin part.
+	RelTypeDigitalSignatureOrigin = "http://schemas.openxmlformats.org/package/2006/relationships/digital-signature/origin"
+	// RelTypeDigitalSignature is the relationship type from the origin part to a signature part.
+	RelTypeDigitalSignature = "http://schemas.openxmlformats.org/package/2006/relationships/digital-signature/signature"
+	// RelTypeDigitalSignatureCertificate is the relationship type from a signature part to a certificate part.
+	RelTypeDigitalSignatureCertificate = "http://schemas.openxmlformats.org/package/2006/relationships/digital-signature/certificate"
+
+	// ContentTypeSignatureOrigin is the digital signature origin content type.
+	ContentTypeSignatureOrigin = "application/vnd.openxmlformats-package.digital-signature-origin"
+	// ContentTypeXMLSignature is the XML digital signature content type.
+	ContentTypeXMLSignature = "application/vnd.openxmlformats-package.digital-signature-xmlsignature+xml"
+	// ContentTypeSignatureCertificate is the digital signature certificate content type.
+	ContentTypeSignatureCertificate = "application/vnd.openxmlformats-package.digital-signature-certificate"
+
+	// DefaultSignatureOriginPath is the recommended digital signature origin part name.
+	DefaultSignatureOriginPath = "/package/services/digital-signature/origin.psdsor"
+	signatureDir               = "/package/services/digital-signature/xml-signature/"
+)
+
+const (
+	nsXMLDSig           = "http://www.w3.org/2000/09/xmldsig#"
+	nsDigitalSignature  = "http://schemas.openxmlformats.org/package/2006/digital-signature"
+	algC14N             = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	algRelationships    = "http://schemas.openxmlformats.org/package/2006/RelationshipTransform"
+	algSHA1             = "http://www.w3.org/2000/09/xmldsig#sha1"
+	algSHA256           = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algSHA512           = "http://www.w3.org/2001/04/xmlenc#sha512"
+	algRSASHA1          = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	algRSASHA256        = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algECDSASHA256      = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	signatureTimeFormat = "2006-01-02T15:04:05Z07:00"
+	packageObjectID     = "idPackageObject"
+	packageSignatureID  = "idPackageSignature"
+)
+
+var (
+	// ErrSignatureValue is returned when the signature value does not match the signed info.
+	ErrSignatureValue = errors.New("go3mf: invalid signature value")
+	// ErrSignatureDigest is returned when a digest does not match the referenced content.
+	ErrSignatureDigest = errors.New("go3mf: digest does not match the signed content")
+	// ErrSignatureAlgorithm is returned when a signature uses an unsupported algorithm or transform.
+	ErrSignatureAlgorithm = errors.New("go3mf: unsupported signature algorithm")
+	// ErrSignatureCertificate is returned when the signature does not embed a usable certificate.
+	ErrSignatureCertificate = errors.New("go3mf: missing or unsupported signature certificate")
+	// ErrSignatureStructure is returned when the signature part is not a single Signature element
+	// with a single SignedInfo child, or when it contains duplicated Id attributes.
+	ErrSignatureStructure = errors.New("go3mf: malformed signature")
+	// ErrSignatureCoverage is returned when the package contains a part or a relationship
+	// not covered by the signature.
+	ErrSignatureCoverage = errors.New("go3mf: content not covered by the signature")
+	// ErrSignatureUntrusted is returned when the signed content is intact
+	// but the signer was not verified, as no roots were given to Decoder.Signatures.
+	ErrSignatureUntrusted = errors.New("go3mf: signer not verified")
+)
+
+// A Signer signs a package with an OPC digital signature while encoding.
+//
+// The signature covers all the parts written by the Encoder and their relationships,
+// which are signed with the OPC relationship transform.
+// The signature origin and signature parts are not covered,
+// so the package can hold further signatures.
+type Signer struct {
+	// Key is the RSA or ECDSA private key of Certificate.
+	Key         crypto.Signer
+	Certificate *x509.Certificate
+	// Intermediates are embedded in the signature so verifiers can build the certificate chain.
+	Intermediates []*x509.Certificate
+	// Time is the signing time. If zero, time.Now() is used.
+	Time time.Time
+}
+
+// A Signature is an OPC digital signature found in a package.
+type Signature struct {
+	// Path is the name of the signature part.
+	Path        string
+	Certificate *x509.Certificate
+	Time        time.Time
+	// Parts are the parts referenced by the signature.
+	Parts []SignedPart
+	// Err is nil if the signature is valid and its certificate
+	// chains up to the roots given to Decoder.Signatures.
+	// It is ErrSignatureUntrusted if the content is intact but no roots were given.
+	Err error
+}
+
+// A SignedPart is a package part referenced by a signature.
+type SignedPart struct {
+	Name        string
+	ContentType string
+	// Valid reports whether the part content matches the signed digest.
+	Valid bool
+}
+
+type partDigest struct {
+	name, contentType string
+	h                 hash.Hash
+}
+
+type signedPart struct {
+	packagePart
+	h hash.Hash
+}
+
+func (p *signedPart) Write(b []byte) (int, error) {
+	n, err := p.packagePart.Write(b)
+	p.h.Write(b[:n])
+	return n, err
+}
+
+// sign records the digest of the content written to w.
+func (e *Encoder) sign(name, contentType string, w packagePart) packagePart {
+	d := partDigest{name: normalizePartName(name), contentType: contentType, h: sha256.New()}
+	e.digests = append(e.digests, d)
+	return &signedPart{packagePart: w, h: d.h}
+}
+
+// writeSignature writes the signature origin and the signature parts.
+func (e *Encoder) writeSignature() error {
+	s := e.Signer
+	if s.Key == nil || s.Certificate == nil {
+		return ErrSignatureCertificate
+	}
+	method := algRSASHA256
+	if _, ok := s.Key.Public().(*ecdsa.PublicKey); ok {
+		method = algECDSASHA256
+	}
+	signTime := s.Time
+	if signTime.IsZero() {
+		signTime = time.Now()
+	}
+
+	relsParts, err := e.w.relationshipsParts()
+	if err != nil {
+		return err
+	}
+	var obj bytes.Buffer
+	fmt.Fprintf(&obj, `<Object Id="%s"><Manifest>`, packageObjectID)
+	for _, d := range e.digests {
+		writeReference(&obj, "", d.name+"?ContentType="+d.contentType, nil, d.h.Sum(nil))
+	}
+	for _, p := range relsParts {
+		if err = writeRelationshipsReference(&obj, p); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(&obj, `</Manifest><SignatureProperties><SignatureProperty Id="idSignatureTime" Target="#%s">`, packageSignatureID)
+	fmt.Fprintf(&obj, `<mdssi:SignatureTime xmlns:mdssi="%s"><mdssi:Format>YYYY-MM-DDThh:mm:ssTZD</mdssi:Format>`, nsDigitalSignature)
+	fmt.Fprintf(&obj, `<mdssi:Value>%s</mdssi:Value></mdssi:SignatureTime>`, signTime.UTC().Format(signatureTimeFormat))
+	obj.WriteString(`</SignatureProperty></SignatureProperties></Object>`)
+	objDigest, err := canonicalDigest(obj.Bytes(), "Object")
+	if err != nil {
+		return err
+	}
+
+	var info bytes.Buffer
+	fmt.Fprintf(&info, `<SignedInfo><CanonicalizationMethod Algorithm="%s"></CanonicalizationMethod>`, algC14N)
+	fmt.Fprintf(&info, `<SignatureMethod Algorithm="%s"></SignatureMethod>`, method)
+	writeReference(&info, nsXMLDSig+"Object", "#"+packageObjectID, nil, objDigest)
+	info.WriteString(`</SignedInfo>`)
+	infoDigest, err := canonicalDigest(info.Bytes(), "SignedInfo")
+	if err != nil {
+		return err
+	}
+	value, err := s.Key.Sign(rand.Reader, infoDigest, crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	if pub, ok := s.Key.Public().(*ecdsa.PublicKey); ok {
+		if value, err = rawECDSASignature(value, pub); err != nil {
+			return err
+		}
+	}
+
+	origin, err := e.w.Create(DefaultSignatureOriginPath, ContentTypeSignatureOrigin, e.Compression)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(s.Certificate.Raw)
+	name := signatureDir + hex.EncodeToString(sum[:8]) + ".psdsxs"
+	origin.AddRelationship(Relationship{Type: RelTypeDigitalSignature, Path: name})
+	e.w.AddRelationship(Relationship{Type: RelTypeDigitalSignatureOrigin, Path: DefaultSignatureOriginPath})
+	w, err := e.w.Create(name, ContentTypeXMLSignature, e.Compression)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, `<Signature xmlns="%s" Id="%s">`, nsXMLDSig, packageSignatureID)
+	buf.Write(info.Bytes())
+	fmt.Fprintf(&buf, `<SignatureValue>%s</SignatureValue><KeyInfo><X509Data>`, base64.StdEncoding.EncodeToString(value))
+	for _, c := range append([]*x509.Certificate{s.Certificate}, s.Intermediates...) {
+		fmt.Fprintf(&buf, `<X509Certificate>%s</X509Certificate>`, base64.StdEncoding.EncodeToString(c.Raw))
+	}
+	buf.WriteString(`</X509Data></KeyInfo>`)
+	buf.Write(obj.Bytes())
+	buf.WriteString(`</Signature>`)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeReference(w *bytes.Buffer, tp, uri string, transforms, digest []byte) {
+	w.WriteString(`<Reference`)
+	if tp != "" {
+		fmt.Fprintf(w, ` Type="%s"`, tp)
+	}
+	w.WriteString(` URI="`)
+	xml.EscapeText(w, []byte(uri))
+	w.WriteString(`">`)
+	w.Write(transforms)
+	fmt.Fprintf(w, `<DigestMethod Algorithm="%s"></DigestMethod>`, algSHA256)
+	fmt.Fprintf(w, `<DigestValue>%s</DigestValue></Reference>`, base64.StdEncoding.EncodeToString(digest))
+}
+
+// writeRelationshipsReference writes the reference of the relationships part p,
+// selecting each relationship by ID with the OPC relationship transform.
+func writeRelationshipsReference(w *bytes.Buffer, p relationshipsPart) error {
+	var transforms bytes.Buffer
+	fmt.Fprintf(&transforms, `<Transforms><Transform Algorithm="%s">`, algRelationships)
+	rels := make([]*opc.Relationship, len(p.rels))
+	for i, r := range p.rels {
+		rels[i] = &opc.Relationship{ID: r.ID, Type: r.Type, TargetURI: r.Path}
+		fmt.Fprintf(&transforms, `<mdssi:RelationshipReference xmlns:mdssi="%s" SourceId="`, nsDigitalSignature)
+		xml.EscapeText(&transforms, []byte(r.ID))
+		transforms.WriteString(`"></mdssi:RelationshipReference>`)
+	}
+	fmt.Fprintf(&transforms, `</Transform><Transform Algorithm="%s"></Transform></Transforms>`, algC14N)
+	c, err := canonicalRelationships(rels)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(c)
+	writeReference(w, "", p.name+"?ContentType="+contentTypeRelationships, transforms.Bytes(), sum[:])
+	return nil
+}
+
+// canonicalDigest returns the SHA-256 digest of the canonical form of el,
+// as if it were a child of the Signature element.
+func canonicalDigest(el []byte, local string) ([]byte, error) {
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, `<Signature xmlns="%s">`, nsXMLDSig)
+	doc.Write(el)
+	doc.WriteString(`</Signature>`)
+	c, err := xml3mf.Canonicalize(&doc, func(s xml.StartElement) bool { return s.Name.Local == local })
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(c)
+	return sum[:], nil
+}
+
+func rawECDSASignature(der []byte, pub *ecdsa.PublicKey) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	}
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	raw := make([]byte, 2*size)
+	r, s := sig.R.Bytes(), sig.S.Bytes()
+	copy(raw[size-len(r):size], r)
+	copy(raw[2*size-len(s):], s)
+	return raw, nil
+}
+
+type algorithmXML struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+
+type transformXML struct {
+	Algorithm string `xml:"Algorithm,attr"`
+	SourceIDs []struct {
+		ID string `xml:"SourceId,attr"`
+	} `xml:"RelationshipReference"`
+	SourceTypes []struct {
+		Type string `xml:"SourceType,attr"`
+	} `xml:"RelationshipsGroupReference"`
+}
+
+type referenceXML struct {
+	URI          string         `xml:"URI,attr"`
+	Transforms   []transformXML `xml:"Transforms>Transform"`
+	DigestMethod algorithmXML
+	DigestValue  string
+}
+
+type objectXML struct {
+	ID            string         `xml:"Id,attr"`
+	References    []referenceXML `xml:"Manifest>Reference"`
+	SignatureTime string         `xml:"SignatureProperties>SignatureProperty>SignatureTime>Value"`
+}
+
+type signatureXML struct {
+	SignedInfo struct {
+		CanonicalizationMethod algorithmXML
+		SignatureMethod        algorithmXML
+		References             []referenceXML `xml:"Reference"`
+	}
+	SignatureValue string
+	Certificates   []string    `xml:"KeyInfo>X509Data>X509Certificate"`
+	Objects        []objectXML `xml:"Object"`
+}
+
+// Signatures verifies the OPC digital signatures of the package.
+// The digests are checked against the stored parts, so they must be verified
+// before decrypting them.
+//
+// The signing certificates must chain up to one of roots. The certificates
+// are embedded in the package, so anyone can produce a signature that is
+// intact but not trusted: if roots is nil the signer cannot be verified and
+// the signatures with intact content have ErrSignatureUntrusted as Err.
+// Only a nil Err proves that the package was signed by a trusted signer
+// and has not been modified since.
+//
+// A signature is not valid if the package contains a part or a relationship
+// it does not cover, other than the signature origin and signature parts.
+// The parts are read within the Decoder Limits.
+//
+// The signatures are canonicalized as supported by this package,
+// that is Canonical XML 1.0 without comments for documents without a DTD.
+//
+// An error is only returned if the package cannot be read,
+// the validity of each signature is reported in its Err field.
+func (d *Decoder) Signatures(roots *x509.CertPool) ([]Signature, error) {
+	if err := d.initLimiter(); err != nil {
+		return nil, err
+	}
+	if err := d.p.Open(d.flate); err != nil {
+		return nil, err
+	}
+	var sigs []Signature
+	for _, r := range d.p.Relationships() {
+		if r.Type != RelTypeDigitalSignatureOrigin {
+			continue
+		}
+		origin, ok := d.p.FindFileFromName(r.Path)
+		if !ok {
+			continue
+		}
+		for _, sr := range origin.Relationships() {
+			if sr.Type != RelTypeDigitalSignature {
+				continue
+			}
+			f, ok := origin.FindFileFromName(sr.Path)
+			if !ok {
+				sigs = append(sigs, Signature{Path: sr.Path, Err: fmt.Errorf("package does not contain %s", sr.Path)})
+				continue
+			}
+			sigs = append(sigs, d.verifySignature(f, roots))
+		}
+	}
+	return sigs, nil
+}
+
+func (d *Decoder) verifySignature(f packageFile, roots *x509.CertPool) Signature {
+	s := Signature{Path: f.Name()}
+	data, err := d.readPackageFile(f)
+	if err != nil {
+		s.Err = err
+		return s
+	}
+	if err = checkSignatureStructure(data); err != nil {
+		s.Err = err
+		return s
+	}
+	var sx signatureXML
+	if err = xml.Unmarshal(data, &sx); err != nil {
+		s.Err = err
+		return s
+	}
+	certs := make([]*x509.Certificate, 0, len(sx.Certificates))
+	for _, c := range sx.Certificates {
+		cert, err := parseBase64Certificate(c)
+		if err != nil {
+			s.Err = err
+			return s
+		}
+		certs = append(certs, cert)
+	}
+	certs = append(certs, d.certificateParts(f)...)
+	if len(certs) == 0 {
+		s.Err = ErrSignatureCertificate
+		return s
+	}
+	s.Certificate = certs[0]
+	if err = verifySignedInfo(data, &sx, s.Certificate); err != nil {
+		s.Err = err
+		return s
+	}
+	signed, err := verifyObjects(data, &sx)
+	if err != nil {
+		s.Err = err
+		return s
+	}
+	var refs []referenceXML
+	for _, o := range signed {
+		if o.SignatureTime != "" && s.Time.IsZero() {
+			s.Time, _ = time.Parse(signatureTimeFormat, strings.TrimSpace(o.SignatureTime))
+		}
+		for _, ref := range o.References {
+			p, err := d.verifyPart(ref)
+			if err != nil && s.Err == nil {
+				s.Err = err
+			}
+			s.Parts = append(s.Parts, p)
+		}
+		refs = append(refs, o.References...)
+	}
+	if s.Err == nil {
+		s.Err = d.checkCoverage(refs)
+	}
+	if s.Err == nil && roots == nil {
+		s.Err = ErrSignatureUntrusted
+	} else if s.Err == nil {
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}
+		for _, c := range certs[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+		_, s.Err = s.Certificate.Verify(opts)
+	}
+	return s
+}
+
+// certificateParts returns the certificates stored in parts related to the signature part.
+func (d *Decoder) certificateParts(f packageFile) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for _, r := range f.Relationships() {
+		if r.Type != RelTypeDigitalSignatureCertificate {
+			continue
+		}
+		if cf, ok := f.FindFileFromName(r.Path); ok {
+			if data, err := d.readPackageFile(cf); err == nil {
+				if cert, err := x509.ParseCertificate(data); err == nil {
+					certs = append(certs, cert)
+				}
+			}
+		}
+	}
+	return certs
+}
+
+// checkSignatureStructure rejects the signatures whose verified content
+// could differ from the interpreted one: the part must hold a single XML-DSig Signature element
+// whose children are in the XML-DSig namespace, the SignedInfo element must be
+// one of those children and appear only once, and the Id attributes must be unique.
+func checkSignatureStructure(data []byte) error {
+	var (
+		d                  = xml.NewDecoder(bytes.NewReader(data))
+		depth, roots, info int
+		ids                = make(map[string]bool)
+	)
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				roots++
+				if roots > 1 || t.Name != (xml.Name{Space: nsXMLDSig, Local: "Signature"}) {
+					return ErrSignatureStructure
+				}
+			}
+			if depth == 2 && t.Name.Space != nsXMLDSig {
+				return ErrSignatureStructure
+			}
+			if t.Name.Local == "SignedInfo" {
+				info++
+				if depth != 2 || info > 1 {
+					return ErrSignatureStructure
+				}
+			}
+			for _, a := range t.Attr {
+				if a.Name.Space == "" && a.Name.Local == "Id" {
+					if ids[a.Value] {
+						return ErrSignatureStructure
+					}
+					ids[a.Value] = true
+				}
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if info != 1 {
+		return ErrSignatureStructure
+	}
+	return nil
+}
+
+// verifySignedInfo checks the signature value of the SignedInfo element,
+// which checkSignatureStructure ensures is unique.
+func verifySignedInfo(data []byte, sx *signatureXML, cert *x509.Certificate) error {
+	if sx.SignedInfo.CanonicalizationMethod.Algorithm != algC14N {
+		return ErrSignatureAlgorithm
+	}
+	info, err := xml3mf.Canonicalize(bytes.NewReader(data), func(s xml.StartElement) bool {
+		return s.Name.Local == "SignedInfo"
+	})
+	if err != nil {
+		return err
+	}
+	value, err := decodeBase64(sx.SignatureValue)
+	if err != nil {
+		return err
+	}
+	h := crypto.SHA256
+	method := sx.SignedInfo.SignatureMethod.Algorithm
+	switch method {
+	case algRSASHA1:
+		h = crypto.SHA1
+	case algRSASHA256, algECDSASHA256:
+	default:
+		return ErrSignatureAlgorithm
+	}
+	hh := h.New()
+	hh.Write(info)
+	digest := hh.Sum(nil)
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if method == algECDSASHA256 {
+			return ErrSignatureAlgorithm
+		}
+		if rsa.VerifyPKCS1v15(pub, h, digest, value) != nil {
+			return ErrSignatureValue
+		}
+	case *ecdsa.PublicKey:
+		if method != algECDSASHA256 {
+			return ErrSignatureAlgorithm
+		}
+		size := len(value) / 2
+		r, s := new(big.Int).SetBytes(value[:size]), new(big.Int).SetBytes(value[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return ErrSignatureValue
+		}
+	default:
+		return ErrSignatureCertificate
+	}
+	return nil
+}
+
+// verifyObjects checks the digests of the signed info references
+// and returns the signed objects.
+// Each reference must point to an Object child of the Signature element,
+// which is the element digested as the Id attributes are unique.
+func verifyObjects(data []byte, sx *signatureXML) ([]objectXML, error) {
+	var signed []objectXML
+	for _, ref := range sx.SignedInfo.References {
+		if !strings.HasPrefix(ref.URI, "#") {
+			return nil, ErrSignatureAlgorithm
+		}
+		for _, t := range ref.Transforms {
+			if t.Algorithm != algC14N {
+				return nil, ErrSignatureAlgorithm
+			}
+		}
+		id := ref.URI[1:]
+		c, err := xml3mf.Canonicalize(bytes.NewReader(data), func(s xml.StartElement) bool {
+			for _, a := range s.Attr {
+				if a.Name.Space == "" && a.Name.Local == "Id" {
+					return a.Value == id
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref.URI, err)
+		}
+		if err = checkDigest(ref, bytes.NewReader(c)); err != nil {
+			return nil, fmt.Errorf("%s: %w", ref.URI, err)
+		}
+		var found bool
+		for _, o := range sx.Objects {
+			if o.ID == id {
+				signed = append(signed, o)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s: %w", ref.URI, ErrSignatureStructure)
+		}
+	}
+	return signed, nil
+}
+
+// verifyPart checks the digest of a part referenced by a manifest.
+func (d *Decoder) verifyPart(ref referenceXML) (SignedPart, error) {
+	var p SignedPart
+	p.Name, p.ContentType = splitPartURI(ref.URI)
+	var err error
+	if isRelationshipsReference(ref) {
+		var content []byte
+		if content, err = d.transformRelationships(p.Name, ref.Transforms); err == nil {
+			err = checkDigest(ref, bytes.NewReader(content))
+		}
+	} else {
+		err = d.digestPart(ref, p.Name, p.ContentType)
+	}
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", p.Name, err)
+	}
+	p.Valid = true
+	return p, nil
+}
+
+// digestPart checks the digest of the part name, streaming its content.
+func (d *Decoder) digestPart(ref referenceXML, name, contentType string) error {
+	f, ok := d.p.FindFileFromName(name)
+	if !ok {
+		return errors.New("part not found")
+	}
+	if !strings.EqualFold(f.ContentType(), contentType) {
+		return ErrSignatureDigest
+	}
+	rc, err := d.openPackageFile(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return checkDigest(ref, rc)
+}
+
+// checkCoverage returns an ErrSignatureCoverage if a part or a relationship of the package
+// is not covered by refs. The signature origin, signature and certificate parts
+// and the package relationships to the signature origin are not checked,
+// as they change each time the package is signed.
+func (d *Decoder) checkCoverage(refs []referenceXML) error {
+	o, ok := d.p.(*opcReader)
+	if !ok {
+		return ErrSignatureAlgorithm
+	}
+	parts := make(map[string]bool, len(refs))
+	rels := make(map[string][]transformXML)
+	for _, ref := range refs {
+		name, _ := splitPartURI(ref.URI)
+		name = strings.ToUpper(name)
+		if isRelationshipsReference(ref) {
+			rels[name] = append(rels[name], ref.Transforms[0])
+		} else {
+			parts[name] = true
+		}
+	}
+	checkRels := func(source string, rs []*opc.Relationship, skip string) error {
+		name := relationshipsPartName(source)
+		for _, r := range rs {
+			if r.Type != skip && !relationshipSelected(rels[strings.ToUpper(name)], r) {
+				return fmt.Errorf("%s: relationship %s: %w", name, r.ID, ErrSignatureCoverage)
+			}
+		}
+		return nil
+	}
+	if err := checkRels("/", o.r.Relationships, RelTypeDigitalSignatureOrigin); err != nil {
+		return err
+	}
+	for _, f := range o.r.Files {
+		switch f.ContentType {
+		case ContentTypeSignatureOrigin, ContentTypeXMLSignature, ContentTypeSignatureCertificate:
+			continue
+		}
+		if !parts[strings.ToUpper(f.Name)] {
+			return fmt.Errorf("%s: %w", f.Name, ErrSignatureCoverage)
+		}
+		if err := checkRels(f.Name, f.Relationships, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitPartURI returns the part name and the content type of a manifest reference URI.
+func splitPartURI(uri string) (name, contentType string) {
+	if i := strings.IndexByte(uri, '?'); i != -1 {
+		return uri[:i], strings.TrimPrefix(uri[i+1:], "ContentType=")
+	}
+	return uri, ""
+}
+
+func isRelationshipsReference(ref referenceXML) bool {
+	return len(ref.Transforms) > 0 && ref.Transforms[0].Algorithm == algRelationships
+}
+
+// relationshipSelected reports whether any of the relationship transforms selects r.
+func relationshipSelected(transforms []transformXML, r *opc.Relationship) bool {
+	for _, t := range transforms {
+		for _, s := range t.SourceIDs {
+			if s.ID == r.ID {
+				return true
+			}
+		}
+		for _, s := range t.SourceTypes {
+			if s.Type == r.Type {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// transformRelationships applies the OPC relationship transform,
+// followed by an optional canonicalization, to the relationships part name.
+func (d *Decoder) transformRelationships(name string, transforms []transformXML) ([]byte, error) {
+	for _, t := range transforms[1:] {
+		if t.Algorithm != algC14N {
+			return nil, ErrSignatureAlgorithm
+		}
+	}
+	o, ok := d.p.(*opcReader)
+	if !ok {
+		return nil, ErrSignatureAlgorithm
+	}
+	rels, ok := o.relationshipsOf(name)
+	if !ok {
+		return nil, errors.New("part not found")
+	}
+	filtered := make([]*opc.Relationship, 0, len(rels))
+	for _, r := range rels {
+		if relationshipSelected(transforms[:1], r) {
+			filtered = append(filtered, r)
+		}
+	}
+	return canonicalRelationships(filtered)
+}
+
+// canonicalRelationships returns the canonical form of the relationships part
+// holding rels sorted by ID, as output by the OPC relationship transform.
+// rels is sorted in place.
+func canonicalRelationships(rels []*opc.Relationship) ([]byte, error) {
+	sort.Slice(rels, func(i, j int) bool { return rels[i].ID < rels[j].ID })
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<Relationships xmlns="%s">`, nsRelationships)
+	for _, r := range rels {
+		mode := "Internal"
+		if r.TargetMode == opc.ModeExternal {
+			mode = "External"
+		}
+		buf.WriteString(`<Relationship Id="`)
+		xml.EscapeText(&buf, []byte(r.ID))
+		buf.WriteString(`" Target="`)
+		xml.EscapeText(&buf, []byte(r.TargetURI))
+		fmt.Fprintf(&buf, `" TargetMode="%s" Type="`, mode)
+		xml.EscapeText(&buf, []byte(r.Type))
+		buf.WriteString(`"/>`)
+	}
+	buf.WriteString(`</Relationships>`)
+	return xml3mf.Canonicalize(&buf, func(s xml.StartElement) bool { return s.Name.Local == "Relationships" })
+}
+
+func checkDigest(ref referenceXML, content io.Reader) error {
+	var h crypto.Hash
+	switch ref.DigestMethod.Algorithm {
+	case algSHA1:
+		h = crypto.SHA1
+	case algSHA256:
+		h = crypto.SHA256
+	case algSHA512:
+		h = crypto.SHA512
+	default:
+		return ErrSignatureAlgorithm
+	}
+	want, err := decodeBase64(ref.DigestValue)
+	if err != nil {
+		return err
+	}
+	hh := h.New()
+	if _, err = io.Copy(hh, content); err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(hh.Sum(nil), want) != 1 {
+		return ErrSignatureDigest
+	}
+	return nil
+}
+
+// openPackageFile opens f within the Decoder Limits.
+func (d *Decoder) openPackageFile(f packageFile) (io.ReadCloser, error) {
+	if d.limiter != nil {
+		f = &limitedFile{packageFile: f, l: d.limiter}
+	}
+	return f.Open()
+}
+
+func (d *Decoder) readPackageFile(f packageFile) ([]byte, error) {
+	rc, err := d.openPackageFile(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+func parseBase64Certificate(s string) (*x509.Certificate, error) {
+	der, err := decodeBase64(s)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
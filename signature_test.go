@@ -0,0 +1,318 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func newTestSigner(t *testing.T, key crypto.Signer) *Signer {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "go3mf"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Signer{Key: key, Certificate: cert, Time: time.Date(2021, 5, 4, 10, 20, 30, 0, time.UTC)}
+}
+
+func newSignedModel() *Model {
+	return &Model{
+		Path: DefaultModelPath,
+		Attachments: []Attachment{
+			{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Data: []byte("pt")},
+		},
+		Relationships: []Relationship{{Path: "/3D/Metadata/pt.xml", Type: RelTypePrintTicket, ID: "1"}},
+		Resources:     Resources{Objects: []*Object{{ID: 1, Mesh: new(Mesh)}}},
+		Childs: map[string]*ChildModel{
+			"/3D/other.model": {Resources: Resources{Objects: []*Object{{ID: 2, Mesh: new(Mesh)}}}},
+		},
+	}
+}
+
+func encodeSigned(t *testing.T, s *Signer) []byte {
+	t.Helper()
+	var buff bytes.Buffer
+	e := NewEncoder(&buff)
+	e.Signer = s
+	if err := e.Encode(newSignedModel()); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	return buff.Bytes()
+}
+
+// replacePart rewrites the zip package with a new content for the part name.
+func replacePart(t *testing.T, pkg []byte, name string, content []byte) []byte {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(pkg), int64(len(pkg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	for _, f := range r.File {
+		fw, err := w.Create(f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Name == name {
+			fw.Write(content)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(fw, rc)
+		rc.Close()
+	}
+	w.Close()
+	return buff.Bytes()
+}
+
+// addPart rewrites the zip package adding the part name.
+func addPart(t *testing.T, pkg []byte, name string, content []byte) []byte {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(pkg), int64(len(pkg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buff bytes.Buffer
+	w := zip.NewWriter(&buff)
+	for _, f := range r.File {
+		fw, err := w.Create(f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(fw, rc)
+		rc.Close()
+	}
+	fw, err := w.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	w.Close()
+	return buff.Bytes()
+}
+
+// editSignature rewrites the signature part of pkg replacing old with new.
+func editSignature(t *testing.T, pkg []byte, old, new string) []byte {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(pkg), int64(len(pkg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range r.File {
+		if "/"+path.Dir(f.Name)+"/" != signatureDir {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		return replacePart(t, pkg, f.Name, bytes.Replace(content, []byte(old), []byte(new), 1))
+	}
+	t.Fatal("signature part not found")
+	return nil
+}
+
+func TestDecoder_Signatures(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSigner, ecSigner := newTestSigner(t, rsaKey), newTestSigner(t, ecKey)
+	rsaRoots, ecRoots := x509.NewCertPool(), x509.NewCertPool()
+	rsaRoots.AddCert(rsaSigner.Certificate)
+	ecRoots.AddCert(ecSigner.Certificate)
+	wantParts := []SignedPart{
+		{Name: "/3D/Metadata/pt.xml", ContentType: "application/vnd.ms-printing.printticket+xml", Valid: true},
+		{Name: "/3D/3dmodel.model", ContentType: ContentType3DModel, Valid: true},
+		{Name: "/3D/other.model", ContentType: ContentType3DModel, Valid: true},
+		{Name: "/3D/_rels/3dmodel.model.rels", ContentType: contentTypeRelationships, Valid: true},
+		{Name: "/_rels/.rels", ContentType: contentTypeRelationships, Valid: true},
+	}
+	tamperedParts := append([]SignedPart(nil), wantParts...)
+	tamperedParts[0].Valid = false
+	retargetedParts := append([]SignedPart(nil), wantParts...)
+	retargetedParts[3].Valid = false
+	tests := []struct {
+		name      string
+		pkg       []byte
+		roots     *x509.CertPool
+		signer    *Signer
+		wantParts []SignedPart
+		wantErr   error
+	}{
+		{"rsa", encodeSigned(t, rsaSigner), rsaRoots, rsaSigner, wantParts, nil},
+		{"ecdsa", encodeSigned(t, ecSigner), ecRoots, ecSigner, wantParts, nil},
+		{"noRoots", encodeSigned(t, ecSigner), nil, ecSigner, wantParts, ErrSignatureUntrusted},
+		{"untrusted", encodeSigned(t, rsaSigner), ecRoots, rsaSigner, wantParts, errors.New("")},
+		{"tampered", replacePart(t, encodeSigned(t, ecSigner), "3D/Metadata/pt.xml", []byte("other")), nil, ecSigner, tamperedParts, ErrSignatureDigest},
+		{"retargeted", replacePart(t, encodeSigned(t, rsaSigner), "3D/_rels/3dmodel.model.rels", []byte(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="1" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/printticket" Target="/3D/other.model"/>
+			<Relationship Id="rId0" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel" Target="/3D/other.model"/>
+		</Relationships>`)), nil, rsaSigner, retargetedParts, ErrSignatureDigest},
+		{"addedPart", addPart(t, encodeSigned(t, rsaSigner), "3D/added.model", []byte("other")), nil, rsaSigner, wantParts, ErrSignatureCoverage},
+		{"duplicatedSignedInfo", editSignature(t, encodeSigned(t, rsaSigner), "<KeyInfo>", "<Object><SignedInfo></SignedInfo></Object><KeyInfo>"), nil, rsaSigner, nil, ErrSignatureStructure},
+		{"duplicatedId", editSignature(t, encodeSigned(t, rsaSigner), "<KeyInfo>", `<Object Id="idPackageObject"></Object><KeyInfo>`), nil, rsaSigner, nil, ErrSignatureStructure},
+		{"foreignRoot", editSignature(t, encodeSigned(t, rsaSigner), `<Signature xmlns="http://www.w3.org/2000/09/xmldsig#"`, `<Signature xmlns="http://www.w3.org/2000/09/xmldsig"`), nil, rsaSigner, nil, ErrSignatureStructure},
+		{"unsigned", replacePart(t, encodeSigned(t, rsaSigner), "_rels/.rels", []byte(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="r" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel" Target="/3D/3dmodel.model"/>
+		</Relationships>`)), nil, nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(bytes.NewReader(tt.pkg), int64(len(tt.pkg)))
+			got, err := d.Signatures(tt.roots)
+			if err != nil {
+				t.Fatalf("Decoder.Signatures() error = %v", err)
+			}
+			if tt.signer == nil {
+				if len(got) != 0 {
+					t.Errorf("Decoder.Signatures() = %v, want none", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("Decoder.Signatures() = %v, want 1 signature", got)
+			}
+			s := got[0]
+			if tt.wantErr == nil && s.Err != nil {
+				t.Errorf("Decoder.Signatures() Err = %v", s.Err)
+			} else if tt.wantErr != nil && (s.Err == nil || (tt.wantErr.Error() != "" && !errors.Is(s.Err, tt.wantErr))) {
+				t.Errorf("Decoder.Signatures() Err = %v, want %v", s.Err, tt.wantErr)
+			}
+			if tt.wantParts == nil {
+				return
+			}
+			if !s.Certificate.Equal(tt.signer.Certificate) {
+				t.Error("Decoder.Signatures() certificate mismatch")
+			}
+			if !s.Time.Equal(tt.signer.Time) {
+				t.Errorf("Decoder.Signatures() Time = %v, want %v", s.Time, tt.signer.Time)
+			}
+			if diff := deep.Equal(s.Parts, tt.wantParts); diff != nil {
+				t.Errorf("Decoder.Signatures() Parts = %v", diff)
+			}
+		})
+	}
+}
+
+func TestDecoder_Signatures_Limits(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := encodeSigned(t, newTestSigner(t, key))
+	d := NewDecoder(bytes.NewReader(pkg), int64(len(pkg)))
+	d.Limits.MaxPartSize = 10
+	_, err = d.Signatures(nil)
+	var lerr *LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != "MaxPartSize" {
+		t.Errorf("Decoder.Signatures() error = %v, want MaxPartSize LimitError", err)
+	}
+}
+
+func TestDecoder_Decode_Signed(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := encodeSigned(t, newTestSigner(t, key))
+	got := new(Model)
+	d := NewDecoder(bytes.NewReader(pkg), int64(len(pkg)))
+	d.BufferAttachments = true
+	if err := d.Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if diff := deep.Equal(got, newSignedModel()); diff != nil {
+		t.Errorf("Decoder.Decode() = %v", diff)
+	}
+}
+
+func TestDecoder_transformRelationships(t *testing.T) {
+	var buff bytes.Buffer
+	e := NewEncoder(&buff)
+	m := newSignedModel()
+	m.RootRelationships = []Relationship{{Path: "/3D/Metadata/pt.xml", Type: RelTypeThumbnail, ID: "a&b"}}
+	if err := e.Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	d := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	if err := d.p.Open(nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		part       string
+		transforms []transformXML
+		want       string
+		wantErr    bool
+	}{
+		{"notRels", "/3D/3dmodel.model", []transformXML{{Algorithm: algRelationships}}, "", true},
+		{"notFound", "/3D/_rels/none.model.rels", []transformXML{{Algorithm: algRelationships}}, "", true},
+		{"transform", "/_rels/.rels", []transformXML{{Algorithm: algRelationships}, {Algorithm: "other"}}, "", true},
+		{"empty", "/_rels/.rels", []transformXML{{Algorithm: algRelationships}}, `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"></Relationships>`, false},
+		{"package", "/_rels/.rels", []transformXML{{Algorithm: algRelationships, SourceTypes: []struct {
+			Type string `xml:"SourceType,attr"`
+		}{{RelType3DModel}}, SourceIDs: []struct {
+			ID string `xml:"SourceId,attr"`
+		}{{"a&b"}}}, {Algorithm: algC14N}}, `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="a&amp;b" Target="/3D/Metadata/pt.xml" TargetMode="Internal" Type="` + RelTypeThumbnail + `"></Relationship>` +
+			`<Relationship Id="rId0" Target="/3D/3dmodel.model" TargetMode="Internal" Type="` + RelType3DModel + `"></Relationship>` +
+			`</Relationships>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.transformRelationships(tt.part, tt.transforms)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decoder.transformRelationships() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Decoder.transformRelationships() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
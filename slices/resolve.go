@@ -0,0 +1,36 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package slices
+
+func (Spec) Resolve(parent interface{}, name string, index int) (interface{}, bool) {
+	switch p := parent.(type) {
+	case *SliceStack:
+		switch name {
+		case attrSlice:
+			if index >= 0 && index < len(p.Slices) {
+				return &p.Slices[index], true
+			}
+		case attrSliceRef:
+			if index >= 0 && index < len(p.Refs) {
+				return &p.Refs[index], true
+			}
+		}
+	case *Slice:
+		switch name {
+		case attrPolygon:
+			if index >= 0 && index < len(p.Polygons) {
+				return &p.Polygons[index], true
+			}
+		case attrVertex:
+			if index >= 0 && index < len(p.Vertices.Vertex) {
+				return &p.Vertices.Vertex[index], true
+			}
+		}
+	case *Polygon:
+		if name == attrSegment && index >= 0 && index < len(p.Segments) {
+			return &p.Segments[index], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,40 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package slices
+
+import (
+	"testing"
+
+	"github.com/hpinc/go3mf"
+)
+
+func TestSpec_Resolve(t *testing.T) {
+	stack := &SliceStack{ID: 1, Slices: []Slice{{
+		Vertices: Vertices{Vertex: make([]go3mf.Point2D, 2)},
+		Polygons: []Polygon{{Segments: make([]Segment, 2)}},
+	}}}
+	refs := &SliceStack{ID: 2, Refs: make([]SliceRef, 2)}
+	m := &go3mf.Model{Resources: go3mf.Resources{Assets: []go3mf.Asset{stack, refs}}}
+	tests := []struct {
+		xpath  string
+		want   interface{}
+		wantOk bool
+	}{
+		{"/model/resources/slicestack[0]/slice[0]", &stack.Slices[0], true},
+		{"/model/resources/slicestack[0]/slice[0]/vertex[1]", &stack.Slices[0].Vertices.Vertex[1], true},
+		{"/model/resources/slicestack[0]/slice[0]/polygon[0]", &stack.Slices[0].Polygons[0], true},
+		{"/model/resources/slicestack[0]/slice[0]/polygon[0]/segment[1]", &stack.Slices[0].Polygons[0].Segments[1], true},
+		{"/model/resources/slicestack[1]/sliceref[1]", &refs.Refs[1], true},
+		{"/model/resources/slicestack[0]/slice[1]", nil, false},
+		{"/model/resources/slicestack[0]/slice[0]/segment[0]", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.xpath, func(t *testing.T) {
+			got, ok := m.Resolve("", tt.xpath)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("Spec.Resolve() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -5,9 +5,9 @@ package slices
 
 import (
 	"encoding/xml"
-	"errors"
 
 	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/errors"
 	"github.com/hpinc/go3mf/spec"
 )
 
@@ -27,18 +27,18 @@ func init() {
 type Spec struct{}
 
 var (
-	ErrSliceExtRequired          = errors.New("a 3MF package which uses low resolution objects MUST enlist the slice extension as required")
-	ErrNonSliceStack             = errors.New("slicestackid MUST reference a slice stack resource")
-	ErrSlicesAndRefs             = errors.New("may either contain slices or refs, but they MUST NOT contain both element types")
-	ErrSliceRefSamePart          = errors.New("the path of the referenced slice stack MUST be different than the path of the original slice stack")
-	ErrSliceRefRef               = errors.New("a referenced slice stack MUST NOT contain any further sliceref elements")
-	ErrSliceSmallTopZ            = errors.New("slice ztop is smaller than stack zbottom")
-	ErrSliceNoMonotonic          = errors.New("the first ztop in the next slicestack MUST be greater than the last ztop in the previous slicestack")
-	ErrSliceInsufficientVertices = errors.New("slice MUST contain at least 2 vertices")
-	ErrSliceInsufficientPolygons = errors.New("slice MUST contain at least 1 polygon")
-	ErrSliceInsufficientSegments = errors.New("slice polygon MUST contain at least 1 segment")
-	ErrSlicePolygonNotClosed     = errors.New("objects with type 'model' and 'solidsupport' MUST not reference slices with open polygons")
-	ErrSliceInvalidTranform      = errors.New("any transform applied to an object that references a slice stack MUST be planar")
+	ErrSliceExtRequired          = errors.NewRule("slices-1-required-extension", errors.SeverityError, "a 3MF package which uses low resolution objects MUST enlist the slice extension as required")
+	ErrNonSliceStack             = errors.NewRule("slices-3-object-slicestack", errors.SeverityError, "slicestackid MUST reference a slice stack resource")
+	ErrSlicesAndRefs             = errors.NewRule("slices-2-slicestack-content", errors.SeverityError, "may either contain slices or refs, but they MUST NOT contain both element types")
+	ErrSliceRefSamePart          = errors.NewRule("slices-2.2-sliceref-path", errors.SeverityError, "the path of the referenced slice stack MUST be different than the path of the original slice stack")
+	ErrSliceRefRef               = errors.NewRule("slices-2.2-sliceref-nested", errors.SeverityError, "a referenced slice stack MUST NOT contain any further sliceref elements")
+	ErrSliceSmallTopZ            = errors.NewRule("slices-2.1-ztop", errors.SeverityError, "slice ztop is smaller than stack zbottom")
+	ErrSliceNoMonotonic          = errors.NewRule("slices-2.2-sliceref-monotonic", errors.SeverityError, "the first ztop in the next slicestack MUST be greater than the last ztop in the previous slicestack")
+	ErrSliceInsufficientVertices = errors.NewRule("slices-2.1.1-vertices", errors.SeverityError, "slice MUST contain at least 2 vertices")
+	ErrSliceInsufficientPolygons = errors.NewRule("slices-2.1.2-polygons", errors.SeverityError, "slice MUST contain at least 1 polygon")
+	ErrSliceInsufficientSegments = errors.NewRule("slices-2.1.2-segments", errors.SeverityError, "slice polygon MUST contain at least 1 segment")
+	ErrSlicePolygonNotClosed     = errors.NewRule("slices-3-closed-polygons", errors.SeverityError, "objects with type 'model' and 'solidsupport' MUST not reference slices with open polygons")
+	ErrSliceInvalidTranform      = errors.NewRule("slices-4-planar-transform", errors.SeverityError, "any transform applied to an object that references a slice stack MUST be planar")
 )
 
 // A Segment element represents a single line segment (or edge) of a polygon.
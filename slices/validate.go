@@ -197,7 +197,7 @@ func validateBuildTransforms(m *go3mf.Model, path string, id uint32) bool {
 			}
 		}
 		if o, ok := m.FindObject(targetPath, item.ObjectID); ok {
-			if !validateObjectTransforms(m, o, path, id) {
+			if !validateObjectTransforms(m, o, path, id, nil) {
 				return false
 			}
 		}
@@ -205,23 +205,25 @@ func validateBuildTransforms(m *go3mf.Model, path string, id uint32) bool {
 	return true
 }
 
-func validateObjectTransforms(m *go3mf.Model, o *go3mf.Object, path string, id uint32) bool {
+func validateObjectTransforms(m *go3mf.Model, o *go3mf.Object, path string, id uint32, visiting []*go3mf.Object) bool {
 	if o.Components == nil {
 		return true
 	}
+	for _, v := range visiting {
+		if v == o {
+			return true // recursive reference
+		}
+	}
+	visiting = append(visiting, o)
 	for _, c := range o.Components.Component {
 		if c.ObjectID == id && c.ObjectPath(path) == path {
 			if c.HasTransform() && !validTransform(c.Transform) {
 				return false
 			}
 		}
-		if c.ObjectID == o.ID && c.ObjectPath(path) == path { // avoid circular references
-			break
-		} else {
-			if o1, ok := m.FindObject(c.ObjectPath(path), c.ObjectID); ok {
-				if !validateObjectTransforms(m, o1, path, id) {
-					return false
-				}
+		if o1, ok := m.FindObject(c.ObjectPath(path), c.ObjectID); ok {
+			if !validateObjectTransforms(m, o1, path, id, visiting) {
+				return false
 			}
 		}
 	}
@@ -159,12 +159,14 @@ func TestValidate(t *testing.T) {
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[1]: %v", ErrSliceInvalidTranform),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[1]: %v", ErrSliceExtRequired),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[2]: %v", &errors.MissingFieldError{Name: attrSliceRefID}),
+			fmt.Sprintf("go3mf: XPath: /model/resources/object[3]/components/component[0]: %v", errors.ErrRecursion),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[3]: %v", ErrNonSliceStack),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[4]: %v", ErrSliceInvalidTranform),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[4]: %v", ErrSlicePolygonNotClosed),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[5]: %v", ErrSliceInvalidTranform),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[5]: %v", ErrSlicePolygonNotClosed),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[6]/components/component[1]: %v", errors.ErrRecursion),
+			fmt.Sprintf("go3mf: XPath: /model/resources/object[6]/components/component[2]: %v", errors.ErrRecursion),
 		}},
 	}
 	for _, tt := range tests {
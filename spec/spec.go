@@ -40,9 +40,20 @@ func LoadValidator(ns string) (ValidateSpec, bool) {
 	return nil, false
 }
 
+func LoadResolver(ns string) (ResolveSpec, bool) {
+	specMu.RLock()
+	ext, ok := specs[ns]
+	specMu.RUnlock()
+	if ok {
+		ext, ok := ext.(ResolveSpec)
+		return ext, ok
+	}
+	return nil, false
+}
+
 // Spec is the interface that must be implemented by a 3mf spec.
 //
-// Specs may implement ValidateSpec.
+// Specs may implement ValidateSpec and ResolveSpec.
 type Spec interface {
 	NewAttrGroup(parent xml.Name) AttrGroup
 	NewElementDecoder(name xml.Name) GetterElementDecoder
@@ -57,6 +68,16 @@ type ValidateSpec interface {
 	Validate(model interface{}, path string, element interface{}) error
 }
 
+// If a Spec implemented ResolveSpec, then model.Resolve will call
+// Resolve to find the elements of the spec referenced by an XPath.
+//
+// parent is the element resolved from the previous XPath levels
+// and name and index define the next level, index being -1 if not indexed.
+type ResolveSpec interface {
+	Spec
+	Resolve(parent interface{}, name string, index int) (interface{}, bool)
+}
+
 // An XMLAttr represents an attribute in an XML element (Name=Value).
 type XMLAttr struct {
 	Name  xml.Name
@@ -0,0 +1,218 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"encoding/xml"
+	"errors"
+
+	specerr "github.com/hpinc/go3mf/errors"
+	"github.com/hpinc/go3mf/spec"
+)
+
+var (
+	errModelWriterClosed   = errors.New("model writer is closed")
+	errVertexAfterTriangle = errors.New("vertices MUST be written before triangles")
+)
+
+// A ModelWriter writes the root model of a 3MF package resource by resource,
+// so models that do not fit in memory can be encoded.
+//
+// Resources are written in the same order as the calls,
+// therefore resources must be written before being referenced.
+// Any error leaves the package in an unusable state and is
+// returned by every following call.
+type ModelWriter struct {
+	e   *Encoder
+	m   *Model
+	w   packagePart
+	x   *xmlEncoder
+	tm  xml.StartElement
+	xt  xml.StartElement
+	err error
+}
+
+// NewModelWriter writes the attachments, the metadata and the
+// resources of m and returns a ModelWriter to write the rest of the resources.
+//
+// The build and the child models of m are not written until
+// calling Close, so they can be filled while writing the resources.
+func (e *Encoder) NewModelWriter(m *Model) (*ModelWriter, error) {
+	w, x, err := e.createRootModel(m)
+	if err != nil {
+		return nil, err
+	}
+	mw := &ModelWriter{e: e, m: m, w: w, x: x}
+	if mw.tm, err = e.modelToken(x, m, true); err != nil {
+		return nil, err
+	}
+	x.EncodeToken(mw.tm)
+	e.writeMetadata(x, m.Metadata)
+	mw.xt = xml.StartElement{Name: xml.Name{Local: attrResources}}
+	m.Resources.AnyAttr.Marshal3MF(x, &mw.xt)
+	x.EncodeToken(mw.xt)
+	for _, a := range m.Resources.Assets {
+		if err = mw.WriteAsset(a); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range m.Resources.Objects {
+		if err = mw.WriteObject(o); err != nil {
+			return nil, err
+		}
+	}
+	return mw, nil
+}
+
+// WriteAsset writes a.
+func (mw *ModelWriter) WriteAsset(a Asset) error {
+	if mw.err != nil {
+		return mw.err
+	}
+	if a, ok := a.(spec.Marshaler); ok {
+		if mw.err = a.Marshal3MF(mw.x, &mw.xt); mw.err != nil {
+			return mw.err
+		}
+	}
+	mw.err = mw.x.Flush()
+	return mw.err
+}
+
+// WriteObject writes o.
+func (mw *ModelWriter) WriteObject(o *Object) error {
+	if mw.err != nil {
+		return mw.err
+	}
+	mw.e.writeObject(mw.x, o)
+	mw.err = mw.x.Flush()
+	return mw.err
+}
+
+// WriteMesh writes o as a mesh object whose vertices and triangles
+// are written by fn, after the ones already defined in o.Mesh, if any.
+// o.Mesh is also used to write the mesh attributes and extension elements.
+func (mw *ModelWriter) WriteMesh(o *Object, fn func(*MeshWriter) error) error {
+	if mw.err != nil {
+		return mw.err
+	}
+	if o.Mesh == nil {
+		oc := *o
+		oc.Mesh = new(Mesh)
+		o = &oc
+	}
+	x := mw.x
+	xo := mw.e.objectToken(x, o)
+	x.EncodeToken(xo)
+	if len(o.Metadata.Metadata) != 0 {
+		mw.e.writeMetadataGroup(x, o.Metadata)
+	}
+	xm := xml.StartElement{Name: xml.Name{Local: attrMesh}}
+	o.Mesh.AnyAttr.Marshal3MF(x, &xm)
+	x.EncodeToken(xm)
+
+	w := &MeshWriter{x: x, o: o}
+	w.openVertices()
+	if mw.err = fn(w); mw.err != nil {
+		return mw.err
+	}
+	if w.triangles == nil {
+		w.openTriangles()
+	}
+	x.SetSkipAttrEscape(false)
+	x.SetAutoClose(false)
+	x.EncodeToken(xml.EndElement{Name: xml.Name{Local: attrTriangles}})
+
+	o.Mesh.Any.Marshal3MF(x, &xm)
+	x.EncodeToken(xm.End())
+	x.EncodeToken(xo.End())
+	mw.err = x.Flush()
+	return mw.err
+}
+
+// Close writes the build and the child models and closes the package.
+func (mw *ModelWriter) Close() error {
+	if mw.err != nil {
+		return mw.err
+	}
+	mw.err = errModelWriterClosed
+	mw.x.EncodeToken(mw.xt.End())
+	mw.e.writeBuild(mw.x, mw.m)
+	mw.m.Any.Marshal3MF(mw.x, &mw.tm)
+	mw.x.EncodeToken(mw.tm.End())
+	if err := mw.x.Flush(); err != nil {
+		mw.err = err
+		return err
+	}
+	if err := mw.e.closeRootModel(mw.w, mw.x, mw.m); err != nil {
+		mw.err = err
+		return err
+	}
+	return nil
+}
+
+// A MeshWriter writes the vertices and triangles of a mesh.
+// All the vertices must be written before the first triangle.
+type MeshWriter struct {
+	x         *xmlEncoder
+	o         *Object
+	vertices  *vertexEncoder
+	triangles *triangleEncoder
+	count     uint32
+	index     int
+}
+
+// WriteVertex writes v.
+func (w *MeshWriter) WriteVertex(v Point3D) error {
+	if w.triangles != nil {
+		return errVertexAfterTriangle
+	}
+	w.vertices.encode(v)
+	w.count++
+	return nil
+}
+
+// WriteTriangle writes t.
+// It fails if t references a vertex that has not been written.
+func (w *MeshWriter) WriteTriangle(t Triangle) error {
+	if w.triangles == nil {
+		w.openTriangles()
+	}
+	if t.V1 >= w.count || t.V2 >= w.count || t.V3 >= w.count {
+		return specerr.WrapIndex(specerr.ErrIndexOutOfBounds, attrTriangle, w.index)
+	}
+	w.triangles.encode(t)
+	w.index++
+	return nil
+}
+
+func (w *MeshWriter) openVertices() {
+	m := w.o.Mesh
+	xvs := xml.StartElement{Name: xml.Name{Local: attrVertices}}
+	m.Vertices.AnyAttr.Marshal3MF(w.x, &xvs)
+	w.x.EncodeToken(xvs)
+	w.vertices = newVertexEncoder(w.x)
+	w.x.SetAutoClose(true)
+	w.x.SetSkipAttrEscape(true)
+	for _, v := range m.Vertices.Vertex {
+		w.vertices.encode(v)
+	}
+	w.count = uint32(len(m.Vertices.Vertex))
+}
+
+func (w *MeshWriter) openTriangles() {
+	m := w.o.Mesh
+	w.x.SetSkipAttrEscape(false)
+	w.x.SetAutoClose(false)
+	w.x.EncodeToken(xml.EndElement{Name: xml.Name{Local: attrVertices}})
+	xvt := xml.StartElement{Name: xml.Name{Local: attrTriangles}}
+	m.Triangles.AnyAttr.Marshal3MF(w.x, &xvt)
+	w.x.EncodeToken(xvt)
+	w.triangles = newTriangleEncoder(w.x, w.o)
+	w.x.SetAutoClose(true)
+	w.x.SetSkipAttrEscape(true)
+	for _, t := range m.Triangles.Triangle {
+		w.triangles.encode(t)
+	}
+	w.index = len(m.Triangles.Triangle)
+}
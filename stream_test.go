@@ -0,0 +1,133 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image/color"
+	"testing"
+
+	"github.com/go-test/deep"
+	specerr "github.com/hpinc/go3mf/errors"
+)
+
+func TestModelWriter_Roundtrip(t *testing.T) {
+	want := &Model{
+		Path:  DefaultModelPath,
+		Units: UnitCentimeter,
+		Resources: Resources{
+			Assets: []Asset{&BaseMaterials{ID: 1, Materials: []Base{{Name: "a", Color: color.RGBA{R: 255, A: 255}}}}},
+			Objects: []*Object{
+				{ID: 2, Name: "pre", Mesh: &Mesh{
+					Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+					Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}},
+				}},
+				{ID: 3, PID: 1, Metadata: MetadataGroup{Metadata: []Metadata{{Name: xml.Name{Local: "Title"}, Value: "t"}}}, Mesh: &Mesh{
+					Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}},
+					Triangles: Triangles{Triangle: []Triangle{
+						{V1: 0, V2: 1, V3: 2, PID: 1},
+						{V1: 0, V2: 1, V3: 3, PID: 1},
+						{V1: 0, V2: 2, V3: 3, PID: 1},
+					}},
+				}},
+				{ID: 4, Mesh: &Mesh{}},
+				{ID: 5, Components: &Components{Component: []*Component{{ObjectID: 2}, {ObjectID: 3}}}},
+			},
+		},
+		Build: Build{Items: []*Item{{ObjectID: 5}}},
+	}
+
+	var buff bytes.Buffer
+	m := &Model{Units: UnitCentimeter, Resources: Resources{
+		Assets:  want.Resources.Assets,
+		Objects: want.Resources.Objects[:1],
+	}}
+	mw, err := NewEncoder(&buff).NewModelWriter(m)
+	if err != nil {
+		t.Fatalf("Encoder.NewModelWriter() error = %v", err)
+	}
+	// Object 3 has one vertex and one triangle defined in memory.
+	o := *want.Resources.Objects[1]
+	o.Mesh = &Mesh{
+		Vertices:  Vertices{Vertex: o.Mesh.Vertices.Vertex[:1]},
+		Triangles: Triangles{Triangle: o.Mesh.Triangles.Triangle[:1]},
+	}
+	err = mw.WriteMesh(&o, func(w *MeshWriter) error {
+		for _, v := range want.Resources.Objects[1].Mesh.Vertices.Vertex[1:] {
+			if err := w.WriteVertex(v); err != nil {
+				return err
+			}
+		}
+		for _, tr := range want.Resources.Objects[1].Mesh.Triangles.Triangle[1:] {
+			if err := w.WriteTriangle(tr); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModelWriter.WriteMesh() error = %v", err)
+	}
+	if err = mw.WriteMesh(&Object{ID: 4}, func(*MeshWriter) error { return nil }); err != nil {
+		t.Fatalf("ModelWriter.WriteMesh() error = %v", err)
+	}
+	if err = mw.WriteObject(want.Resources.Objects[3]); err != nil {
+		t.Fatalf("ModelWriter.WriteObject() error = %v", err)
+	}
+	m.Build.Items = want.Build.Items
+	if err = mw.Close(); err != nil {
+		t.Fatalf("ModelWriter.Close() error = %v", err)
+	}
+	if err = mw.Close(); err != errModelWriterClosed {
+		t.Errorf("ModelWriter.Close() error = %v, want %v", err, errModelWriterClosed)
+	}
+
+	got := new(Model)
+	if err = NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(got); err != nil {
+		t.Fatalf("ModelWriter malformed = %v", err)
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("ModelWriter = %v", diff)
+	}
+}
+
+func TestModelWriter_WriteMesh_Error(t *testing.T) {
+	errFake := errors.New("fake")
+	tests := []struct {
+		name string
+		fn   func(*MeshWriter) error
+		want error
+	}{
+		{"callback", func(*MeshWriter) error { return errFake }, errFake},
+		{"vertexAfterTriangle", func(w *MeshWriter) error {
+			w.WriteVertex(Point3D{})
+			w.WriteVertex(Point3D{})
+			w.WriteVertex(Point3D{})
+			w.WriteTriangle(Triangle{V1: 0, V2: 1, V3: 2})
+			return w.WriteVertex(Point3D{})
+		}, errVertexAfterTriangle},
+		{"outOfBounds", func(w *MeshWriter) error {
+			return w.WriteTriangle(Triangle{V1: 0, V2: 1, V3: 2})
+		}, specerr.ErrIndexOutOfBounds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, err := NewEncoder(new(bytes.Buffer)).NewModelWriter(new(Model))
+			if err != nil {
+				t.Fatalf("Encoder.NewModelWriter() error = %v", err)
+			}
+			if err = mw.WriteMesh(&Object{ID: 1}, tt.fn); !errors.Is(err, tt.want) {
+				t.Errorf("ModelWriter.WriteMesh() error = %v, want %v", err, tt.want)
+			}
+			if err2 := mw.WriteObject(&Object{ID: 2}); err2 != err {
+				t.Errorf("ModelWriter.WriteObject() error = %v, want %v", err2, err)
+			}
+			if err2 := mw.Close(); err2 != err {
+				t.Errorf("ModelWriter.Close() error = %v, want %v", err2, err)
+			}
+		})
+	}
+}
@@ -4,11 +4,13 @@
 package go3mf
 
 import (
+	"context"
 	"encoding/xml"
 	"image/color"
 	"sort"
+	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 
 	"github.com/hpinc/go3mf/errors"
 	"github.com/hpinc/go3mf/spec"
@@ -27,49 +29,286 @@ func (m *Model) sortedChilds() []string {
 }
 
 // Validate checks that the model is conformant with the 3MF specs.
+// Only the violations with errors.SeverityError are returned,
+// use ValidateAll to also get the warnings and the infos.
+//
+// If the model was decoded with Decoder.TrackPositions the errors
+// contain the line and column of the offending element, or of its closest ancestor.
+// The positions are the decoded ones, so they may not match a model modified afterwards.
 func (m *Model) Validate() error {
+	return m.ValidateWith(ValidateOptions{})
+}
+
+// ValidateContext is like Validate but it stops early if ctx is done, returning ctx.Err().
+func (m *Model) ValidateContext(ctx context.Context) error {
+	return m.ValidateWithContext(ctx, ValidateOptions{})
+}
+
+// ValidateAll is like Validate but it returns the violations of all the severities.
+// Use errors.HasErrors to know if the model is not conformant.
+func (m *Model) ValidateAll() error {
+	return m.ValidateWith(ValidateOptions{Severity: errors.SeverityInfo})
+}
+
+// ValidateOptions configures Model.ValidateWith.
+// The zero value validates like Model.Validate.
+type ValidateOptions struct {
+	// Severity is the lowest severity reported.
+	Severity errors.Severity
+	// Rules enables or disables individual rules by code.
+	// Enabled rules are reported regardless of Severity and of the core spec being disabled.
+	Rules map[string]bool
+	// Specs enables or disables whole specs by namespace.
+	// Enabled specs are validated even if the model does not declare them
+	// and disabled ones are not validated even if it does.
+	Specs map[string]bool
+	// Version is the version of the core spec to validate against, such as "1.2.3".
+	// The rules introduced by later versions are not reported.
+	// Empty means the latest version.
+	Version string
+	// Coherency also runs the mesh coherency checks of ValidateCoherency.
+	Coherency bool
+	// MaxErrors is the maximum number of errors returned, zero means no limit.
+	// Validation stops as soon as MaxErrors errors are found,
+	// without checking the rest of the objects and triangles.
+	MaxErrors int
+	// Parallelism is the maximum number of objects validated concurrently,
+	// zero means the parallelism set in the context with WithParallelism.
+	Parallelism int
+}
+
+// keep reports whether err is reported.
+func (opts *ValidateOptions) keep(err error) bool {
+	code := errors.Code(err)
+	if enabled, ok := opts.Rules[code]; ok {
+		return enabled
+	}
+	if errors.SeverityOf(err) > opts.Severity {
+		return false
+	}
+	if enabled, ok := opts.Specs[Namespace]; ok && !enabled && strings.HasPrefix(code, "core-") {
+		return false
+	}
+	if since := errors.Since(err); opts.Version != "" && since != "" && strings.HasPrefix(code, "core-") && compareVersions(since, opts.Version) > 0 {
+		return false
+	}
+	return true
+}
+
+// compareVersions compares two dotted versions numerically,
+// returning -1, 0 or 1 if a is lower, equal or greater than b.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// validators returns the extension validators to run on m.
+func (opts *ValidateOptions) validators(m *Model) []spec.ValidateSpec {
+	var (
+		validators []spec.ValidateSpec
+		visited    = make(map[string]struct{})
+	)
+	add := func(ns string) {
+		if _, ok := visited[ns]; ok {
+			return
+		}
+		visited[ns] = struct{}{}
+		if enabled, ok := opts.Specs[ns]; ok && !enabled {
+			return
+		}
+		if v, ok := spec.LoadValidator(ns); ok {
+			validators = append(validators, v)
+		}
+	}
+	for _, ext := range m.Extensions {
+		add(ext.Namespace)
+	}
+	forced := make([]string, 0, len(opts.Specs))
+	for ns, enabled := range opts.Specs {
+		if enabled {
+			forced = append(forced, ns)
+		}
+	}
+	sort.Strings(forced)
+	for _, ns := range forced {
+		add(ns)
+	}
+	return validators
+}
+
+// specValidators returns the extension validators to run on m.
+func (m *Model) specValidators() []spec.ValidateSpec {
+	if m.validation != nil {
+		return m.validation.validators
+	}
+	return new(ValidateOptions).validators(m)
+}
+
+// validation holds the state of a ValidateWith call.
+type validation struct {
+	ctx         context.Context
+	opts        *ValidateOptions
+	validators  []spec.ValidateSpec
+	parallelism int
+	remaining   int64 // errors left to reach MaxErrors, accessed atomically
+}
+
+// errorBudget returns the number of errors the running validation
+// can still report, -1 meaning no limit.
+func (m *Model) errorBudget() int {
+	if m.validation == nil || m.validation.opts.MaxErrors <= 0 {
+		return -1
+	}
+	if n := atomic.LoadInt64(&m.validation.remaining); n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
+// spendErrors subtracts the errors in err reported by the running validation from its budget.
+func (m *Model) spendErrors(err error) {
+	if err != nil && m.errorBudget() >= 0 {
+		atomic.AddInt64(&m.validation.remaining, -int64(countErrors(errors.Filter(err, m.validation.opts.keep))))
+	}
+}
+
+// reports reports whether the running validation reports err.
+func (m *Model) reports(err error) bool {
+	return m.validation == nil || m.validation.opts.keep(err)
+}
+
+// countErrors returns the number of errors in err.
+func countErrors(err error) int {
+	switch e := err.(type) {
+	case nil:
+		return 0
+	case *errors.List:
+		var n int
+		for _, e1 := range e.Errors {
+			n += countErrors(e1)
+		}
+		return n
+	}
+	return 1
+}
+
+// validationContext returns the context and the parallelism of the running validation.
+func (m *Model) validationContext() (context.Context, int) {
+	if m.validation != nil {
+		return m.validation.ctx, m.validation.parallelism
+	}
+	return context.Background(), 0
+}
+
+// ValidateWith checks that the model is conformant with the 3MF specs
+// as configured by opts.
+func (m *Model) ValidateWith(opts ValidateOptions) error {
+	return m.ValidateWithContext(context.Background(), opts)
+}
+
+// ValidateWithContext is like ValidateWith but it stops early if ctx is done,
+// returning ctx.Err().
+func (m *Model) ValidateWithContext(ctx context.Context, opts ValidateOptions) error {
+	mc := m.withIndexes()
+	mc.validation = &validation{ctx: ctx, opts: &opts, validators: opts.validators(m), parallelism: opts.Parallelism}
+	rootPath := m.PathOrDefault()
+	sortedChilds := m.sortedChilds()
+	stages := []func() error{
+		mc.validateModel,
+		func() error {
+			var errs error
+			for _, ext := range mc.validation.validators {
+				errs = errors.Append(errs, ext.Validate(mc, mc.Path, mc))
+			}
+			return errs
+		},
+	}
+	for _, path := range sortedChilds {
+		path := path
+		stages = append(stages, func() error {
+			return errors.WrapPath(mc.Childs[path].Resources.validate(mc, path), attrResources, path)
+		})
+	}
+	stages = append(stages, func() error {
+		return errors.Wrap(mc.Resources.validate(mc, rootPath), attrResources)
+	}, func() error {
+		return errors.Wrap(mc.Build.validate(mc), attrBuild)
+	})
+	if opts.Coherency {
+		stages = append(stages, func() error {
+			return mc.validateCoherency(ctx, opts.Parallelism)
+		})
+	}
+	var list errors.List
+	for _, stage := range stages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		atomic.StoreInt64(&mc.validation.remaining, int64(opts.MaxErrors-list.Len()))
+		stageErrs := stage()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		errors.Append(&list, errors.Filter(stageErrs, opts.keep))
+		if opts.MaxErrors > 0 && list.Len() >= opts.MaxErrors {
+			break
+		}
+	}
+	if list.Len() == 0 {
+		return nil
+	}
+	if opts.MaxErrors > 0 && list.Len() > opts.MaxErrors {
+		list.Errors = list.Errors[:opts.MaxErrors]
+	}
+	errs := errors.Wrap(&list, attrModel)
+	if m.positions != nil {
+		m.positions.annotate(errs, rootPath)
+	}
+	return errs
+}
+
+// validateModel validates the relationships, the namespaces and the metadata.
+func (m *Model) validateModel() error {
 	var errs error
 	errs = errors.Append(errs, validateRelationship(m, m.RootRelationships, ""))
 	errs = errors.Append(errs, m.validateNamespaces())
 	rootPath := m.PathOrDefault()
-	sortedChilds := m.sortedChilds()
-	for _, path := range sortedChilds {
+	if !inModelFolder(rootPath) {
+		errs = errors.Append(errs, errors.ErrModelFolder)
+	}
+	for _, path := range m.sortedChilds() {
 		c := m.Childs[path]
 		if path == rootPath {
 			errs = errors.Append(errs, errors.ErrOPCDuplicatedModelName)
 		} else {
+			if !inModelFolder(path) {
+				errs = errors.Append(errs, &errors.Error{Err: errors.ErrModelFolder, Path: path})
+			}
 			errs = errors.Append(errs, validateRelationship(m, c.Relationships, path))
 		}
 	}
-
 	errs = errors.Append(errs, validateRelationship(m, m.Relationships, rootPath))
-	errs = errors.Append(errs, checkMetadadata(m, m.Metadata))
-
-	for _, ext := range m.Extensions {
-		if ext, ok := spec.LoadValidator(ext.Namespace); ok {
-			errs = errors.Append(errs, ext.Validate(m, m.Path, m))
-		}
-	}
+	return errors.Append(errs, checkMetadadata(m, m.Metadata))
+}
 
-	for _, path := range sortedChilds {
-		c := m.Childs[path]
-		err := c.Resources.validate(m, path)
-		if err != nil {
-			errs = errors.Append(errs, errors.WrapPath(err, attrResources, path))
-		}
-	}
-	err := m.Resources.validate(m, rootPath)
-	if err != nil {
-		errs = errors.Append(errs, errors.Wrap(err, attrResources))
-	}
-	err = m.Build.validate(m)
-	if err != nil {
-		errs = errors.Append(errs, errors.Wrap(err, attrBuild))
-	}
-	if errs != nil {
-		return errors.Wrap(errs, attrModel)
-	}
-	return nil
+// inModelFolder reports whether the part name is in the folder recommended for model parts.
+func inModelFolder(path string) bool {
+	return len(path) > len("/3D/") && strings.EqualFold(path[:len("/3D/")], "/3D/")
 }
 
 func (item *Item) validate(m *Model) error {
@@ -103,6 +342,11 @@ var allowedMetadataNames = [...]string{ // sorted
 	"licenseterms", "modificationdate", "rating", "title",
 }
 
+var metadataNames = [...]string{ // same order as allowedMetadataNames
+	"Application", "Copyright", "CreationDate", "Description", "Designer",
+	"LicenseTerms", "ModificationDate", "Rating", "Title",
+}
+
 func (m *Metadata) validate(model *Model) error {
 	if m.Name.Local == "" {
 		return errors.NewMissingFieldError(attrName)
@@ -113,6 +357,8 @@ func (m *Metadata) validate(model *Model) error {
 		n := sort.SearchStrings(allowedMetadataNames[:], nm)
 		if n >= len(allowedMetadataNames) || allowedMetadataNames[n] != nm {
 			errs = errors.Append(errs, errors.ErrMetadataName)
+		} else if metadataNames[n] != m.Name.Local {
+			errs = errors.Append(errs, errors.ErrMetadataNameCase)
 		}
 	} else {
 		var hasExt bool
@@ -167,6 +413,9 @@ func (res *Resources) validate(m *Model, path string) error {
 	var errs error
 	assets := make(map[uint32]struct{})
 	for i, r := range res.Assets {
+		if m.errorBudget() == 0 {
+			break
+		}
 		var aErrs error
 		id := r.Identify()
 		if id != 0 {
@@ -180,13 +429,23 @@ func (res *Resources) validate(m *Model, path string) error {
 			aErrs = errors.Append(aErrs, r.Validate(m, path))
 		}
 
-		for _, ext := range m.Extensions {
-			if ext, ok := spec.LoadValidator(ext.Namespace); ok {
-				aErrs = errors.Append(aErrs, ext.Validate(m, path, r))
-			}
+		for _, ext := range m.specValidators() {
+			aErrs = errors.Append(aErrs, ext.Validate(m, path, r))
 		}
+		m.spendErrors(aErrs)
 		errs = errors.Append(errs, errors.WrapIndex(aErrs, r.XMLName().Local, i))
 	}
+	ctx, workers := m.validationContext()
+	objErrs := make([]error, len(res.Objects))
+	if err := forEach(ctx, workers, len(res.Objects), func(i int) {
+		if m.errorBudget() == 0 {
+			return
+		}
+		objErrs[i] = res.Objects[i].Validate(m, path)
+		m.spendErrors(objErrs[i])
+	}); err != nil {
+		return err
+	}
 	for i, r := range res.Objects {
 		if r.ID != 0 {
 			if _, ok := assets[r.ID]; ok {
@@ -194,8 +453,7 @@ func (res *Resources) validate(m *Model, path string) error {
 			}
 		}
 		assets[r.ID] = struct{}{}
-		err := r.Validate(m, path)
-		errs = errors.Append(errs, errors.WrapIndex(err, attrObject, i))
+		errs = errors.Append(errs, errors.WrapIndex(objErrs[i], attrObject, i))
 	}
 	return errs
 }
@@ -203,7 +461,6 @@ func (res *Resources) validate(m *Model, path string) error {
 // Validate validates that the object is compliant with 3MF specs,
 // except for the mesh coherency.
 func (r *Object) Validate(m *Model, path string) error {
-	res, _ := m.FindResources(path)
 	var errs error
 	if r.ID == 0 {
 		errs = errors.Append(errs, errors.ErrMissingID)
@@ -216,7 +473,7 @@ func (r *Object) Validate(m *Model, path string) error {
 	}
 	if r.Mesh != nil {
 		if r.PID != 0 {
-			if a, ok := res.FindAsset(r.PID); ok {
+			if a, ok := m.FindAsset(path, r.PID); ok {
 				if a, ok := a.(spec.PropertyGroup); ok {
 					if int(r.PIndex) >= a.Len() {
 						errs = errors.Append(errs, errors.ErrIndexOutOfBounds)
@@ -238,16 +495,13 @@ func (r *Object) Validate(m *Model, path string) error {
 		errs = errors.Append(errs, r.validateComponents(m, path))
 	}
 
-	for _, ext := range m.Extensions {
-		if ext, ok := spec.LoadValidator(ext.Namespace); ok {
-			errs = errors.Append(errs, ext.Validate(m, path, r))
-		}
+	for _, ext := range m.specValidators() {
+		errs = errors.Append(errs, ext.Validate(m, path, r))
 	}
 	return errs
 }
 
 func (r *Object) validateMesh(m *Model, path string) error {
-	res, _ := m.FindResources(path)
 	var errs error
 	switch r.Type {
 	case ObjectTypeModel, ObjectTypeSolidSupport:
@@ -259,28 +513,38 @@ func (r *Object) validateMesh(m *Model, path string) error {
 		}
 	}
 
+	budget, found := m.errorBudget(), 0
+	add := func(err error, i int) {
+		errs = errors.Append(errs, errors.WrapIndex(err, attrTriangle, i))
+		if m.reports(err) {
+			found++
+		}
+	}
 	nodeCount := uint32(len(r.Mesh.Vertices.Vertex))
 	for i, t := range r.Mesh.Triangles.Triangle {
+		if budget >= 0 && found >= budget {
+			break
+		}
 		if t.V1 == t.V2 || t.V1 == t.V3 || t.V2 == t.V3 {
-			errs = errors.Append(errs, errors.WrapIndex(errors.ErrDuplicatedIndices, attrTriangle, i))
+			add(errors.ErrDuplicatedIndices, i)
 		}
 		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
-			errs = errors.Append(errs, errors.WrapIndex(errors.ErrIndexOutOfBounds, attrTriangle, i))
+			add(errors.ErrIndexOutOfBounds, i)
 		}
 		if t.PID != 0 {
 			if t.PID == r.PID && t.P1 == r.PIndex &&
 				t.P2 == r.PIndex && t.P3 == r.PIndex {
 				continue
 			}
-			if a, ok := res.FindAsset(t.PID); ok {
+			if a, ok := m.FindAsset(path, t.PID); ok {
 				if a, ok := a.(spec.PropertyGroup); ok {
 					l := a.Len()
 					if int(t.P1) >= l || int(t.P2) >= l || int(t.P3) >= l {
-						errs = errors.Append(errs, errors.WrapIndex(errors.ErrIndexOutOfBounds, attrTriangle, i))
+						add(errors.ErrIndexOutOfBounds, i)
 					}
 				}
 			} else {
-				errs = errors.Append(errs, errors.WrapIndex(errors.ErrMissingResource, attrTriangle, i))
+				add(errors.ErrMissingResource, i)
 			}
 		}
 	}
@@ -289,12 +553,16 @@ func (r *Object) validateMesh(m *Model, path string) error {
 
 func (r *Object) validateComponents(m *Model, path string) error {
 	var errs error
+	self := objectKey{m.partPath(path), r.ID}
+	visited := make(map[objectKey]struct{})
 	for j, c := range r.Components.Component {
+		cpath := c.ObjectPath(path)
 		if c.ObjectID == 0 {
 			errs = errors.Append(errs, errors.WrapIndex(errors.NewMissingFieldError(attrObjectID), attrComponent, j))
-		} else if ref, ok := m.FindObject(c.ObjectPath(path), c.ObjectID); ok {
-			if ref.ID == r.ID && c.ObjectPath(path) == path {
+		} else if ref, ok := m.FindObject(cpath, c.ObjectID); ok {
+			if m.references(cpath, ref, self, visited) {
 				errs = errors.Append(errs, errors.WrapIndex(errors.ErrRecursion, attrComponent, j))
+				visited = make(map[objectKey]struct{})
 			}
 		} else {
 			errs = errors.Append(errs, errors.WrapIndex(errors.ErrMissingResource, attrComponent, j))
@@ -306,6 +574,45 @@ func (r *Object) validateComponents(m *Model, path string) error {
 	return nil
 }
 
+// objectKey identifies an object among all the model parts.
+type objectKey struct {
+	path string
+	id   uint32
+}
+
+// partPath returns the part path of the resources found at path.
+func (m *Model) partPath(path string) string {
+	if path == "" {
+		return m.PathOrDefault()
+	}
+	return path
+}
+
+// references reports whether obj, defined in path, is target
+// or references it through its components, even from other parts.
+// visited holds the objects already traversed, so each object is only traversed once
+// and the traversal ends even on cycles not containing target.
+func (m *Model) references(path string, obj *Object, target objectKey, visited map[objectKey]struct{}) bool {
+	key := objectKey{m.partPath(path), obj.ID}
+	if key == target {
+		return true
+	}
+	if _, ok := visited[key]; ok {
+		return false
+	}
+	visited[key] = struct{}{}
+	if obj.Components == nil {
+		return false
+	}
+	for _, c := range obj.Components.Component {
+		cpath := c.ObjectPath(path)
+		if ref, ok := m.FindObject(cpath, c.ObjectID); ok && m.references(cpath, ref, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Model) validateNamespaces() error {
 	for _, ext := range m.Extensions {
 		if ext.IsRequired {
@@ -324,13 +631,13 @@ func validateRelationship(m *Model, rels []Relationship, path string) error {
 	var hasPrintTicket bool
 	for i, r := range rels {
 		if r.Path == "" || r.Path[0] != '/' || strings.Contains(r.Path, "/.") {
-			errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCPartName, "relationship", i))
+			errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCPartName, attrRelationship, i))
 		} else {
 			if _, ok := findAttachment(m.Attachments, r.Path); !ok {
-				errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCRelTarget, "relationship", i))
+				errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCRelTarget, attrRelationship, i))
 			}
 			if _, ok := visitedParts[partrel{r.Path, r.Type}]; ok {
-				errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCDuplicatedRel, "relationship", i))
+				errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCDuplicatedRel, attrRelationship, i))
 			}
 			visitedParts[partrel{r.Path, r.Type}] = struct{}{}
 		}
@@ -338,10 +645,10 @@ func validateRelationship(m *Model, rels []Relationship, path string) error {
 		case RelTypePrintTicket:
 			if a, ok := findAttachment(m.Attachments, r.Path); ok {
 				if a.ContentType != ContentTypePrintTicket {
-					errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCContentType, "relationship", i))
+					errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCContentType, attrRelationship, i))
 				}
 				if hasPrintTicket {
-					errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCDuplicatedTicket, "relationship", i))
+					errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCDuplicatedTicket, attrRelationship, i))
 				}
 				hasPrintTicket = true
 			}
@@ -368,49 +675,65 @@ func findAttachment(att []Attachment, path string) (*Attachment, bool) {
 
 // ValidateCoherency checks that all the mesh are non-empty, manifold and oriented.
 func (m *Model) ValidateCoherency() error {
-	var (
-		errs error
-		wg   sync.WaitGroup
-		mu   sync.Mutex
-	)
-	wg.Add(len(m.Resources.Objects))
-	for i := range m.Resources.Objects {
-		go func(i int) {
-			defer wg.Done()
-			r := m.Resources.Objects[i]
+	return m.ValidateCoherencyContext(context.Background())
+}
+
+// ValidateCoherencyContext is like ValidateCoherency but it stops early if ctx is done,
+// returning ctx.Err().
+func (m *Model) ValidateCoherencyContext(ctx context.Context) error {
+	errs := m.validateCoherency(ctx, 0)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Wrap(errs, attrModel)
+}
+
+// validateCoherency checks the coherency of the solid meshes
+// using at most workers goroutines.
+// The callers must check ctx, as the result is incomplete if it is done.
+func (m *Model) validateCoherency(ctx context.Context, workers int) error {
+	type meshObject struct {
+		path  string
+		index int
+		obj   *Object
+	}
+	var objs []meshObject
+	for i, r := range m.Resources.Objects {
+		if isSolidObject(r) {
+			objs = append(objs, meshObject{"", i, r})
+		}
+	}
+	for _, path := range m.sortedChilds() {
+		for i, r := range m.Childs[path].Resources.Objects {
 			if isSolidObject(r) {
-				err := r.Mesh.ValidateCoherency()
-				if err != nil {
-					mu.Lock()
-					errs = errors.Append(errs, errors.Wrap(errors.WrapIndex(errors.Wrap(err, attrMesh), attrObject, i), attrResources))
-					mu.Unlock()
-				}
+				objs = append(objs, meshObject{path, i, r})
 			}
-		}(i)
-	}
-	for path, c := range m.Childs {
-		wg.Add(len(c.Resources.Objects))
-		for i := range c.Resources.Objects {
-			go func(path string, i int) {
-				defer wg.Done()
-				res := m.Childs[path].Resources
-				r := res.Objects[i]
-				if isSolidObject(r) {
-					err := r.Mesh.ValidateCoherency()
-					if err != nil {
-						mu.Lock()
-						errs = errors.Append(errs, errors.WrapPath(errors.WrapIndex(errors.Wrap(err, attrMesh), attrObject, i), attrResources, path))
-						mu.Unlock()
-					}
-				}
-			}(path, i)
 		}
 	}
-	wg.Wait()
-	if errs != nil {
-		return errors.Wrap(errs, attrModel)
+	objErrs := make([]error, len(objs))
+	if err := forEach(ctx, workers, len(objs), func(i int) {
+		if m.errorBudget() == 0 {
+			return
+		}
+		objErrs[i] = objs[i].obj.Mesh.ValidateCoherency()
+		m.spendErrors(objErrs[i])
+	}); err != nil {
+		return err
 	}
-	return nil
+	var errs error
+	for i, o := range objs {
+		if objErrs[i] == nil {
+			continue
+		}
+		err := errors.WrapIndex(errors.Wrap(objErrs[i], attrMesh), attrObject, o.index)
+		if o.path == "" {
+			err = errors.Wrap(err, attrResources)
+		} else {
+			err = errors.WrapPath(err, attrResources, o.path)
+		}
+		errs = errors.Append(errs, err)
+	}
+	return errs
 }
 
 func isSolidObject(r *Object) bool {
@@ -4,10 +4,12 @@
 package go3mf
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"image/color"
 	"sort"
+	"sync/atomic"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -37,6 +39,21 @@ func TestValidate(t *testing.T) {
 			fmt.Sprintf("go3mf: Path: /3D/3dmodel.model XPath: /model/relationship[7]: %v", errors.ErrOPCContentType),
 			fmt.Sprintf("go3mf: Path: /3D/3dmodel.model XPath: /model/relationship[7]: %v", errors.ErrOPCDuplicatedTicket),
 		}},
+		{"recursion", &Model{Childs: map[string]*ChildModel{
+			"/other.model": {Resources: Resources{Objects: []*Object{
+				{ID: 5, Components: &Components{Component: []*Component{{ObjectID: 4, AnyAttr: spec.AnyAttr{&fakeAttr{Value: "/3D/3dmodel.model"}}}}}},
+			}}},
+		}, Resources: Resources{Objects: []*Object{
+			{ID: 1, Components: &Components{Component: []*Component{{ObjectID: 2}}}},
+			{ID: 2, Components: &Components{Component: []*Component{{ObjectID: 1}}}},
+			{ID: 3, Components: &Components{Component: []*Component{{ObjectID: 1}, {ObjectID: 2}}}},
+			{ID: 4, Components: &Components{Component: []*Component{{ObjectID: 5, AnyAttr: spec.AnyAttr{&fakeAttr{Value: "/other.model"}}}}}},
+		}}}, []string{
+			fmt.Sprintf("go3mf: Path: /other.model XPath: /model/resources/object[0]/components/component[0]: %v", errors.ErrRecursion),
+			fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/components/component[0]: %v", errors.ErrRecursion),
+			fmt.Sprintf("go3mf: XPath: /model/resources/object[1]/components/component[0]: %v", errors.ErrRecursion),
+			fmt.Sprintf("go3mf: XPath: /model/resources/object[3]/components/component[0]: %v", errors.ErrRecursion),
+		}},
 		{"namespaces", &Model{Extensions: []Extension{{Namespace: "fake", LocalName: "f", IsRequired: true}}}, []string{
 			fmt.Sprintf("go3mf: XPath: /model: %v", errors.ErrRequiredExt),
 		}},
@@ -154,6 +171,193 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestModel_ValidateAll(t *testing.T) {
+	m := &Model{Metadata: []Metadata{{Name: xml.Name{Local: "title"}}, {Name: xml.Name{Local: "issue"}}}}
+	want := []string{
+		fmt.Sprintf("go3mf: XPath: /model/metadata[0]: %v", errors.ErrMetadataNameCase),
+		fmt.Sprintf("go3mf: XPath: /model/metadata[1]: %v", errors.ErrMetadataName),
+	}
+	err := m.ValidateAll()
+	var errs []string
+	for _, err := range err.(*errors.List).Errors {
+		errs = append(errs, err.Error())
+	}
+	if diff := deep.Equal(errs, want); diff != nil {
+		t.Errorf("Model.ValidateAll() = %v", diff)
+	}
+	if err := m.Validate(); len(err.(*errors.List).Errors) != 1 {
+		t.Errorf("Model.Validate() = %v, want only errors", err)
+	}
+	m.Metadata = m.Metadata[:1]
+	if err := m.Validate(); err != nil {
+		t.Errorf("Model.Validate() = %v, want nil", err)
+	}
+	if err := m.ValidateAll(); err == nil || errors.HasErrors(err) {
+		t.Errorf("Model.ValidateAll() = %v, want only warnings", err)
+	}
+	m.Metadata = nil
+	m.Path = "/3dmodel.model"
+	m.Childs = map[string]*ChildModel{"/3d/other.model": {}, "/other.model": {}}
+	want = []string{
+		fmt.Sprintf("go3mf: XPath: /model: %v", errors.ErrModelFolder),
+		fmt.Sprintf("go3mf: Path: /other.model XPath: /model: %v", errors.ErrModelFolder),
+	}
+	errs = nil
+	if err := m.ValidateAll(); err != nil {
+		for _, err := range err.(*errors.List).Errors {
+			errs = append(errs, err.Error())
+		}
+	}
+	if diff := deep.Equal(errs, want); diff != nil {
+		t.Errorf("Model.ValidateAll() = %v", diff)
+	}
+}
+
+func TestModel_ValidateWith(t *testing.T) {
+	spec.Register(fakeSpec.Namespace, new(qmExtension))
+	invalidMesh := &Mesh{Vertices: Vertices{Vertex: []Point3D{{}, {}, {}, {}}}, Triangles: Triangles{Triangle: []Triangle{
+		{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 3, V3: 1},
+		{V1: 0, V2: 2, V3: 3}, {V1: 1, V2: 2, V3: 3},
+	}}}
+	metadata := []Metadata{{Name: xml.Name{Local: "title"}}, {Name: xml.Name{Local: "issue"}}, {Name: xml.Name{Local: "other"}}, {}}
+	tests := []struct {
+		name  string
+		model *Model
+		opts  ValidateOptions
+		want  []string
+	}{
+		{"default", &Model{Metadata: metadata}, ValidateOptions{}, []string{
+			fmt.Sprintf("go3mf: XPath: /model/metadata[1]: %v", errors.ErrMetadataName),
+			fmt.Sprintf("go3mf: XPath: /model/metadata[2]: %v", errors.ErrMetadataName),
+			fmt.Sprintf("go3mf: XPath: /model/metadata[3]: %v", &errors.MissingFieldError{Name: attrName}),
+		}},
+		{"rules", &Model{Metadata: metadata}, ValidateOptions{Rules: map[string]bool{"core-3.2.1-metadata-name": false, "core-3.2.1-metadata-name-case": true}}, []string{
+			fmt.Sprintf("go3mf: XPath: /model/metadata[0]: %v", errors.ErrMetadataNameCase),
+			fmt.Sprintf("go3mf: XPath: /model/metadata[3]: %v", &errors.MissingFieldError{Name: attrName}),
+		}},
+		{"noCore", &Model{Metadata: metadata}, ValidateOptions{Specs: map[string]bool{Namespace: false}}, []string{
+			fmt.Sprintf("go3mf: XPath: /model/metadata[3]: %v", &errors.MissingFieldError{Name: attrName}),
+		}},
+		{"maxErrors", &Model{Metadata: metadata, Build: Build{Items: []*Item{{}}}}, ValidateOptions{MaxErrors: 2}, []string{
+			fmt.Sprintf("go3mf: XPath: /model/metadata[1]: %v", errors.ErrMetadataName),
+			fmt.Sprintf("go3mf: XPath: /model/metadata[2]: %v", errors.ErrMetadataName),
+		}},
+		{"forcedSpec", &Model{Build: Build{AnyAttr: spec.AnyAttr{&fakeAttr{}}}}, ValidateOptions{Specs: map[string]bool{fakeSpec.Namespace: true}}, []string{
+			"go3mf: XPath: /model: Build: fake",
+		}},
+		{"maxErrorsValid", new(Model), ValidateOptions{MaxErrors: 1}, nil},
+		{"disabledSpec", &Model{Extensions: []Extension{fakeSpec}, Build: Build{AnyAttr: spec.AnyAttr{&fakeAttr{}}}}, ValidateOptions{Specs: map[string]bool{fakeSpec.Namespace: false}}, nil},
+		{"coherency", &Model{Resources: Resources{Objects: []*Object{{ID: 1, Mesh: invalidMesh}}}}, ValidateOptions{Coherency: true}, []string{
+			fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh: %v", errors.ErrMeshConsistency),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.model.ValidateWith(tt.opts)
+			var errs []string
+			if err != nil {
+				for _, err := range err.(*errors.List).Errors {
+					errs = append(errs, err.Error())
+				}
+			}
+			if diff := deep.Equal(errs, tt.want); diff != nil {
+				t.Errorf("Model.ValidateWith() = %v", diff)
+			}
+		})
+	}
+}
+
+func TestValidateOptions_Version(t *testing.T) {
+	rule := errors.NewRuleSince("core-0-test", "1.3", errors.SeverityError, "test")
+	tests := []struct {
+		version string
+		err     error
+		want    bool
+	}{
+		{"", rule, true},
+		{"1.2.3", rule, false},
+		{"1.3.0", rule, true},
+		{"1.10", rule, true},
+		{"1.0", errors.ErrMetadataNamespace, false},
+		{"1.2.3", errors.NewRuleSince("materials-0-test", "1.3", errors.SeverityError, "test"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			opts := ValidateOptions{Version: tt.version}
+			if got := opts.keep(tt.err); got != tt.want {
+				t.Errorf("ValidateOptions.keep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type countingSpec struct {
+	qmExtension
+	objects int32
+}
+
+func (c *countingSpec) Validate(_ interface{}, _ string, element interface{}) error {
+	if _, ok := element.(*Object); ok {
+		atomic.AddInt32(&c.objects, 1)
+	}
+	return nil
+}
+
+func TestModel_ValidateWith_MaxErrors(t *testing.T) {
+	const ns = "http://www.go3mf.com/counting"
+	counter := new(countingSpec)
+	spec.Register(ns, counter)
+	objs := make([]*Object, 10)
+	for i := range objs {
+		objs[i] = &Object{ID: uint32(i + 1), Mesh: &Mesh{Triangles: Triangles{Triangle: make([]Triangle, 100)}}}
+	}
+	m := &Model{Resources: Resources{Objects: objs}}
+	err := m.ValidateWith(ValidateOptions{MaxErrors: 5, Parallelism: 1, Specs: map[string]bool{ns: true}})
+	if got := err.(*errors.List).Len(); got != 5 {
+		t.Errorf("Model.ValidateWith() = %d errors, want 5", got)
+	}
+	if got := atomic.LoadInt32(&counter.objects); got != 1 {
+		t.Errorf("Model.ValidateWith() validated %d objects, want 1", got)
+	}
+	mc := &Model{validation: &validation{opts: &ValidateOptions{MaxErrors: 5}, remaining: 5}}
+	// Each triangle has two errors, so the budget is spent after the third one.
+	if got := countErrors(objs[0].validateMesh(mc, "")); got != 7 {
+		t.Errorf("Object.validateMesh() = %d errors, want 7", got)
+	}
+}
+
+func TestModel_ValidateContext(t *testing.T) {
+	objs := make([]*Object, 50)
+	for i := range objs {
+		objs[i] = &Object{ID: uint32(i % 40), Mesh: &Mesh{Vertices: Vertices{Vertex: make([]Point3D, i%4)}}}
+	}
+	m := &Model{Resources: Resources{Objects: objs}, Childs: map[string]*ChildModel{
+		"/other.model": {Resources: Resources{Objects: objs[:10]}},
+	}}
+	want := m.ValidateWith(ValidateOptions{Coherency: true, Parallelism: 1})
+	if want == nil {
+		t.Fatal("Model.ValidateWith() = nil, want errors")
+	}
+	if diff := deep.Equal(m.ValidateWith(ValidateOptions{Coherency: true, Parallelism: 8}), want); diff != nil {
+		t.Errorf("Model.ValidateWith() = %v", diff)
+	}
+	if err := m.ValidateContext(context.Background()); err == nil {
+		t.Error("Model.ValidateContext() = nil, want errors")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.ValidateContext(ctx); err != context.Canceled {
+		t.Errorf("Model.ValidateContext() = %v, want %v", err, context.Canceled)
+	}
+	if err := m.ValidateWithContext(ctx, ValidateOptions{Coherency: true}); err != context.Canceled {
+		t.Errorf("Model.ValidateWithContext() = %v, want %v", err, context.Canceled)
+	}
+	if err := m.ValidateCoherencyContext(ctx); err != context.Canceled {
+		t.Errorf("Model.ValidateCoherencyContext() = %v, want %v", err, context.Canceled)
+	}
+}
+
 func TestObject_ValidateMesh(t *testing.T) {
 	tests := []struct {
 		name    string